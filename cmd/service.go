@@ -2,11 +2,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 
 	"github.com/bugfixes/go-bugfixes/logs"
 	"github.com/todo-lists-app/todo-lists-api/internal/config"
 	"github.com/todo-lists-app/todo-lists-api/internal/service"
+	"github.com/todo-lists-app/todo-lists-api/internal/version"
 )
 
 var (
@@ -28,7 +31,8 @@ func runApp() error {
 	}
 
 	s := &service.Service{
-		Config: cfg,
+		Config:  cfg,
+		Version: version.New(ServiceName, BuildVersion, BuildHash),
 	}
 
 	if err := s.Start(); err != nil {
@@ -38,7 +42,28 @@ func runApp() error {
 	return nil
 }
 
+// checkConfig builds and validates the config without starting anything
+func checkConfig() error {
+	if _, err := config.Build(); err != nil {
+		return err
+	}
+	fmt.Println("config ok")
+
+	return nil
+}
+
 func main() {
+	check := flag.Bool("check-config", false, "validate the config and exit")
+	flag.Parse()
+
+	if *check {
+		if err := checkConfig(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := runApp(); err != nil {
 		_ = logs.Local().Errorf("run app: %v", err)
 	}
@@ -2,11 +2,11 @@ package api
 
 import (
 	"context"
-	"github.com/bugfixes/go-bugfixes/logs"
+	"fmt"
+
 	pb "github.com/todo-lists-app/protobufs/generated/user/v1"
 	"github.com/todo-lists-app/todo-lists-api/internal/config"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type AccountServiceClientCreator interface {
@@ -19,6 +19,8 @@ type Account struct {
 	UserID      string
 	AccessToken string
 	Client      pb.UserServiceClient
+
+	Connections *Connections
 }
 
 func NewAccountService(ctx context.Context, cfg config.Config, id, accessToken string) *Account {
@@ -30,10 +32,31 @@ func NewAccountService(ctx context.Context, cfg config.Config, id, accessToken s
 	}
 }
 
+// WithConnections makes GetClient reuse a pooled connection instead of dialing a new one
+func (a *Account) WithConnections(c *Connections) *Account {
+	a.Connections = c
+	return a
+}
+
 func (a *Account) GetClient() (*Account, error) {
-	conn, err := grpc.DialContext(a.Context, a.Config.Services.User, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if a.Connections != nil {
+		conn, err := a.Connections.Get("user")
+		if err != nil {
+			return nil, fmt.Errorf("error getting connection: %v", err)
+		}
+
+		a.Client = pb.NewUserServiceClient(conn)
+		return a, nil
+	}
+
+	creds, _, err := transportCredentials(a.Config, a.Config.Services.UserTLS)
+	if err != nil {
+		return nil, fmt.Errorf("error getting credentials: %v", err)
+	}
+
+	conn, err := grpc.DialContext(a.Context, a.Config.Services.User, append(dialOptions(a.Config), grpc.WithTransportCredentials(creds))...)
 	if err != nil {
-		return nil, logs.Errorf("error dialing grpc: %v", err)
+		return nil, fmt.Errorf("error dialing grpc: %v", err)
 	}
 
 	a.Client = pb.NewUserServiceClient(conn)
@@ -46,11 +69,11 @@ func (a *Account) DeleteAccount() error {
 		AccessToken: a.AccessToken,
 	})
 	if err != nil {
-		return logs.Errorf("error deleting account: %v", err)
+		return fmt.Errorf("error deleting account: %v", err)
 	}
 
 	if resp.GetStatus() != "ok" {
-		return logs.Errorf("error deleting account: %v", resp.GetStatus())
+		return fmt.Errorf("error deleting account: %v", resp.GetStatus())
 	}
 
 	return nil
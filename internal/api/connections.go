@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+)
+
+// Connections keeps one client connection per downstream address and tls config so they are reused
+// between requests, each service gets its own circuit breaker and transport credentials, and they all
+// share one retry budget
+type Connections struct {
+	cfg config.Config
+
+	mu       sync.Mutex
+	conns    map[endpoint]*grpc.ClientConn
+	opts     []grpc.DialOption
+	services map[string]endpoint
+	breakers map[string]*breaker.Breaker
+	budget   *RetryBudget
+	stop     chan struct{}
+	logger   *slog.Logger
+}
+
+// endpoint is where a service is and how to reach it, services only share a connection when both match
+type endpoint struct {
+	address string
+	tls     config.TLS
+}
+
+// serviceConn is a connection as one service uses it, calls go through that services breaker
+type serviceConn struct {
+	*grpc.ClientConn
+	breaker *breaker.Breaker
+}
+
+// Invoke implements grpc.ClientConnInterface
+func (s *serviceConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if s.breaker != nil {
+		opts = append(opts, breakerOption{breaker: s.breaker})
+	}
+
+	return s.ClientConn.Invoke(ctx, method, args, reply, opts...)
+}
+
+// breakerOption names the breaker a call goes through, it is a call option rather than part of the
+// connection because services on the same endpoint share the connection
+type breakerOption struct {
+	grpc.EmptyCallOption
+	breaker *breaker.Breaker
+}
+
+// breakerInterceptor sends the call through the breaker named in its options, it sits inside the retry
+// interceptor so every attempt counts and an open breaker stops the retries
+func breakerInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	for _, opt := range opts {
+		if b, ok := opt.(breakerOption); ok {
+			return b.breaker.UnaryClientInterceptor()(ctx, method, req, reply, cc, invoker, opts...)
+		}
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// NewConnections creates the connection pool, every connection shares the same dial options
+func NewConnections(cfg config.Config, logger *slog.Logger) *Connections {
+	c := &Connections{
+		conns:  make(map[endpoint]*grpc.ClientConn),
+		stop:   make(chan struct{}),
+		logger: logger,
+	}
+	c.configure(cfg)
+
+	return c
+}
+
+// configure sets up everything that comes from the config, breakers and the retry budget are
+// kept when their config hasn't changed so a reload doesn't reset them
+func (c *Connections) configure(cfg config.Config) {
+	if c.breakers == nil || cfg.Breaker != c.cfg.Breaker {
+		c.breakers = make(map[string]*breaker.Breaker)
+	}
+	if c.budget == nil || cfg.Retry.BudgetRatio != c.cfg.Retry.BudgetRatio || cfg.Retry.BudgetMax != c.cfg.Retry.BudgetMax {
+		c.budget = NewRetryBudget(cfg.Retry.BudgetRatio, cfg.Retry.BudgetMax)
+	}
+
+	c.cfg = cfg
+	c.opts = append(dialOptions(cfg), grpc.WithChainUnaryInterceptor(RetryInterceptor(cfg.Retry, c.budget), breakerInterceptor))
+	c.services = make(map[string]endpoint)
+	for _, svc := range []struct {
+		name    string
+		address string
+		tls     config.TLS
+	}{
+		{"identity", cfg.Services.Identity, cfg.Services.IdentityTLS},
+		{"todo", cfg.Services.Todo, cfg.Services.TodoTLS},
+		{"user", cfg.Services.User, cfg.Services.UserTLS},
+	} {
+		if _, ok := c.breakers[svc.name]; !ok {
+			c.breakers[svc.name] = breaker.New(svc.name, cfg.Breaker.FailureThreshold, cfg.Breaker.OpenDuration, cfg.Breaker.HalfOpenProbes)
+		}
+		c.services[svc.name] = endpoint{
+			address: svc.address,
+			tls:     svc.tls,
+		}
+	}
+}
+
+// Apply switches the pool to a new config, new connections are made as they are asked for and the old
+// ones are closed once in-flight calls have had the shutdown grace to finish
+func (c *Connections) Apply(cfg *config.Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	same := reflect.DeepEqual(cfg.Services, c.cfg.Services) &&
+		reflect.DeepEqual(cfg.Retry, c.cfg.Retry) &&
+		cfg.Breaker == c.cfg.Breaker
+	if same {
+		c.cfg = *cfg
+		return nil
+	}
+
+	old, stop := c.conns, c.stop
+	c.conns = make(map[endpoint]*grpc.ClientConn)
+	c.stop = make(chan struct{})
+	c.configure(*cfg)
+
+	time.AfterFunc(cfg.Server.ShutdownGrace, func() {
+		close(stop)
+		for e, conn := range old {
+			if err := conn.Close(); err != nil {
+				c.logger.Warn("close old connection", "address", e.address, "error", err)
+			}
+		}
+	})
+
+	return nil
+}
+
+// Get returns the connection for a service, identity, todo or user, calls on it go through the services
+// breaker
+func (c *Connections) Get(name string) (grpc.ClientConnInterface, error) {
+	conn, err := c.Dial(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serviceConn{ClientConn: conn, breaker: c.Breaker(name)}, nil
+}
+
+// Dial returns the connection shared by every service on the same endpoint as the named one, creating it
+// the first time the endpoint is asked for, calls on it skip the services breaker
+func (c *Connections) Dial(name string) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.services[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown service %s", name)
+	}
+
+	conn, ok := c.conns[e]
+	if !ok {
+		creds, store, err := transportCredentials(c.cfg, e.tls)
+		if err != nil {
+			return nil, fmt.Errorf("error getting credentials for %s: %v", name, err)
+		}
+
+		conn, err = grpc.NewClient(e.address, append(append([]grpc.DialOption{}, c.opts...), grpc.WithTransportCredentials(creds))...)
+		if err != nil {
+			return nil, fmt.Errorf("error creating grpc client: %v", err)
+		}
+		c.conns[e] = conn
+
+		if store != nil {
+			go store.Watch(e.tls.ReloadInterval, c.stop, c.logger)
+		}
+	}
+
+	return conn, nil
+}
+
+// Breaker returns the circuit breaker for a service, identity, todo or user
+func (c *Connections) Breaker(name string) *breaker.Breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.breakers[name]
+}
+
+// Close closes every connection in the pool and stops reloading their tls material
+func (c *Connections) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+
+	var closeErr error
+	for e, conn := range c.conns {
+		if err := conn.Close(); err != nil {
+			closeErr = fmt.Errorf("error closing %s: %v", e.address, err)
+		}
+		delete(c.conns, e)
+	}
+
+	return closeErr
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+)
+
+// clientConn unwraps the connection Get returned
+func clientConn(t *testing.T, c *Connections, name string) *serviceConn {
+	t.Helper()
+
+	conn, err := c.Get(name)
+	require.NoError(t, err)
+	svc, ok := conn.(*serviceConn)
+	require.True(t, ok)
+
+	return svc
+}
+
+func TestConnectionsApply(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Services = config.Services{Identity: "127.0.0.1:3001", Todo: "127.0.0.1:3002", User: "127.0.0.1:3003"}
+	cfg.Breaker = config.Breaker{FailureThreshold: 5, OpenDuration: time.Minute, HalfOpenProbes: 1}
+	cfg.Server.ShutdownGrace = time.Millisecond
+
+	c := NewConnections(*cfg, slog.New(slog.DiscardHandler))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	old := clientConn(t, c, "todo").ClientConn
+	todo := c.Breaker("todo")
+
+	next := *cfg
+	next.Services.Todo = "127.0.0.1:4002"
+	assert.NoError(t, c.Apply(&next))
+
+	assert.NotSame(t, old, clientConn(t, c, "todo").ClientConn)
+	assert.Same(t, todo, c.Breaker("todo"), "breaker kept when its config is unchanged")
+
+	next.Breaker.FailureThreshold = 2
+	assert.NoError(t, c.Apply(&next))
+	assert.NotSame(t, todo, c.Breaker("todo"))
+	assert.Equal(t, 2, c.Breaker("todo").FailureThreshold)
+}
+
+func TestConnectionsSharedAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		userTLS  config.TLS
+		wantSame bool
+	}{
+		{
+			name:     "same tls shares the connection",
+			userTLS:  config.TLS{Mode: "insecure"},
+			wantSame: true,
+		},
+		{
+			name:    "different tls gets its own connection",
+			userTLS: config.TLS{Mode: "insecure", ServerName: "user-service"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{}
+			cfg.Services = config.Services{
+				Identity:    "127.0.0.1:3001",
+				Todo:        "127.0.0.1:3002",
+				User:        "127.0.0.1:3002",
+				TodoTLS:     config.TLS{Mode: "insecure"},
+				UserTLS:     tt.userTLS,
+				IdentityTLS: config.TLS{Mode: "insecure"},
+			}
+			cfg.Breaker = config.Breaker{FailureThreshold: 5, OpenDuration: time.Minute, HalfOpenProbes: 1}
+
+			c := NewConnections(cfg, slog.New(slog.DiscardHandler))
+			defer func() {
+				_ = c.Close()
+			}()
+
+			todo := clientConn(t, c, "todo")
+			user := clientConn(t, c, "user")
+			if tt.wantSame {
+				assert.Same(t, todo.ClientConn, user.ClientConn)
+			} else {
+				assert.NotSame(t, todo.ClientConn, user.ClientConn)
+			}
+			assert.Same(t, c.Breaker("todo"), todo.breaker, "each service keeps its own breaker")
+			assert.Same(t, c.Breaker("user"), user.breaker)
+		})
+	}
+
+	t.Run("unknown service", func(t *testing.T) {
+		c := NewConnections(config.Config{}, slog.New(slog.DiscardHandler))
+		defer func() {
+			_ = c.Close()
+		}()
+
+		_, err := c.Get("billing")
+		assert.Error(t, err)
+	})
+}
+
+var _ grpc.ClientConnInterface = (*serviceConn)(nil)
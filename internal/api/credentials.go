@@ -0,0 +1,43 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	vaultHelper "github.com/keloran/vault-helper"
+	"github.com/todo-lists-app/todo-lists-api/internal/certs"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// transportCredentials builds the credentials for a downstream service from its tls config,
+// the store is returned so the caller can keep the material up to date
+func transportCredentials(cfg config.Config, t config.TLS) (credentials.TransportCredentials, *certs.Store, error) {
+	switch t.Mode {
+	case "", config.TLSInsecure:
+		return insecure.NewCredentials(), nil, nil
+	case config.TLSServer, config.TLSMutual:
+	default:
+		return nil, nil, fmt.Errorf("unknown tls mode: %s", t.Mode)
+	}
+
+	load := certs.FileLoader(t.CAFile, t.CertFile, t.KeyFile)
+	if t.VaultPath != "" {
+		var vh vaultHelper.VaultHelper
+		if cfg.Config.VaultHelper != nil {
+			vh = *cfg.Config.VaultHelper
+		}
+		load = certs.VaultLoader(vh, t.VaultPath)
+	}
+
+	store, err := certs.NewStore(load)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load tls material: %v", err)
+	}
+	if t.Mode == config.TLSMutual && store.Certificate() == nil {
+		return nil, nil, errors.New("mtls needs a client certificate and key")
+	}
+
+	return credentials.NewTLS(store.ClientConfig(t.ServerName)), store, nil
+}
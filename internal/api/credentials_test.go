@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/certs/certstest"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func startTLSServer(t *testing.T, ca *certstest.CA, requireClientCert bool) string {
+	t.Helper()
+
+	certPEM, keyPEM := ca.Issue(t, "server")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca.PEM)
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if requireClientCert {
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsCfg)))
+	healthpb.RegisterHealthServer(srv, grpchealth.NewServer())
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+func checkHealth(t *testing.T, tlsCfg config.TLS, address string) error {
+	t.Helper()
+
+	cfg := config.Config{}
+	cfg.Services.Todo = address
+	cfg.Services.TodoTLS = tlsCfg
+	cfg.Retry.MaxAttempts = 1
+
+	conns := NewConnections(cfg, slog.New(slog.DiscardHandler))
+	defer func() {
+		_ = conns.Close()
+	}()
+
+	conn, err := conns.Get("todo")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestTransportCredentials(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+	caFile := certstest.Write(t, dir, "ca.pem", ca.PEM)
+	certPEM, keyPEM := ca.Issue(t, "client")
+	certFile := certstest.Write(t, dir, "client.pem", certPEM)
+	keyFile := certstest.Write(t, dir, "client-key.pem", keyPEM)
+
+	t.Run("tls", func(t *testing.T) {
+		address := startTLSServer(t, ca, false)
+
+		err := checkHealth(t, config.TLS{
+			Mode:       config.TLSServer,
+			CAFile:     caFile,
+			ServerName: "localhost",
+		}, address)
+		assert.NoError(t, err)
+	})
+
+	t.Run("tls with unknown ca", func(t *testing.T) {
+		address := startTLSServer(t, certstest.NewCA(t), false)
+
+		err := checkHealth(t, config.TLS{
+			Mode:       config.TLSServer,
+			CAFile:     caFile,
+			ServerName: "localhost",
+		}, address)
+		assert.Error(t, err)
+	})
+
+	t.Run("mtls", func(t *testing.T) {
+		address := startTLSServer(t, ca, true)
+
+		err := checkHealth(t, config.TLS{
+			Mode:       config.TLSMutual,
+			CAFile:     caFile,
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			ServerName: "localhost",
+		}, address)
+		assert.NoError(t, err)
+	})
+
+	t.Run("mtls without client cert", func(t *testing.T) {
+		address := startTLSServer(t, ca, true)
+
+		err := checkHealth(t, config.TLS{
+			Mode:       config.TLSServer,
+			CAFile:     caFile,
+			ServerName: "localhost",
+		}, address)
+		assert.Error(t, err)
+
+		err = checkHealth(t, config.TLS{
+			Mode:   config.TLSMutual,
+			CAFile: caFile,
+		}, address)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		_, _, err := transportCredentials(config.Config{}, config.TLS{Mode: "ssl"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	validate "github.com/todo-lists-app/go-validate-user"
+	pb "github.com/todo-lists-app/protobufs/generated/id_checker/v1"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+)
+
+// Identity validates users against the identity service
+type Identity struct {
+	config.Config
+	context.Context
+	Client pb.IdCheckerServiceClient
+
+	Connections *Connections
+}
+
+// NewIdentityService creates a new identity service
+func NewIdentityService(ctx context.Context, cfg config.Config) *Identity {
+	return &Identity{
+		Config:  cfg,
+		Context: ctx,
+	}
+}
+
+// WithConnections makes GetClient reuse a pooled connection instead of dialing a new one
+func (i *Identity) WithConnections(c *Connections) *Identity {
+	i.Connections = c
+	return i
+}
+
+func (i *Identity) GetClient() (*Identity, error) {
+	if i.Connections != nil {
+		conn, err := i.Connections.Get("identity")
+		if err != nil {
+			return nil, fmt.Errorf("error getting connection: %v", err)
+		}
+
+		i.Client = pb.NewIdCheckerServiceClient(conn)
+		return i, nil
+	}
+
+	creds, _, err := transportCredentials(i.Config, i.Config.Services.IdentityTLS)
+	if err != nil {
+		return nil, fmt.Errorf("error getting credentials: %v", err)
+	}
+
+	conn, err := grpc.DialContext(i.Context, i.Config.Services.Identity, append(dialOptions(i.Config), grpc.WithTransportCredentials(creds))...)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing grpc: %v", err)
+	}
+
+	i.Client = pb.NewIdCheckerServiceClient(conn)
+	return i, nil
+}
+
+// ValidateUser checks the user with go-validate-user, which reports a failed call as an invalid user,
+// so the call error is kept and returned to tell an outage apart from a bad token
+func (i *Identity) ValidateUser(accessToken, subject string) (bool, error) {
+	rec := &recordingChecker{IdCheckerServiceClient: i.Client}
+	v := &validate.Validate{
+		IdentityService: i.Config.Services.Identity,
+		CTX:             i.Context,
+		DevMode:         i.Config.Local.Development,
+		Client:          rec,
+	}
+
+	valid, err := v.ValidateUser(accessToken, subject)
+	if err != nil {
+		return false, err
+	}
+	if rec.err != nil {
+		return false, rec.err
+	}
+
+	return valid, nil
+}
+
+type recordingChecker struct {
+	pb.IdCheckerServiceClient
+	err error
+}
+
+func (r *recordingChecker) CheckId(ctx context.Context, in *pb.CheckIdRequest, opts ...grpc.CallOption) (*pb.CheckIdResponse, error) {
+	resp, err := r.IdCheckerServiceClient.CheckId(ctx, in, opts...)
+	r.err = err
+	return resp, err
+}
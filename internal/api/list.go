@@ -2,12 +2,13 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 
-	"github.com/bugfixes/go-bugfixes/logs"
 	pb "github.com/todo-lists-app/protobufs/generated/todo/v1"
 	"github.com/todo-lists-app/todo-lists-api/internal/config"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type TodoServiceClientCreator interface {
@@ -20,6 +21,8 @@ type List struct {
 	context.Context
 	UserID string
 	Client pb.TodoServiceClient
+
+	Connections *Connections
 }
 
 type TodoList interface {
@@ -45,10 +48,38 @@ type StoredList struct {
 	IV     string `bson:"iv" json:"iv"`
 }
 
+// ETag identifies this version of the list, it is a hash of the ciphertext so it changes with every edit
+// without saying anything about what is in it
+func (s *StoredList) ETag() string {
+	sum := sha256.Sum256([]byte(s.IV + ":" + s.Data))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WithConnections makes GetClient reuse a pooled connection instead of dialing a new one
+func (l *List) WithConnections(c *Connections) *List {
+	l.Connections = c
+	return l
+}
+
 func (l *List) GetClient() (*List, error) {
-	conn, err := grpc.DialContext(l.Context, l.Config.Services.Todo, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if l.Connections != nil {
+		conn, err := l.Connections.Get("todo")
+		if err != nil {
+			return nil, fmt.Errorf("error getting connection: %v", err)
+		}
+
+		l.Client = pb.NewTodoServiceClient(conn)
+		return l, nil
+	}
+
+	creds, _, err := transportCredentials(l.Config, l.Config.Services.TodoTLS)
+	if err != nil {
+		return nil, fmt.Errorf("error getting credentials: %v", err)
+	}
+
+	conn, err := grpc.DialContext(l.Context, l.Config.Services.Todo, append(dialOptions(l.Config), grpc.WithTransportCredentials(creds))...)
 	if err != nil {
-		return nil, logs.Errorf("error dialing grpc: %v", err)
+		return nil, fmt.Errorf("error dialing grpc: %v", err)
 	}
 
 	l.Client = pb.NewTodoServiceClient(conn)
@@ -61,10 +92,10 @@ func (l *List) GetList() (*StoredList, error) {
 		UserId: l.UserID,
 	})
 	if err != nil {
-		return nil, logs.Errorf("error getting list: %v", err)
+		return nil, fmt.Errorf("error getting list: %v", err)
 	}
 	if resp.GetStatus() != "" {
-		return nil, logs.Errorf("error getting list status: %v", resp.GetStatus())
+		return nil, fmt.Errorf("error getting list status: %v", resp.GetStatus())
 	}
 
 	return &StoredList{
@@ -82,10 +113,10 @@ func (l *List) UpdateList(list *StoredList) (*StoredList, error) {
 		Iv:     list.IV,
 	})
 	if err != nil {
-		return nil, logs.Errorf("error updating list: %v", err)
+		return nil, fmt.Errorf("error updating list: %v", err)
 	}
 	if resp.GetStatus() != "" {
-		return nil, logs.Errorf("error updating list status: %v", resp.GetStatus())
+		return nil, fmt.Errorf("error updating list status: %v", resp.GetStatus())
 	}
 
 	return &StoredList{
@@ -101,10 +132,10 @@ func (l *List) DeleteList(id string) (*StoredList, error) {
 		UserId: l.UserID,
 	})
 	if err != nil {
-		return nil, logs.Errorf("error deleting list: %v", err)
+		return nil, fmt.Errorf("error deleting list: %v", err)
 	}
 	if resp.GetStatus() != "" {
-		return nil, logs.Errorf("error deleting list status: %v", resp.GetStatus())
+		return nil, fmt.Errorf("error deleting list status: %v", resp.GetStatus())
 	}
 
 	return &StoredList{
@@ -120,10 +151,10 @@ func (l *List) CreateList(list *StoredList) (*StoredList, error) {
 		Iv:     list.IV,
 	})
 	if err != nil {
-		return nil, logs.Errorf("error inserting list: %v", err)
+		return nil, fmt.Errorf("error inserting list: %v", err)
 	}
 	if resp.GetStatus() != "" {
-		return nil, logs.Errorf("error inserting list status: %v", resp.GetStatus())
+		return nil, fmt.Errorf("error inserting list status: %v", resp.GetStatus())
 	}
 
 	return &StoredList{
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys sent to the downstream services
+const (
+	MetadataRequestID   = "x-request-id"
+	MetadataSubject     = "x-user-subject"
+	MetadataAccessToken = "x-user-access-token"
+)
+
+type contextKey string
+
+const (
+	requestIDKey contextKey = "request-id"
+	callerKey    contextKey = "caller"
+)
+
+// Caller is the authenticated user making the request
+type Caller struct {
+	Subject     string
+	AccessToken string
+	// Device is the registered device the request came from, empty when the client didn't say
+	Device string
+}
+
+// WithRequestID stores the request id so it can be passed downstream
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id, if there is one
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithCaller stores the authenticated caller so it can be passed downstream
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey, c)
+}
+
+// CallerFromContext returns the authenticated caller, if there is one
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerKey).(Caller)
+	return c, ok
+}
+
+// MetadataInterceptor attaches the request id and caller to every outgoing call,
+// the access token is only sent when the config allows it
+func MetadataInterceptor(cfg config.Config) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var pairs []string
+		if id := RequestIDFromContext(ctx); id != "" {
+			pairs = append(pairs, MetadataRequestID, id)
+		}
+		if c, ok := CallerFromContext(ctx); ok {
+			if c.Subject != "" {
+				pairs = append(pairs, MetadataSubject, c.Subject)
+			}
+			if cfg.Services.PropagateAccessToken && c.AccessToken != "" {
+				pairs = append(pairs, MetadataAccessToken, c.AccessToken)
+			}
+		}
+		if len(pairs) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// dialOptions are the options shared by every downstream client, the transport credentials are per service
+func dialOptions(cfg config.Config) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(MetadataInterceptor(cfg)),
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMetadataInterceptor(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithCaller(ctx, Caller{
+		Subject:     "testSubject",
+		AccessToken: "testToken",
+	})
+
+	capture := func(md *metadata.MD) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			*md, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		}
+	}
+
+	t.Run("without access token", func(t *testing.T) {
+		var md metadata.MD
+		err := MetadataInterceptor(config.Config{})(ctx, "/todo.v1.TodoService/Get", nil, nil, nil, capture(&md))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"req-1"}, md.Get(MetadataRequestID))
+		assert.Equal(t, []string{"testSubject"}, md.Get(MetadataSubject))
+		assert.Empty(t, md.Get(MetadataAccessToken))
+	})
+
+	t.Run("with access token", func(t *testing.T) {
+		cfg := config.Config{}
+		cfg.Services.PropagateAccessToken = true
+
+		var md metadata.MD
+		err := MetadataInterceptor(cfg)(ctx, "/todo.v1.TodoService/Get", nil, nil, nil, capture(&md))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"testToken"}, md.Get(MetadataAccessToken))
+	})
+
+	t.Run("no caller", func(t *testing.T) {
+		var md metadata.MD
+		err := MetadataInterceptor(config.Config{})(context.Background(), "/todo.v1.TodoService/Get", nil, nil, nil, capture(&md))
+
+		assert.NoError(t, err)
+		assert.Empty(t, md.Get(MetadataRequestID))
+		assert.Empty(t, md.Get(MetadataSubject))
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// idempotent are the calls that are safe to send again, the todo service has no revision to guard
+// Update with, so it is never retried
+var idempotent = map[string]bool{
+	"/todo.TodoService/Get":                true,
+	"/id_checker.IdCheckerService/CheckId": true,
+}
+
+// RetryBudget is shared by every connection so a degraded service can't cause a retry storm,
+// each call deposits Ratio and each retry withdraws one
+type RetryBudget struct {
+	Ratio float64
+	Max   float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewRetryBudget creates a full budget
+func NewRetryBudget(ratio, maxTokens float64) *RetryBudget {
+	return &RetryBudget{
+		Ratio:  ratio,
+		Max:    maxTokens,
+		tokens: maxTokens,
+	}
+}
+
+// Deposit is called for every call
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.Ratio
+	if b.tokens > b.Max {
+		b.tokens = b.Max
+	}
+}
+
+// Withdraw reports whether there is budget left for a retry
+func (b *RetryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// RetryInterceptor gives every call a deadline, and retries idempotent calls that fail with
+// UNAVAILABLE using jittered exponential backoff while the deadline and budget allow
+func RetryInterceptor(cfg config.Retry, budget *RetryBudget) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if timeout := callTimeout(cfg, method); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		budget.Deposit()
+
+		backoff := cfg.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if !retryable(method, err) || attempt >= cfg.MaxAttempts || !budget.Withdraw() {
+				return err
+			}
+
+			wait := jitter(backoff)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+
+			backoff *= 2
+			if backoff > cfg.MaxBackoff {
+				backoff = cfg.MaxBackoff
+			}
+		}
+	}
+}
+
+func callTimeout(cfg config.Retry, method string) time.Duration {
+	name := method[strings.LastIndex(method, "/")+1:]
+	if t, ok := cfg.Timeouts[name]; ok {
+		return t
+	}
+
+	return cfg.Timeout
+}
+
+func retryable(method string, err error) bool {
+	if err == nil || !idempotent[method] {
+		return false
+	}
+	if errors.Is(err, breaker.ErrOpen) {
+		return false
+	}
+
+	return status.Code(err) == codes.Unavailable
+}
+
+// jitter picks a wait between half and all of the backoff
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	half := int64(d) / 2
+	return time.Duration(half + rand.Int64N(half+1))
+}
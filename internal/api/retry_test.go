@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryInterceptor(t *testing.T) {
+	cfg := config.Retry{
+		Timeout:        time.Second,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+
+	failing := func(calls *int, errs ...error) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			*calls++
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("no deadline on call")
+			}
+			if *calls <= len(errs) {
+				return errs[*calls-1]
+			}
+			return nil
+		}
+	}
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	t.Run("retries idempotent calls", func(t *testing.T) {
+		calls := 0
+		err := RetryInterceptor(cfg, NewRetryBudget(0.1, 10))(context.Background(), "/todo.TodoService/Get", nil, nil, nil, failing(&calls, unavailable, unavailable))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		calls := 0
+		err := RetryInterceptor(cfg, NewRetryBudget(0.1, 10))(context.Background(), "/todo.TodoService/Get", nil, nil, nil, failing(&calls, unavailable, unavailable, unavailable))
+
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("never retries writes", func(t *testing.T) {
+		calls := 0
+		err := RetryInterceptor(cfg, NewRetryBudget(0.1, 10))(context.Background(), "/todo.TodoService/Update", nil, nil, nil, failing(&calls, unavailable))
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("never retries other errors", func(t *testing.T) {
+		calls := 0
+		err := RetryInterceptor(cfg, NewRetryBudget(0.1, 10))(context.Background(), "/todo.TodoService/Get", nil, nil, nil, failing(&calls, status.Error(codes.InvalidArgument, "bad")))
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("never retries an open breaker", func(t *testing.T) {
+		calls := 0
+		err := RetryInterceptor(cfg, NewRetryBudget(0.1, 10))(context.Background(), "/todo.TodoService/Get", nil, nil, nil, failing(&calls, breaker.ErrOpen))
+
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("budget is shared", func(t *testing.T) {
+		budget := NewRetryBudget(0, 1)
+		calls := 0
+		_ = RetryInterceptor(cfg, budget)(context.Background(), "/todo.TodoService/Get", nil, nil, nil, failing(&calls, unavailable, unavailable, unavailable))
+		assert.Equal(t, 2, calls)
+
+		calls = 0
+		_ = RetryInterceptor(cfg, budget)(context.Background(), "/todo.TodoService/Get", nil, nil, nil, failing(&calls, unavailable, unavailable, unavailable))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("per method timeout", func(t *testing.T) {
+		c := cfg
+		c.Timeouts = map[string]time.Duration{"Get": 10 * time.Millisecond}
+		err := RetryInterceptor(c, NewRetryBudget(0.1, 10))(context.Background(), "/todo.TodoService/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			deadline, _ := ctx.Deadline()
+			assert.WithinDuration(t, time.Now().Add(10*time.Millisecond), deadline, 10*time.Millisecond)
+			return nil
+		})
+
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,208 @@
+// Package breaker provides the circuit breakers that sit in front of the downstream services
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrOpen is returned instead of calling a service whose breaker is open
+var ErrOpen = status.Error(codes.Unavailable, "circuit breaker is open")
+
+// State is the state of a breaker
+type State int
+
+// The breaker states
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker trips after FailureThreshold consecutive failures, stays open for OpenDuration,
+// then lets HalfOpenProbes calls through and closes again once they have all succeeded
+type Breaker struct {
+	Name             string
+	FailureThreshold int
+	OpenDuration     time.Duration
+	HalfOpenProbes   int
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	inflight  int
+	successes int
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+// New creates a closed breaker
+func New(name string, failureThreshold int, openDuration time.Duration, halfOpenProbes int) *Breaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	if halfOpenProbes < 1 {
+		halfOpenProbes = 1
+	}
+
+	b := &Breaker{
+		Name:             name,
+		FailureThreshold: failureThreshold,
+		OpenDuration:     openDuration,
+		HalfOpenProbes:   halfOpenProbes,
+		now:              time.Now,
+	}
+	b.publish()
+
+	return b
+}
+
+// State returns the current state, an open breaker whose time is up reports half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	return b.state
+}
+
+// RetryAfter is how long until an open breaker lets probes through again
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		return 0
+	}
+
+	remaining := b.OpenDuration - b.now().Sub(b.openedAt)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Allow reports whether a call can go ahead, every allowed call has to be followed by Done
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	switch b.state {
+	case Open:
+		return ErrOpen
+	case HalfOpen:
+		if b.inflight >= b.HalfOpenProbes {
+			return ErrOpen
+		}
+	}
+	b.inflight++
+
+	return nil
+}
+
+// Done records the outcome of a call that Allow let through
+func (b *Breaker) Done(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.inflight > 0 {
+		b.inflight--
+	}
+
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.FailureThreshold {
+			b.trip()
+		}
+	case HalfOpen:
+		if !success {
+			b.trip()
+			return
+		}
+		b.successes++
+		if b.successes >= b.HalfOpenProbes {
+			b.state = Closed
+			b.failures = 0
+			b.successes = 0
+			b.publish()
+		}
+	}
+}
+
+// UnaryClientInterceptor fails calls fast while the breaker is open
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := b.Allow(); err != nil {
+			return err
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.Done(!IsFailure(err))
+
+		return err
+	}
+}
+
+// IsFailure reports whether an error means the service is unhealthy, rather than the request being bad
+func IsFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+// advance moves an open breaker to half-open once OpenDuration has passed, callers hold the lock
+func (b *Breaker) advance() {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.OpenDuration {
+		b.state = HalfOpen
+		b.inflight = 0
+		b.successes = 0
+		b.publish()
+	}
+}
+
+// trip opens the breaker, callers hold the lock
+func (b *Breaker) trip() {
+	b.state = Open
+	b.openedAt = b.now()
+	b.failures = 0
+	b.successes = 0
+	metrics.BreakerTrips.Add(b.Name, 1)
+	b.publish()
+}
+
+func (b *Breaker) publish() {
+	metrics.SetBreakerState(b.Name, int64(b.state))
+}
@@ -0,0 +1,95 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := New("test", 2, time.Minute, 1)
+	b.now = func() time.Time {
+		return now
+	}
+
+	t.Run("opens after threshold", func(t *testing.T) {
+		assert.NoError(t, b.Allow())
+		b.Done(false)
+		assert.Equal(t, Closed, b.State())
+
+		assert.NoError(t, b.Allow())
+		b.Done(false)
+		assert.Equal(t, Open, b.State())
+		assert.ErrorIs(t, b.Allow(), ErrOpen)
+		assert.Equal(t, time.Minute, b.RetryAfter())
+	})
+
+	t.Run("half open after duration", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		assert.Equal(t, HalfOpen, b.State())
+
+		assert.NoError(t, b.Allow())
+		assert.ErrorIs(t, b.Allow(), ErrOpen, "only one probe at a time")
+	})
+
+	t.Run("failed probe reopens", func(t *testing.T) {
+		b.Done(false)
+		assert.Equal(t, Open, b.State())
+	})
+
+	t.Run("successful probe closes", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		assert.NoError(t, b.Allow())
+		b.Done(true)
+		assert.Equal(t, Closed, b.State())
+	})
+
+	t.Run("success resets failures", func(t *testing.T) {
+		assert.NoError(t, b.Allow())
+		b.Done(false)
+		assert.NoError(t, b.Allow())
+		b.Done(true)
+		assert.NoError(t, b.Allow())
+		b.Done(false)
+		assert.Equal(t, Closed, b.State())
+	})
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	b := New("interceptor", 1, time.Minute, 1)
+	calls := 0
+	invoker := func(err error) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			return err
+		}
+	}
+	intercept := b.UnaryClientInterceptor()
+
+	err := intercept(context.Background(), "/test", nil, nil, nil, invoker(status.Error(codes.NotFound, "not found")))
+	assert.Error(t, err)
+	assert.Equal(t, Closed, b.State(), "client errors don't count")
+
+	err = intercept(context.Background(), "/test", nil, nil, nil, invoker(status.Error(codes.Unavailable, "down")))
+	assert.Error(t, err)
+	assert.Equal(t, Open, b.State())
+
+	err = intercept(context.Background(), "/test", nil, nil, nil, invoker(nil))
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIsFailure(t *testing.T) {
+	assert.False(t, IsFailure(nil))
+	assert.False(t, IsFailure(context.Canceled))
+	assert.False(t, IsFailure(status.Error(codes.InvalidArgument, "bad")))
+	assert.True(t, IsFailure(status.Error(codes.DeadlineExceeded, "slow")))
+	assert.True(t, IsFailure(errors.New("plain")))
+}
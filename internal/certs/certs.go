@@ -0,0 +1,290 @@
+// Package certs loads TLS material from files or Vault and reloads it when it rotates
+package certs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	vaultHelper "github.com/keloran/vault-helper"
+)
+
+// Vault secret keys the material is read from
+const (
+	VaultCA   = "ca"
+	VaultCert = "cert"
+	VaultKey  = "key"
+)
+
+// Material is the PEM encoded TLS material, any of it can be empty
+type Material struct {
+	CA   []byte
+	Cert []byte
+	Key  []byte
+}
+
+// Loader fetches the current material
+type Loader func() (Material, error)
+
+// FileLoader reads the material from files, empty paths are skipped
+func FileLoader(caFile, certFile, keyFile string) Loader {
+	return func() (Material, error) {
+		m := Material{}
+		for _, f := range []struct {
+			path string
+			dest *[]byte
+		}{
+			{caFile, &m.CA},
+			{certFile, &m.Cert},
+			{keyFile, &m.Key},
+		} {
+			if f.path == "" {
+				continue
+			}
+			b, err := os.ReadFile(f.path)
+			if err != nil {
+				return m, fmt.Errorf("read %s: %v", f.path, err)
+			}
+			*f.dest = b
+		}
+
+		return m, nil
+	}
+}
+
+// VaultLoader reads the material from the ca, cert and key secrets at path
+func VaultLoader(vh vaultHelper.VaultHelper, path string) Loader {
+	return func() (Material, error) {
+		m := Material{}
+		if vh == nil {
+			return m, errors.New("no vault helper for tls material")
+		}
+		if err := vh.GetSecrets(path); err != nil {
+			return m, fmt.Errorf("get tls secrets: %v", err)
+		}
+
+		for _, s := range []struct {
+			key  string
+			dest *[]byte
+		}{
+			{VaultCA, &m.CA},
+			{VaultCert, &m.Cert},
+			{VaultKey, &m.Key},
+		} {
+			v, err := vh.GetSecret(s.key)
+			if err != nil {
+				continue
+			}
+			*s.dest = []byte(v)
+		}
+
+		return m, nil
+	}
+}
+
+// Store holds the parsed material, swapping it when Reload finds it has changed
+type Store struct {
+	load Loader
+
+	mu     sync.RWMutex
+	loaded bool
+	sum    [32]byte
+	cert   *tls.Certificate
+	pool   *x509.CertPool
+}
+
+// NewStore loads the material for the first time
+func NewStore(load Loader) (*Store, error) {
+	s := &Store{load: load}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload fetches the material again, reporting whether it changed, the old material is kept on error
+func (s *Store) Reload() (bool, error) {
+	m, err := s.load()
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(bytes.Join([][]byte{m.CA, m.Cert, m.Key}, []byte{0}))
+	s.mu.RLock()
+	same := s.loaded && sum == s.sum
+	s.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	var cert *tls.Certificate
+	if len(m.Cert) > 0 || len(m.Key) > 0 {
+		c, err := tls.X509KeyPair(m.Cert, m.Key)
+		if err != nil {
+			return false, fmt.Errorf("parse key pair: %v", err)
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if len(m.CA) > 0 {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(m.CA) {
+			return false, errors.New("no certificates in ca bundle")
+		}
+	}
+
+	s.mu.Lock()
+	s.loaded = true
+	s.sum = sum
+	s.cert = cert
+	s.pool = pool
+	s.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch reloads the material every interval until stop is closed
+func (s *Store) Watch(interval time.Duration, stop <-chan struct{}, logger *slog.Logger) {
+	if interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			changed, err := s.Reload()
+			if err != nil {
+				logger.Error("reload tls material", "error", err)
+				continue
+			}
+			if changed {
+				logger.Info("tls material reloaded")
+			}
+		}
+	}
+}
+
+// Certificate returns the current key pair, if there is one
+func (s *Store) Certificate() *tls.Certificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cert
+}
+
+// Pool returns the current ca pool, nil means the system roots
+func (s *Store) Pool() *x509.CertPool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pool
+}
+
+// ClientConfig is a tls config for dialing that always uses the current material,
+// the peer is verified against the current ca pool rather than one fixed at creation
+func (s *Store) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// verification is done in VerifyConnection so a rotated ca is picked up
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return s.verify(cs, x509.ExtKeyUsageServerAuth, cs.ServerName)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := s.Certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+	}
+}
+
+func (s *Store) verify(cs tls.ConnectionState, usage x509.ExtKeyUsage, name string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificates")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         s.Pool(),
+		DNSName:       name,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// ServerConfig is a tls config for serving that always hands out the current key pair
+func (s *Store) ServerConfig(minVersion uint16, cipherSuites []uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert := s.Certificate()
+			if cert == nil {
+				return nil, errors.New("no certificate loaded")
+			}
+			return cert, nil
+		},
+	}
+}
+
+// ParseVersion turns a version such as 1.2 into its tls constant
+func ParseVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("unsupported tls version: %s", v)
+}
+
+// ParseCipherSuites turns suite names into their ids, the list has to include a suite HTTP/2 allows
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	h2 := false
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite: %s", name)
+		}
+		if id == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+			h2 = true
+		}
+		ids = append(ids, id)
+	}
+	if !h2 {
+		return nil, errors.New("cipher suites need TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 or TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 for HTTP/2")
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,45 @@
+package certs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/certs/certstest"
+)
+
+func TestStore_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t)
+	certPEM, keyPEM := ca.Issue(t, "first")
+	caFile := certstest.Write(t, dir, "ca.pem", ca.PEM)
+	certFile := certstest.Write(t, dir, "cert.pem", certPEM)
+	keyFile := certstest.Write(t, dir, "key.pem", keyPEM)
+
+	s, err := NewStore(FileLoader(caFile, certFile, keyFile))
+	assert.NoError(t, err)
+	first := s.Certificate()
+	assert.NotNil(t, first)
+	assert.NotNil(t, s.Pool())
+
+	changed, err := s.Reload()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	certPEM, keyPEM = ca.Issue(t, "second")
+	certstest.Write(t, dir, "cert.pem", certPEM)
+	certstest.Write(t, dir, "key.pem", keyPEM)
+
+	changed, err = s.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NotEqual(t, first.Certificate[0], s.Certificate().Certificate[0])
+
+	t.Run("bad material keeps the old", func(t *testing.T) {
+		current := s.Certificate()
+		certstest.Write(t, dir, "cert.pem", []byte("not a cert"))
+
+		_, err := s.Reload()
+		assert.Error(t, err)
+		assert.Equal(t, current, s.Certificate())
+	})
+}
@@ -0,0 +1,231 @@
+// Package changes keeps an ordered feed of every change a user makes, so an offline client can ask
+// what changed since it was last online instead of fetching everything again
+package changes
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resources that changes are recorded for
+const (
+	ResourceList    = "list"
+	ResourceAccount = "account"
+	// ResourceSharedList is a list shared between users, the record names which one
+	ResourceSharedList = "shared-list"
+	// ResourceInvite is an invite the user sent to one of their shared lists
+	ResourceInvite = "invite"
+	// ResourceInbox is an item dropped in the users inbox, the record names which one
+	ResourceInbox = "inbox"
+)
+
+// Operations that changed a resource
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	// OperationConflict is an update from a stale revision kept as a sibling of the list
+	OperationConflict = "conflict"
+	// OperationResolve is an update that merged siblings back into the list
+	OperationResolve = "resolve"
+	// OperationShare is a shared list the user was given or had their access to changed
+	OperationShare = "share"
+	// OperationUnshare is a shared list the user no longer has access to
+	OperationUnshare = "unshare"
+	// OperationAccept is an invite that was accepted
+	OperationAccept = "accept"
+)
+
+var (
+	// ErrBadCursor is returned for a cursor the feed didn't hand out
+	ErrBadCursor = errors.New("invalid cursor")
+	// ErrCursorExpired is returned when records after the cursor have been dropped, the client has to resync
+	ErrCursorExpired = errors.New("cursor is older than the kept changes")
+)
+
+// Record is one change, Seq orders a users records and never goes backwards
+type Record struct {
+	Seq      uint64 `json:"-" bson:"seq"`
+	Subject  string `json:"-" bson:"subject"`
+	Cursor   string `json:"cursor" bson:"-"`
+	Resource string `json:"resource" bson:"resource"`
+	// ID is set for resources a user can have more than one of
+	ID        string    `json:"id,omitempty" bson:"resource_id,omitempty"`
+	Revision  string    `json:"revision,omitempty" bson:"revision,omitempty"`
+	Operation string    `json:"operation" bson:"operation"`
+	At        time.Time `json:"at" bson:"at"`
+	Device    string    `json:"device,omitempty" bson:"device,omitempty"`
+}
+
+// Store keeps the records
+type Store interface {
+	// Append stores the record after the subjects latest one and returns the sequence it was given
+	Append(ctx context.Context, r Record) (uint64, error)
+	// Since returns up to limit of the subjects records after seq, oldest first
+	Since(ctx context.Context, subject string, seq uint64, limit int) ([]Record, error)
+}
+
+// Page is a slice of the feed, Cursor is where the next page starts
+type Page struct {
+	Changes []Record `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	More    bool     `json:"more"`
+}
+
+// Feed records changes and reads them back a page at a time, waking long polls on this instance
+// straight away, long polls on other instances see a change the next time they check the store
+type Feed struct {
+	store Store
+	epoch string
+
+	mu      sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+// epocher is a store whose sequences start again when the process does, its cursors carry the epoch so
+// one handed out before a restart is expired rather than silently skipping the records after it
+type epocher interface {
+	Epoch() string
+}
+
+// NewFeed creates a feed over the store
+func NewFeed(store Store) *Feed {
+	f := &Feed{
+		store:   store,
+		waiters: make(map[string]chan struct{}),
+	}
+	if e, ok := store.(epocher); ok {
+		f.epoch = e.Epoch()
+	}
+
+	return f
+}
+
+// Record appends a change for the subject
+func (f *Feed) Record(ctx context.Context, r Record) (Record, error) {
+	if r.At.IsZero() {
+		r.At = time.Now().UTC()
+	}
+
+	seq, err := f.store.Append(ctx, r)
+	if err != nil {
+		return r, err
+	}
+	r.Seq = seq
+	r.Cursor = f.formatCursor(seq)
+
+	f.mu.Lock()
+	if c, ok := f.waiters[r.Subject]; ok {
+		close(c)
+		delete(f.waiters, r.Subject)
+	}
+	f.mu.Unlock()
+
+	return r, nil
+}
+
+// Since returns the page after cursor, an empty cursor starts from the oldest kept change
+func (f *Feed) Since(ctx context.Context, subject, cursor string, limit int) (Page, error) {
+	seq, err := f.parseCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+
+	records, err := f.store.Since(ctx, subject, seq, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+	// sequences don't have gaps, so a missing record after a cursor we handed out has been dropped
+	if cursor != "" && len(records) > 0 && records[0].Seq != seq+1 {
+		return Page{}, ErrCursorExpired
+	}
+
+	p := Page{Changes: []Record{}, Cursor: f.formatCursor(seq)}
+	if len(records) > limit {
+		records = records[:limit]
+		p.More = true
+	}
+	for _, r := range records {
+		r.Cursor = f.formatCursor(r.Seq)
+		p.Changes = append(p.Changes, r)
+		p.Cursor = r.Cursor
+	}
+
+	return p, nil
+}
+
+// Wait is Since, holding the request for up to wait when there is nothing new yet
+func (f *Feed) Wait(ctx context.Context, subject, cursor string, limit int, wait, poll time.Duration) (Page, error) {
+	p, err := f.Since(ctx, subject, cursor, limit)
+	if err != nil || len(p.Changes) > 0 || wait <= 0 {
+		return p, err
+	}
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		woken := f.waiter(subject)
+		// a change recorded between the last read and registering would otherwise be missed
+		if p, err = f.Since(ctx, subject, cursor, limit); err != nil || len(p.Changes) > 0 {
+			return p, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return p, ctx.Err()
+		case <-timeout.C:
+			return p, nil
+		case <-woken:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Feed) waiter(subject string) <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.waiters[subject]
+	if !ok {
+		c = make(chan struct{})
+		f.waiters[subject] = c
+	}
+
+	return c
+}
+
+func (f *Feed) formatCursor(seq uint64) string {
+	if f.epoch == "" {
+		return strconv.FormatUint(seq, 10)
+	}
+
+	return f.epoch + "." + strconv.FormatUint(seq, 10)
+}
+
+func (f *Feed) parseCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	epoch, number, _ := strings.Cut(cursor, ".")
+	if number == "" {
+		epoch, number = "", cursor
+	}
+	seq, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, ErrBadCursor
+	}
+	if epoch != f.epoch {
+		// handed out by an earlier process, or by a different store
+		return 0, ErrCursorExpired
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,166 @@
+package changes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func record(t *testing.T, f *Feed, subject, revision string) Record {
+	t.Helper()
+
+	r, err := f.Record(context.Background(), Record{
+		Subject:   subject,
+		Resource:  ResourceList,
+		Operation: OperationUpdate,
+		Revision:  revision,
+		Device:    "phone",
+	})
+	assert.NoError(t, err)
+	return r
+}
+
+func TestFeedSince(t *testing.T) {
+	f := NewFeed(NewMemoryStore(time.Hour))
+	ctx := context.Background()
+
+	empty, err := f.Since(ctx, "alice", "", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, empty.Changes)
+	assert.Equal(t, f.formatCursor(0), empty.Cursor)
+
+	for _, rev := range []string{"a", "b", "c"} {
+		record(t, f, "alice", rev)
+	}
+	record(t, f, "bob", "z")
+
+	first, err := f.Since(ctx, "alice", "", 2)
+	assert.NoError(t, err)
+	assert.True(t, first.More)
+	assert.Equal(t, []string{"a", "b"}, revisions(first))
+	assert.Equal(t, "phone", first.Changes[0].Device)
+
+	rest, err := f.Since(ctx, "alice", first.Cursor, 2)
+	assert.NoError(t, err)
+	assert.False(t, rest.More)
+	assert.Equal(t, []string{"c"}, revisions(rest))
+
+	current, err := f.Since(ctx, "alice", rest.Cursor, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, current.Changes)
+	assert.Equal(t, rest.Cursor, current.Cursor)
+
+	_, err = f.Since(ctx, "alice", "not-a-cursor", 2)
+	assert.ErrorIs(t, err, ErrBadCursor)
+}
+
+func TestFeedExpiredCursor(t *testing.T) {
+	f := NewFeed(NewMemoryStore(time.Minute))
+	ctx := context.Background()
+
+	_, err := f.Record(ctx, Record{Subject: "alice", Revision: "a", At: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	record(t, f, "alice", "b")
+	record(t, f, "alice", "c")
+
+	all, err := f.Since(ctx, "alice", "", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, revisions(all))
+
+	page, err := f.Since(ctx, "alice", f.formatCursor(1), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, revisions(page))
+
+	// a has been dropped, so a client that hasn't seen it has to resync
+	_, err = f.Since(ctx, "alice", f.formatCursor(0), 10)
+	assert.ErrorIs(t, err, ErrCursorExpired)
+}
+
+func TestFeedWait(t *testing.T) {
+	f := NewFeed(NewMemoryStore(time.Hour))
+	ctx := context.Background()
+
+	t.Run("woken by a change", func(t *testing.T) {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			record(t, f, "alice", "a")
+		}()
+
+		start := time.Now()
+		page, err := f.Wait(ctx, "alice", "", 10, 5*time.Second, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a"}, revisions(page))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		page, err := f.Wait(ctx, "alice", f.formatCursor(1), 10, 20*time.Millisecond, time.Minute)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Changes)
+		assert.Equal(t, f.formatCursor(1), page.Cursor)
+	})
+
+	t.Run("picks up changes from elsewhere", func(t *testing.T) {
+		store := NewMemoryStore(time.Hour)
+		other := NewFeed(store)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_, _ = store.Append(ctx, Record{Subject: "alice", Revision: "b", At: time.Now()})
+		}()
+
+		page, err := other.Wait(ctx, "alice", "", 10, 5*time.Second, 10*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b"}, revisions(page))
+	})
+}
+
+// restartingStore is a memory store the feed can't see the epoch of, like the mongo store
+type restartingStore struct {
+	Store
+}
+
+func TestFeedRestart(t *testing.T) {
+	ctx := context.Background()
+
+	before := NewFeed(NewMemoryStore(time.Hour))
+	for _, rev := range []string{"a", "b", "c"} {
+		record(t, before, "alice", rev)
+	}
+	old, err := before.Since(ctx, "alice", "", 10)
+	assert.NoError(t, err)
+
+	t.Run("a cursor from before a restart is expired", func(t *testing.T) {
+		after := NewFeed(NewMemoryStore(time.Hour))
+		record(t, after, "alice", "d")
+
+		_, err := after.Since(ctx, "alice", old.Cursor, 10)
+		assert.ErrorIs(t, err, ErrCursorExpired)
+		_, err = after.Since(ctx, "alice", "3", 10)
+		assert.ErrorIs(t, err, ErrCursorExpired, "a cursor without the epoch isn't one this store handed out")
+	})
+
+	t.Run("stores that survive a restart use plain sequences", func(t *testing.T) {
+		f := NewFeed(restartingStore{NewMemoryStore(time.Hour)})
+		r := record(t, f, "alice", "a")
+		assert.Equal(t, "1", r.Cursor)
+
+		_, err := f.Since(ctx, "alice", old.Cursor, 10)
+		assert.ErrorIs(t, err, ErrCursorExpired)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := before.Since(ctx, "alice", before.epoch+".x", 10)
+		assert.ErrorIs(t, err, ErrBadCursor)
+	})
+}
+
+func revisions(p Page) []string {
+	var out []string
+	for _, r := range p.Changes {
+		out = append(out, r.Revision)
+	}
+
+	return out
+}
@@ -0,0 +1,73 @@
+package changes
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in memory, for development and tests, cursors from before a restart are expired
+type MemoryStore struct {
+	retention time.Duration
+	epoch     string
+
+	mu      sync.Mutex
+	records map[string][]Record
+	seqs    map[string]uint64
+}
+
+// NewMemoryStore creates an empty store, records older than retention are dropped as new ones are added
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention: retention,
+		epoch:     strings.ToLower(rand.Text()[:8]),
+		records:   make(map[string][]Record),
+		seqs:      make(map[string]uint64),
+	}
+}
+
+// Epoch is different for every store, so the feed can tell its cursors from those of an earlier process
+func (m *MemoryStore) Epoch() string {
+	return m.epoch
+}
+
+// Append implements Store
+func (m *MemoryStore) Append(_ context.Context, r Record) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seqs[r.Subject]++
+	r.Seq = m.seqs[r.Subject]
+
+	records := m.records[r.Subject]
+	if m.retention > 0 {
+		cutoff := time.Now().Add(-m.retention)
+		for len(records) > 0 && records[0].At.Before(cutoff) {
+			records = records[1:]
+		}
+	}
+	m.records[r.Subject] = append(records, r)
+
+	return r.Seq, nil
+}
+
+// Since implements Store
+func (m *MemoryStore) Since(_ context.Context, subject string, seq uint64, limit int) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Record
+	for _, r := range m.records[subject] {
+		if r.Seq <= seq {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, r)
+	}
+
+	return out, nil
+}
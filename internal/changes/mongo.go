@@ -0,0 +1,124 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// appendAttempts is how many times Append retries when another instance takes the same sequence
+const appendAttempts = 5
+
+// ttlIndex is the index that drops records once they are older than the retention
+const ttlIndex = "at_ttl"
+
+// MongoStore keeps records in a collection, a unique index on subject and seq makes sure two instances
+// appending at once can't both take a sequence, and a heads collection remembers the latest sequence
+// so it carries on from there once every record has expired
+type MongoStore struct {
+	records *mongo.Collection
+	heads   *mongo.Collection
+	logger  *slog.Logger
+}
+
+type head struct {
+	Seq uint64 `bson:"seq"`
+}
+
+// NewMongoStore makes sure the indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collection string, retentionSeconds int32, logger *slog.Logger) (*MongoStore, error) {
+	m := &MongoStore{
+		records: db.Collection(collection),
+		heads:   db.Collection(collection + "_heads"),
+		logger:  logger,
+	}
+
+	if _, err := m.records.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "subject", Value: 1}, {Key: "seq", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return nil, fmt.Errorf("create sequence index: %v", err)
+	}
+	if _, err := m.records.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "at", Value: 1}},
+		Options: options.Index().SetName(ttlIndex).SetExpireAfterSeconds(retentionSeconds),
+	}); err != nil {
+		// the index already exists with the old retention
+		if err := db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection},
+			{Key: "index", Value: bson.D{{Key: "name", Value: ttlIndex}, {Key: "expireAfterSeconds", Value: retentionSeconds}}},
+		}).Err(); err != nil {
+			return nil, fmt.Errorf("create retention index: %v", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Append implements Store
+func (m *MongoStore) Append(ctx context.Context, r Record) (uint64, error) {
+	for range appendAttempts {
+		latest, err := m.latest(ctx, r.Subject)
+		if err != nil {
+			return 0, err
+		}
+
+		r.Seq = latest + 1
+		if _, err := m.records.InsertOne(ctx, r); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return 0, fmt.Errorf("insert change: %v", err)
+		}
+
+		// the head is only a floor for when every record has expired, so falling behind isn't fatal
+		if _, err := m.heads.UpdateOne(ctx,
+			bson.M{"_id": r.Subject},
+			bson.M{"$max": bson.M{"seq": r.Seq}},
+			options.Update().SetUpsert(true)); err != nil {
+			m.logger.Warn("update change head", "error", err)
+		}
+
+		return r.Seq, nil
+	}
+
+	return 0, fmt.Errorf("append change: sequence still taken after %d attempts", appendAttempts)
+}
+
+// latest is the highest sequence handed out for the subject, from its records or its head
+func (m *MongoStore) latest(ctx context.Context, subject string) (uint64, error) {
+	h := head{}
+	if err := m.heads.FindOne(ctx, bson.M{"_id": subject}).Decode(&h); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, fmt.Errorf("get change head: %v", err)
+	}
+
+	r := Record{}
+	err := m.records.FindOne(ctx, bson.M{"subject": subject}, options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})).Decode(&r)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, fmt.Errorf("get latest change: %v", err)
+	}
+
+	return max(h.Seq, r.Seq), nil
+}
+
+// Since implements Store
+func (m *MongoStore) Since(ctx context.Context, subject string, seq uint64, limit int) ([]Record, error) {
+	cur, err := m.records.Find(ctx,
+		bson.M{"subject": subject, "seq": bson.M{"$gt": seq}},
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("find changes: %v", err)
+	}
+
+	var records []Record
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("read changes: %v", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// AdminVaultToken is the vault secret the admin token is read from
+const AdminVaultToken = "admin-token"
+
+// Admin protects the admin routes on the internal listener, they are turned off when there is no token
+type Admin struct {
+	Token     string `env:"ADMIN_TOKEN"`
+	VaultPath string `env:"ADMIN_VAULT_PATH"`
+	// ProfileDir is where on-demand cpu profiles are written
+	ProfileDir string `env:"ADMIN_PROFILE_DIR" envDefault:"/tmp"`
+}
+
+// Logging is the level every component starts at, it can be changed at runtime through the admin routes
+type Logging struct {
+	Level string `env:"LOG_LEVEL" envDefault:"info"`
+}
+
+func BuildAdmin(cfg *Config) error {
+	admin := &Admin{}
+	if err := env.Parse(admin); err != nil {
+		return fmt.Errorf("unable to parse admin: %v", err)
+	}
+
+	if admin.VaultPath != "" {
+		if cfg.Config.VaultHelper == nil {
+			return errors.New("admin vault path set without vault")
+		}
+		vh := *cfg.Config.VaultHelper
+		if err := vh.GetSecrets(admin.VaultPath); err != nil {
+			return fmt.Errorf("unable to get admin secrets: %v", err)
+		}
+		token, err := vh.GetSecret(AdminVaultToken)
+		if err != nil {
+			return fmt.Errorf("unable to get admin token: %v", err)
+		}
+		admin.Token = token
+	}
+	cfg.Admin = *admin
+
+	return nil
+}
+
+func BuildLogging(cfg *Config) error {
+	logging := &Logging{}
+	if err := env.Parse(logging); err != nil {
+		return fmt.Errorf("unable to parse logging: %v", err)
+	}
+	cfg.Logging = *logging
+
+	return nil
+}
+
+// Validate makes sure a token isn't so short it can be guessed and profiles have somewhere to go
+func (a Admin) Validate() error {
+	var errs []error
+	if a.Token != "" && len(a.Token) < 16 {
+		errs = append(errs, errors.New("ADMIN_TOKEN has to be at least 16 characters"))
+	}
+	if a.ProfileDir == "" {
+		errs = append(errs, errors.New("ADMIN_PROFILE_DIR can't be empty"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the level is one slog knows
+func (l Logging) Validate() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return errors.New("LOG_LEVEL has to be debug, info, warn or error")
+	}
+
+	return nil
+}
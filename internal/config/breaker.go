@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Breaker is the circuit breaker config shared by the downstream services
+type Breaker struct {
+	// FailureThreshold is how many failures in a row open the breaker
+	FailureThreshold int `env:"BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	// OpenDuration is how long the breaker fails fast before letting probes through
+	OpenDuration time.Duration `env:"BREAKER_OPEN_DURATION" envDefault:"30s"`
+	// HalfOpenProbes is how many probes have to succeed before the breaker closes
+	HalfOpenProbes int `env:"BREAKER_HALF_OPEN_PROBES" envDefault:"1"`
+}
+
+func BuildBreaker(cfg *Config) error {
+	breaker := &Breaker{}
+	if err := env.Parse(breaker); err != nil {
+		return fmt.Errorf("unable to parse breaker: %v", err)
+	}
+	cfg.Breaker = *breaker
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Changes is the config for the change feed offline clients sync from, it is kept in Storage
+type Changes struct {
+	Collection string `env:"CHANGES_COLLECTION" envDefault:"changes"`
+	// PageSize is the most records a page can hold, and what a page holds when the client doesn't say
+	PageSize int `env:"CHANGES_PAGE_SIZE" envDefault:"100"`
+	// MaxWait is the longest a long poll is held open
+	MaxWait time.Duration `env:"CHANGES_MAX_WAIT" envDefault:"25s"`
+	// PollInterval is how often a long poll checks the store for changes made on other instances
+	PollInterval time.Duration `env:"CHANGES_POLL_INTERVAL" envDefault:"2s"`
+	// Retention is how long records are kept, older cursors have to resync
+	Retention time.Duration `env:"CHANGES_RETENTION" envDefault:"720h"`
+}
+
+func BuildChanges(cfg *Config) error {
+	changes := &Changes{}
+	if err := env.Parse(changes); err != nil {
+		return fmt.Errorf("unable to parse changes: %v", err)
+	}
+	cfg.Changes = *changes
+
+	return nil
+}
+
+// Validate checks the limits
+func (c Changes) Validate() error {
+	var errs []error
+	if c.Collection == "" {
+		errs = append(errs, errors.New("CHANGES_COLLECTION can't be empty"))
+	}
+	if c.PageSize < 1 || c.PageSize > 1000 {
+		errs = append(errs, errors.New("CHANGES_PAGE_SIZE has to be between 1 and 1000"))
+	}
+	errs = append(errs,
+		notNegative("CHANGES_MAX_WAIT", c.MaxWait),
+		positive("CHANGES_POLL_INTERVAL", c.PollInterval),
+		positive("CHANGES_RETENTION", c.Retention))
+
+	return errors.Join(errs...)
+}
@@ -2,28 +2,95 @@
 package config
 
 import (
-	"github.com/bugfixes/go-bugfixes/logs"
+	"errors"
+	"fmt"
+
 	gc "github.com/keloran/go-config"
 )
 
 // Config is the main config
 type Config struct {
 	Services
+	Health
+	Breaker
+	Retry
+	HTTPS
+	CORS
+	Server
+	Reload
+	Admin
+	Logging
+	Maintenance
+	Events
+	WebSocket
+	Storage
+	Changes
+	Devices
+	Conflicts
+	Sharing
+	Keys
+	Mail
+	Invites
+	Shares
+	Inbox
 	gc.Config
 }
 
-// Build is used to build the config, it will call BuildVault and BuildMongo
+// Build is used to build the config, it will call BuildVault and BuildMongo,
+// every section is built and validated before any problems are returned so they can all be fixed at once
 func Build() (*Config, error) {
 	cfg := &Config{}
 
+	if err := loadEnvFile(); err != nil {
+		return nil, fmt.Errorf("load config file: %v", err)
+	}
+
 	gcc, err := gc.Build(gc.Vault, gc.Local)
 	if err != nil {
-		return nil, logs.Errorf("build config: %v", err)
+		return nil, fmt.Errorf("build config: %v", err)
 	}
 	cfg.Config = *gcc
 
-	if err := BuildServices(cfg); err != nil {
-		return nil, logs.Errorf("build services: %v", err)
+	var errs []error
+	for _, section := range []struct {
+		name  string
+		build func(*Config) error
+	}{
+		{"services", BuildServices},
+		{"health", BuildHealth},
+		{"breaker", BuildBreaker},
+		{"retry", BuildRetry},
+		{"https", BuildHTTPS},
+		{"cors", BuildCORS},
+		{"server", BuildServer},
+		{"reload", BuildReload},
+		{"admin", BuildAdmin},
+		{"logging", BuildLogging},
+		{"maintenance", BuildMaintenance},
+		{"events", BuildEvents},
+		{"websocket", BuildWebSocket},
+		{"storage", BuildStorage},
+		{"changes", BuildChanges},
+		{"devices", BuildDevices},
+		{"conflicts", BuildConflicts},
+		{"sharing", BuildSharing},
+		{"keys", BuildKeys},
+		{"mail", BuildMail},
+		{"invites", BuildInvites},
+		{"shares", BuildShares},
+		{"inbox", BuildInbox},
+	} {
+		if err := section.build(cfg); err != nil {
+			errs = append(errs, prefixErrors("build "+section.name, err)...)
+		}
+	}
+	if len(errs) == 0 {
+		if err := cfg.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config:\n%v", errors.Join(errs...))
 	}
 
 	return cfg, nil
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Conflicts is the config for the versions of a list written from a stale revision, they are kept in Storage
+type Conflicts struct {
+	Collection string `env:"CONFLICTS_COLLECTION" envDefault:"conflicts"`
+	// MaxSiblings is how many unresolved versions a list can have before stale writes are rejected
+	MaxSiblings int `env:"CONFLICTS_MAX_SIBLINGS" envDefault:"10"`
+}
+
+func BuildConflicts(cfg *Config) error {
+	conflicts := &Conflicts{}
+	if err := env.Parse(conflicts); err != nil {
+		return fmt.Errorf("unable to parse conflicts: %v", err)
+	}
+	cfg.Conflicts = *conflicts
+
+	return nil
+}
+
+// Validate checks the limit
+func (c Conflicts) Validate() error {
+	var errs []error
+	if c.Collection == "" {
+		errs = append(errs, errors.New("CONFLICTS_COLLECTION can't be empty"))
+	}
+	if c.MaxSiblings < 1 {
+		errs = append(errs, errors.New("CONFLICTS_MAX_SIBLINGS has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/caarlos0/env/v8"
+	vaultHelper "github.com/keloran/vault-helper"
+)
+
+// CORS is the cross-origin policy for the public routes, origins can contain one * for wildcard subdomains
+// e.g. https://*.todo-list.app, anything set at VaultPath overrides the environment
+type CORS struct {
+	AllowedOrigins   []string `env:"CORS_ALLOWED_ORIGINS" envDefault:"http://localhost:3000,https://todo-list.app,https://beta.todo-list.app"`
+	AllowedMethods   []string `env:"CORS_ALLOWED_METHODS" envDefault:"GET,POST,PUT,DELETE,OPTIONS"`
+	AllowedHeaders   []string `env:"CORS_ALLOWED_HEADERS" envDefault:"Accept,Authorization,Content-Type,X-CSRF-Token,X-User-Subject,X-User-Access-Token,X-Device-ID,X-Share-Password"`
+	ExposedHeaders   []string `env:"CORS_EXPOSED_HEADERS" envDefault:"Link,X-Request-ID,ETag"`
+	AllowCredentials bool     `env:"CORS_ALLOW_CREDENTIALS" envDefault:"true"`
+	// MaxAge is in seconds, 300 is the maximum value not ignored by any of major browsers
+	MaxAge    int    `env:"CORS_MAX_AGE" envDefault:"300"`
+	VaultPath string `env:"CORS_VAULT_PATH"`
+}
+
+// Vault secret keys for the cors policy
+const (
+	corsVaultOrigins     = "allowed-origins"
+	corsVaultMethods     = "allowed-methods"
+	corsVaultHeaders     = "allowed-headers"
+	corsVaultExposed     = "exposed-headers"
+	corsVaultCredentials = "allow-credentials"
+	corsVaultMaxAge      = "max-age"
+)
+
+func BuildCORS(cfg *Config) error {
+	cors := &CORS{}
+	if err := env.Parse(cors); err != nil {
+		return fmt.Errorf("unable to parse cors: %v", err)
+	}
+
+	if cors.VaultPath != "" {
+		if cfg.Config.VaultHelper == nil {
+			return errors.New("cors vault path set without vault")
+		}
+		if err := cors.fromVault(*cfg.Config.VaultHelper); err != nil {
+			return fmt.Errorf("unable to get cors from vault: %v", err)
+		}
+	}
+
+	if cfg.Local.Development {
+		cors.AllowedOrigins = append(cors.AllowedOrigins, "http://*")
+	}
+
+	cfg.CORS = *cors
+
+	return nil
+}
+
+func (c *CORS) fromVault(vh vaultHelper.VaultHelper) error {
+	if err := vh.GetSecrets(c.VaultPath); err != nil {
+		return err
+	}
+
+	for key, dest := range map[string]*[]string{
+		corsVaultOrigins: &c.AllowedOrigins,
+		corsVaultMethods: &c.AllowedMethods,
+		corsVaultHeaders: &c.AllowedHeaders,
+		corsVaultExposed: &c.ExposedHeaders,
+	} {
+		if v, err := vh.GetSecret(key); err == nil && v != "" {
+			*dest = splitList(v)
+		}
+	}
+	if v, err := vh.GetSecret(corsVaultCredentials); err == nil && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", corsVaultCredentials, err)
+		}
+		c.AllowCredentials = b
+	}
+	if v, err := vh.GetSecret(corsVaultMaxAge); err == nil && v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", corsVaultMaxAge, err)
+		}
+		c.MaxAge = i
+	}
+
+	return nil
+}
+
+// Validate reports every problem with the policy at once
+func (c CORS) Validate() error {
+	var errs []error
+
+	if len(c.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("no allowed origins"))
+	}
+	for _, o := range c.AllowedOrigins {
+		if err := validateOrigin(o); err != nil {
+			errs = append(errs, err)
+		}
+		if o == "*" && c.AllowCredentials {
+			errs = append(errs, errors.New("origin * can't be used with credentials"))
+		}
+	}
+
+	if len(c.AllowedMethods) == 0 {
+		errs = append(errs, errors.New("no allowed methods"))
+	}
+	for _, m := range c.AllowedMethods {
+		if !isToken(m) || strings.ToUpper(m) != m {
+			errs = append(errs, fmt.Errorf("method %q is not an upper case http method", m))
+		}
+	}
+
+	for _, h := range append(append([]string{}, c.AllowedHeaders...), c.ExposedHeaders...) {
+		if h != "*" && !isToken(h) {
+			errs = append(errs, fmt.Errorf("header %q is not a valid header name", h))
+		}
+	}
+
+	if c.MaxAge < 0 || c.MaxAge > 86400 {
+		errs = append(errs, fmt.Errorf("max age %d is not between 0 and 86400", c.MaxAge))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateOrigin(o string) error {
+	if o == "*" {
+		return nil
+	}
+	if strings.Count(o, "*") > 1 {
+		return fmt.Errorf("origin %q has more than one wildcard", o)
+	}
+
+	u, err := url.Parse(strings.Replace(o, "*", "wildcard", 1))
+	if err != nil {
+		return fmt.Errorf("origin %q: %w", o, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("origin %q needs an http or https scheme", o)
+	}
+	if u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("origin %q should only be a scheme and host", o)
+	}
+
+	return nil
+}
+
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > 127 || r <= ' ' || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSValidate(t *testing.T) {
+	valid := CORS{
+		AllowedOrigins: []string{"https://todo-list.app", "https://*.todo-list.app", "http://localhost:3000"},
+		AllowedMethods: []string{"GET", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "X-User-Subject"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         300,
+	}
+
+	t.Run("valid policy", func(t *testing.T) {
+		assert.NoError(t, valid.Validate())
+	})
+
+	tests := []struct {
+		name   string
+		change func(c *CORS)
+		want   string
+	}{
+		{"credentials with any origin", func(c *CORS) { c.AllowedOrigins = []string{"*"}; c.AllowCredentials = true }, "credentials"},
+		{"origin without a scheme", func(c *CORS) { c.AllowedOrigins = []string{"todo-list.app"} }, "scheme"},
+		{"two wildcards", func(c *CORS) { c.AllowedOrigins = []string{"https://*.*.todo-list.app"} }, "more than one wildcard"},
+		{"origin with a path", func(c *CORS) { c.AllowedOrigins = []string{"https://todo-list.app/path"} }, "scheme and host"},
+		{"lower case method", func(c *CORS) { c.AllowedMethods = []string{"get"} }, "method"},
+		{"header with a space", func(c *CORS) { c.AllowedHeaders = []string{"X Bad"} }, "header"},
+		{"negative max age", func(c *CORS) { c.MaxAge = -1 }, "max age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bad := valid
+			tt.change(&bad)
+
+			err := bad.Validate()
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Devices is the config for the device registry, it is kept in Storage
+type Devices struct {
+	Collection string `env:"DEVICES_COLLECTION" envDefault:"devices"`
+	// MaxPerUser is how many devices a user can have registered and not revoked
+	MaxPerUser int `env:"DEVICES_MAX_PER_USER" envDefault:"20"`
+	// LastSeenInterval is how stale last seen can get before a request updates it, so every
+	// request doesn't turn into a write
+	LastSeenInterval time.Duration `env:"DEVICES_LAST_SEEN_INTERVAL" envDefault:"1m"`
+}
+
+func BuildDevices(cfg *Config) error {
+	devices := &Devices{}
+	if err := env.Parse(devices); err != nil {
+		return fmt.Errorf("unable to parse devices: %v", err)
+	}
+	cfg.Devices = *devices
+
+	return nil
+}
+
+// Validate checks the limits
+func (d Devices) Validate() error {
+	var errs []error
+	if d.Collection == "" {
+		errs = append(errs, errors.New("DEVICES_COLLECTION can't be empty"))
+	}
+	if d.MaxPerUser < 1 {
+		errs = append(errs, errors.New("DEVICES_MAX_PER_USER has to be at least 1"))
+	}
+	errs = append(errs, notNegative("DEVICES_LAST_SEEN_INTERVAL", d.LastSeenInterval))
+
+	return errors.Join(errs...)
+}
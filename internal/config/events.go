@@ -0,0 +1,46 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Events is the config for the list change streams
+type Events struct {
+	// MaxStreamsPerUser is how many streams a user can have open at once, one per device is typical,
+	// /list/events streams and /ws sockets both count towards it
+	MaxStreamsPerUser int `env:"SSE_MAX_STREAMS_PER_USER" envDefault:"5"`
+	// Heartbeat is how often a comment is sent so proxies don't close an idle stream
+	Heartbeat time.Duration `env:"SSE_HEARTBEAT" envDefault:"15s"`
+	// Backlog is how many events are kept per user for streams that reconnect
+	Backlog int `env:"SSE_BACKLOG" envDefault:"50"`
+	// BacklogTTL is how long those events are kept, a stream reconnecting after that starts afresh
+	BacklogTTL time.Duration `env:"SSE_BACKLOG_TTL" envDefault:"10m"`
+}
+
+func BuildEvents(cfg *Config) error {
+	events := &Events{}
+	if err := env.Parse(events); err != nil {
+		return fmt.Errorf("unable to parse events: %v", err)
+	}
+	cfg.Events = *events
+
+	return nil
+}
+
+// Validate checks the limits
+func (e Events) Validate() error {
+	var errs []error
+	if e.MaxStreamsPerUser < 1 {
+		errs = append(errs, errors.New("SSE_MAX_STREAMS_PER_USER has to be at least 1"))
+	}
+	if e.Backlog < 0 {
+		errs = append(errs, errors.New("SSE_BACKLOG can't be negative"))
+	}
+	errs = append(errs, positive("SSE_HEARTBEAT", e.Heartbeat), positive("SSE_BACKLOG_TTL", e.BacklogTTL))
+
+	return errors.Join(errs...)
+}
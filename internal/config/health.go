@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Health is the readiness config
+type Health struct {
+	// CheckTimeout is how long each dependency gets to answer
+	CheckTimeout time.Duration `env:"READINESS_CHECK_TIMEOUT" envDefault:"2s"`
+	// CacheTTL is how long a dependency result is reused before checking again
+	CacheTTL time.Duration `env:"READINESS_CACHE_TTL" envDefault:"5s"`
+	// ShutdownDelay is how long /readyz reports shutting down before the server stops accepting requests
+	ShutdownDelay time.Duration `env:"SHUTDOWN_DELAY" envDefault:"5s"`
+}
+
+func BuildHealth(cfg *Config) error {
+	health := &Health{}
+	if err := env.Parse(health); err != nil {
+		return fmt.Errorf("unable to parse health: %v", err)
+	}
+	cfg.Health = *health
+
+	return nil
+}
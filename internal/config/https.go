@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// HTTPS is the tls config for the public http server, the certificate comes from VaultPath
+// (cert and key secrets) when it is set, otherwise from the files
+type HTTPS struct {
+	Enabled        bool          `env:"HTTP_TLS_ENABLED" envDefault:"false"`
+	CertFile       string        `env:"HTTP_TLS_CERT_FILE"`
+	KeyFile        string        `env:"HTTP_TLS_KEY_FILE"`
+	VaultPath      string        `env:"HTTP_TLS_VAULT_PATH"`
+	ReloadInterval time.Duration `env:"HTTP_TLS_RELOAD_INTERVAL" envDefault:"1m"`
+	// MinVersion is 1.2 or 1.3
+	MinVersion string `env:"HTTP_TLS_MIN_VERSION" envDefault:"1.2"`
+	// CipherSuites limits the TLS 1.2 suites, go's defaults are used when empty
+	CipherSuites []string `env:"HTTP_TLS_CIPHER_SUITES"`
+	// H2C serves HTTP/2 without tls, for plaintext traffic inside the cluster
+	H2C bool `env:"HTTP_H2C" envDefault:"false"`
+}
+
+func BuildHTTPS(cfg *Config) error {
+	https := &HTTPS{}
+	if err := env.Parse(https); err != nil {
+		return fmt.Errorf("unable to parse https: %v", err)
+	}
+	cfg.HTTPS = *https
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Inbox is the config for the sealed inboxes anyone can drop items into, they are kept in Storage
+type Inbox struct {
+	AddressesCollection string `env:"INBOX_ADDRESSES_COLLECTION" envDefault:"inbox_addresses"`
+	ItemsCollection     string `env:"INBOX_ITEMS_COLLECTION" envDefault:"inbox_items"`
+	// MaxItemBytes caps the sealed item, MaxItems is how many can wait in one inbox
+	MaxItemBytes int `env:"INBOX_MAX_ITEM_BYTES" envDefault:"16384"`
+	MaxItems     int `env:"INBOX_MAX_ITEMS" envDefault:"200"`
+	// Retention is how long an item nobody takes out is kept
+	Retention time.Duration `env:"INBOX_RETENTION" envDefault:"720h"`
+	// SendsPerMinute and SendBurst limit how often one address can drop items, senders don't need an account
+	SendsPerMinute int `env:"INBOX_SENDS_PER_MINUTE" envDefault:"10"`
+	SendBurst      int `env:"INBOX_SEND_BURST" envDefault:"5"`
+}
+
+func BuildInbox(cfg *Config) error {
+	inbox := &Inbox{}
+	if err := env.Parse(inbox); err != nil {
+		return fmt.Errorf("unable to parse inbox: %v", err)
+	}
+	cfg.Inbox = *inbox
+
+	return nil
+}
+
+// Validate checks the caps and limits
+func (i Inbox) Validate() error {
+	var errs []error
+	if i.AddressesCollection == "" {
+		errs = append(errs, errors.New("INBOX_ADDRESSES_COLLECTION can't be empty"))
+	}
+	if i.ItemsCollection == "" {
+		errs = append(errs, errors.New("INBOX_ITEMS_COLLECTION can't be empty"))
+	}
+	if i.MaxItemBytes < 1 {
+		errs = append(errs, errors.New("INBOX_MAX_ITEM_BYTES has to be at least 1"))
+	}
+	if i.MaxItems < 1 {
+		errs = append(errs, errors.New("INBOX_MAX_ITEMS has to be at least 1"))
+	}
+	errs = append(errs, positive("INBOX_RETENTION", i.Retention))
+	if i.SendsPerMinute < 1 {
+		errs = append(errs, errors.New("INBOX_SENDS_PER_MINUTE has to be at least 1"))
+	}
+	if i.SendBurst < 1 {
+		errs = append(errs, errors.New("INBOX_SEND_BURST has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// InviteToken is replaced with the invite token in Invites.AcceptURL
+const InviteToken = "{token}"
+
+// Invites is the config for inviting people to a shared list by email, they are kept in Storage
+type Invites struct {
+	Collection string `env:"INVITES_COLLECTION" envDefault:"invites"`
+	// TTL is how long an invite can be accepted for
+	TTL time.Duration `env:"INVITES_TTL" envDefault:"168h"`
+	// MaxPending is how many invites a list can have waiting to be accepted
+	MaxPending int `env:"INVITES_MAX_PENDING" envDefault:"20"`
+	// AcceptURL is the link in the email, where the app accepts the invite
+	AcceptURL string `env:"INVITES_ACCEPT_URL" envDefault:"https://todo-list.app/invites/{token}"`
+	// SendsPerMinute and SendBurst limit how many invites a user can send, so they can't be used for spam
+	SendsPerMinute int `env:"INVITES_SENDS_PER_MINUTE" envDefault:"2"`
+	SendBurst      int `env:"INVITES_SEND_BURST" envDefault:"10"`
+}
+
+func BuildInvites(cfg *Config) error {
+	invites := &Invites{}
+	if err := env.Parse(invites); err != nil {
+		return fmt.Errorf("unable to parse invites: %v", err)
+	}
+	cfg.Invites = *invites
+
+	return nil
+}
+
+// Validate checks the limits and that the link has somewhere to put the token
+func (i Invites) Validate() error {
+	var errs []error
+	if i.Collection == "" {
+		errs = append(errs, errors.New("INVITES_COLLECTION can't be empty"))
+	}
+	errs = append(errs, positive("INVITES_TTL", i.TTL))
+	if i.MaxPending < 1 {
+		errs = append(errs, errors.New("INVITES_MAX_PENDING has to be at least 1"))
+	}
+	u, err := url.Parse(strings.Replace(i.AcceptURL, InviteToken, "token", 1))
+	if err != nil || u.Scheme != "https" && u.Scheme != "http" || !strings.Contains(i.AcceptURL, InviteToken) {
+		errs = append(errs, errors.New("INVITES_ACCEPT_URL has to be an http or https url containing "+InviteToken))
+	}
+	if i.SendsPerMinute < 1 {
+		errs = append(errs, errors.New("INVITES_SENDS_PER_MINUTE has to be at least 1"))
+	}
+	if i.SendBurst < 1 {
+		errs = append(errs, errors.New("INVITES_SEND_BURST has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
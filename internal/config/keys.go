@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// EmailToken is replaced with the verification token in Keys.EmailVerifyURL
+const EmailToken = "{token}"
+
+// Keys is the config for the public key directory, the key histories and verified emails are kept in Storage
+type Keys struct {
+	Collection string `env:"KEYS_COLLECTION" envDefault:"keys"`
+	// EmailsCollection holds the email each user can be found by
+	EmailsCollection string `env:"KEYS_EMAILS_COLLECTION" envDefault:"key_emails"`
+	// EmailTTL is how long a verification email can be used for
+	EmailTTL time.Duration `env:"KEYS_EMAIL_TTL" envDefault:"24h"`
+	// EmailVerifyURL is the link in the verification email, where the app sends the token back
+	EmailVerifyURL string `env:"KEYS_EMAIL_VERIFY_URL" envDefault:"https://todo-list.app/account/email/{token}"`
+	// LookupsPerMinute is how many keys a user can look up, so the directory can't be walked to find
+	// out who has an account
+	LookupsPerMinute int `env:"KEYS_LOOKUPS_PER_MINUTE" envDefault:"30"`
+	// LookupBurst is how many lookups a user can make at once, such as when sharing with a few people
+	LookupBurst int `env:"KEYS_LOOKUP_BURST" envDefault:"10"`
+}
+
+func BuildKeys(cfg *Config) error {
+	keys := &Keys{}
+	if err := env.Parse(keys); err != nil {
+		return fmt.Errorf("unable to parse keys: %v", err)
+	}
+	cfg.Keys = *keys
+
+	return nil
+}
+
+// Validate checks the collections, the verification link and the lookup limits
+func (k Keys) Validate() error {
+	var errs []error
+	if k.Collection == "" {
+		errs = append(errs, errors.New("KEYS_COLLECTION can't be empty"))
+	}
+	if k.EmailsCollection == "" {
+		errs = append(errs, errors.New("KEYS_EMAILS_COLLECTION can't be empty"))
+	}
+	errs = append(errs, positive("KEYS_EMAIL_TTL", k.EmailTTL))
+	u, err := url.Parse(strings.Replace(k.EmailVerifyURL, EmailToken, "token", 1))
+	if err != nil || u.Scheme != "https" && u.Scheme != "http" || !strings.Contains(k.EmailVerifyURL, EmailToken) {
+		errs = append(errs, errors.New("KEYS_EMAIL_VERIFY_URL has to be an http or https url containing "+EmailToken))
+	}
+	if k.LookupsPerMinute < 1 {
+		errs = append(errs, errors.New("KEYS_LOOKUPS_PER_MINUTE has to be at least 1"))
+	}
+	if k.LookupBurst < 1 {
+		errs = append(errs, errors.New("KEYS_LOOKUP_BURST has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Mail is the smtp server emails such as invites are sent through, without a host they are only logged
+type Mail struct {
+	Host     string `env:"MAIL_SMTP_HOST"`
+	Port     int    `env:"MAIL_SMTP_PORT" envDefault:"587"`
+	Username string `env:"MAIL_SMTP_USERNAME"`
+	Password string `env:"MAIL_SMTP_PASSWORD"`
+	From     string `env:"MAIL_FROM" envDefault:"Todo Lists <no-reply@todo-list.app>"`
+	// Timeout bounds sending a single email, including connecting
+	Timeout time.Duration `env:"MAIL_TIMEOUT" envDefault:"10s"`
+}
+
+func BuildMail(cfg *Config) error {
+	m := &Mail{}
+	if err := env.Parse(m); err != nil {
+		return fmt.Errorf("unable to parse mail: %v", err)
+	}
+	cfg.Mail = *m
+
+	return nil
+}
+
+// Validate checks the server and the from address
+func (m Mail) Validate() error {
+	var errs []error
+	if m.Port < 1 || m.Port > 65535 {
+		errs = append(errs, errors.New("MAIL_SMTP_PORT has to be between 1 and 65535"))
+	}
+	if _, err := mail.ParseAddress(m.From); err != nil {
+		errs = append(errs, errors.New("MAIL_FROM has to be an email address"))
+	}
+	if (m.Username == "") != (m.Password == "") {
+		errs = append(errs, errors.New("MAIL_SMTP_USERNAME and MAIL_SMTP_PASSWORD have to be set together"))
+	}
+	errs = append(errs, positive("MAIL_TIMEOUT", m.Timeout))
+
+	return errors.Join(errs...)
+}
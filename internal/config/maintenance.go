@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Maintenance modes, read-only stops writes to lists and accounts, full stops every user route
+const (
+	MaintenanceOff      = "off"
+	MaintenanceReadOnly = "read-only"
+	MaintenanceFull     = "full"
+)
+
+// Maintenance is the mode the api starts in, it can be changed at runtime through the admin routes
+type Maintenance struct {
+	Mode string `env:"MAINTENANCE_MODE" envDefault:"off"`
+	// RetryAfter is what clients are told to wait before trying again
+	RetryAfter time.Duration `env:"MAINTENANCE_RETRY_AFTER" envDefault:"5m"`
+	Message    string        `env:"MAINTENANCE_MESSAGE"`
+}
+
+func BuildMaintenance(cfg *Config) error {
+	maintenance := &Maintenance{}
+	if err := env.Parse(maintenance); err != nil {
+		return fmt.Errorf("unable to parse maintenance: %v", err)
+	}
+	cfg.Maintenance = *maintenance
+
+	return nil
+}
+
+// ValidMaintenanceMode reports whether mode is off, read-only or full
+func ValidMaintenanceMode(mode string) bool {
+	return mode == MaintenanceOff || mode == MaintenanceReadOnly || mode == MaintenanceFull
+}
+
+// Validate checks the mode and retry after
+func (m Maintenance) Validate() error {
+	var errs []error
+	if !ValidMaintenanceMode(m.Mode) {
+		errs = append(errs, fmt.Errorf("MAINTENANCE_MODE %q is not one of %s, %s or %s", m.Mode, MaintenanceOff, MaintenanceReadOnly, MaintenanceFull))
+	}
+	if m.RetryAfter < time.Second {
+		errs = append(errs, errors.New("MAINTENANCE_RETRY_AFTER has to be at least 1s"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+	gc "github.com/keloran/go-config"
+)
+
+// Reload is how the config is kept up to date while the service runs, it is also reloaded on SIGHUP
+type Reload struct {
+	// Interval is how often the config is rebuilt to pick up vault and config file changes, 0 only reloads on SIGHUP
+	Interval time.Duration `env:"CONFIG_RELOAD_INTERVAL" envDefault:"1m"`
+}
+
+func BuildReload(cfg *Config) error {
+	reload := &Reload{}
+	if err := env.Parse(reload); err != nil {
+		return fmt.Errorf("unable to parse reload: %v", err)
+	}
+	cfg.Reload = *reload
+
+	return nil
+}
+
+// Validate checks the interval
+func (r Reload) Validate() error {
+	return notNegative("CONFIG_RELOAD_INTERVAL", r.Interval)
+}
+
+// Subscriber applies a new config, it either applies all of it or returns an error and keeps what it had
+type Subscriber interface {
+	Apply(cfg *Config) error
+}
+
+// SubscriberFunc lets a plain function subscribe to config changes
+type SubscriberFunc func(cfg *Config) error
+
+// Apply calls f
+func (f SubscriberFunc) Apply(cfg *Config) error {
+	return f(cfg)
+}
+
+// Reloader holds the active config and swaps it when a reload builds a different, valid one.
+// A new config is only made active once every subscriber has applied it, if any of them fails
+// the ones that already applied it are given the old config back
+type Reloader struct {
+	build  func() (*Config, error)
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	subs    []subscriber
+	current atomic.Pointer[Config]
+}
+
+type subscriber struct {
+	name string
+	sub  Subscriber
+}
+
+// NewReloader starts with cfg active, build is how a new config is made, normally Build
+func NewReloader(cfg *Config, build func() (*Config, error), logger *slog.Logger) *Reloader {
+	r := &Reloader{build: build, logger: logger}
+	r.current.Store(cfg)
+
+	return r
+}
+
+// Current returns the active config, it must not be modified
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Subscribe adds a subscriber, they are applied in the order they subscribed
+func (r *Reloader) Subscribe(name string, s Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subs = append(r.subs, subscriber{name: name, sub: s})
+}
+
+// Reload builds the config again, reporting whether it changed, an invalid config is rejected and the old one stays active
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.build()
+	if err != nil {
+		return false, fmt.Errorf("rejected config: %v", err)
+	}
+
+	old := r.current.Load()
+	if old.Equal(next) {
+		return false, nil
+	}
+
+	for i, s := range r.subs {
+		if err := s.sub.Apply(next); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rerr := r.subs[j].sub.Apply(old); rerr != nil {
+					r.logger.Error("restore config", "subscriber", r.subs[j].name, "error", rerr)
+				}
+			}
+			return false, fmt.Errorf("rejected config, %s: %v", s.name, err)
+		}
+	}
+
+	for _, section := range old.restartRequired(next) {
+		r.logger.Warn("config changed, it needs a restart to apply", "section", section)
+	}
+	r.current.Store(next)
+
+	return true, nil
+}
+
+// Watch reloads whenever hup fires, and every interval so vault changes are picked up, until ctx is done
+func (r *Reloader) Watch(ctx context.Context, hup <-chan os.Signal, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+		}
+
+		changed, err := r.Reload()
+		if err != nil {
+			r.logger.Error("reload config", "error", err)
+			continue
+		}
+		if changed {
+			r.logger.Info("config reloaded")
+		}
+	}
+}
+
+// Equal reports whether two configs would behave the same, connections to vault and the
+// environment snapshot are ignored
+func (c *Config) Equal(o *Config) bool {
+	a, b := *c, *o
+	a.Config = gc.Config{Local: c.Local}
+	b.Config = gc.Config{Local: o.Local}
+	a.Local.EnvMap, b.Local.EnvMap = nil, nil
+
+	return reflect.DeepEqual(a, b)
+}
+
+// restartRequired names the sections that changed but are only read when the server starts
+func (c *Config) restartRequired(o *Config) []string {
+	var sections []string
+	if !reflect.DeepEqual(c.Server, o.Server) || c.Local.HTTPPort != o.Local.HTTPPort {
+		sections = append(sections, "server")
+	}
+	if !reflect.DeepEqual(c.HTTPS, o.HTTPS) {
+		sections = append(sections, "https")
+	}
+	if !reflect.DeepEqual(c.Storage, o.Storage) {
+		sections = append(sections, "storage")
+	}
+	if c.Changes.Collection != o.Changes.Collection || c.Changes.Retention != o.Changes.Retention {
+		sections = append(sections, "changes")
+	}
+	if c.Devices.Collection != o.Devices.Collection {
+		sections = append(sections, "devices")
+	}
+	if c.Conflicts.Collection != o.Conflicts.Collection {
+		sections = append(sections, "conflicts")
+	}
+	if c.Sharing.ListsCollection != o.Sharing.ListsCollection || c.Sharing.GrantsCollection != o.Sharing.GrantsCollection {
+		sections = append(sections, "sharing")
+	}
+	if c.Keys.Collection != o.Keys.Collection || c.Keys.EmailsCollection != o.Keys.EmailsCollection {
+		sections = append(sections, "keys")
+	}
+	if c.Invites.Collection != o.Invites.Collection {
+		sections = append(sections, "invites")
+	}
+	if c.Shares.Collection != o.Shares.Collection {
+		sections = append(sections, "shares")
+	}
+	if c.Inbox.AddressesCollection != o.Inbox.AddressesCollection || c.Inbox.ItemsCollection != o.Inbox.ItemsCollection || c.Inbox.Retention != o.Inbox.Retention {
+		sections = append(sections, "inbox")
+	}
+
+	return sections
+}
+
+// loadEnvFile sets the KEY=VALUE lines of CONFIG_FILE in the environment, it is read on every build so a
+// mounted config map can change settings without a restart, keys removed from the file keep their last value
+func loadEnvFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("config file line %q is not KEY=VALUE", line)
+		}
+		if err := os.Setenv(strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), `"`)); err != nil {
+			return fmt.Errorf("set %s: %v", key, err)
+		}
+	}
+
+	return scanner.Err()
+}
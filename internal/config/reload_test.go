@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	first := &Config{}
+	first.Services.Todo = "todo:3000"
+
+	var next *Config
+	var buildErr error
+	r := NewReloader(first, func() (*Config, error) {
+		return next, buildErr
+	}, slog.New(slog.DiscardHandler))
+
+	var applied []string
+	r.Subscribe("first", SubscriberFunc(func(cfg *Config) error {
+		applied = append(applied, "first "+cfg.Services.Todo)
+		return nil
+	}))
+	failing := false
+	r.Subscribe("second", SubscriberFunc(func(cfg *Config) error {
+		if failing {
+			return errors.New("can't apply")
+		}
+		applied = append(applied, "second "+cfg.Services.Todo)
+		return nil
+	}))
+
+	t.Run("unchanged", func(t *testing.T) {
+		applied = nil
+		next = &Config{}
+		next.Services.Todo = "todo:3000"
+
+		changed, err := r.Reload()
+		assert.NoError(t, err)
+		assert.False(t, changed)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		applied = nil
+		buildErr = errors.New("TODO_SERVICE: bad address")
+
+		changed, err := r.Reload()
+		assert.Error(t, err)
+		assert.False(t, changed)
+		assert.Empty(t, applied)
+		assert.Same(t, first, r.Current())
+		buildErr = nil
+	})
+
+	t.Run("subscriber fails", func(t *testing.T) {
+		applied = nil
+		failing = true
+		next = &Config{}
+		next.Services.Todo = "todo:4000"
+
+		changed, err := r.Reload()
+		assert.Error(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, []string{"first todo:4000", "first todo:3000"}, applied)
+		assert.Same(t, first, r.Current())
+		failing = false
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		applied = nil
+
+		changed, err := r.Reload()
+		assert.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, []string{"first todo:4000", "second todo:4000"}, applied)
+		assert.Same(t, next, r.Current())
+	})
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	os.Clearenv()
+	path := filepath.Join(t.TempDir(), "config.env")
+	assert.NoError(t, os.WriteFile(path, []byte("# services\nTODO_SERVICE=todo:4000\nCORS_ALLOWED_ORIGINS=\"https://todo-list.app\"\n"), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	assert.NoError(t, loadEnvFile())
+	assert.Equal(t, "todo:4000", os.Getenv("TODO_SERVICE"))
+	assert.Equal(t, "https://todo-list.app", os.Getenv("CORS_ALLOWED_ORIGINS"))
+
+	assert.NoError(t, os.WriteFile(path, []byte("not a setting\n"), 0o600))
+	assert.Error(t, loadEnvFile())
+}
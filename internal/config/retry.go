@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Retry is the deadline and retry config for calls to the downstream services
+type Retry struct {
+	// Timeout is the deadline for a call, including any retries
+	Timeout time.Duration `env:"RPC_TIMEOUT" envDefault:"5s"`
+	// Timeouts overrides Timeout per method, e.g. "Get:2s,Update:10s"
+	Timeouts map[string]time.Duration `env:"RPC_TIMEOUTS"`
+	// MaxAttempts is the most times an idempotent call is tried, including the first
+	MaxAttempts int `env:"RPC_MAX_ATTEMPTS" envDefault:"3"`
+	// InitialBackoff is the backoff before the first retry, it doubles each retry up to MaxBackoff
+	InitialBackoff time.Duration `env:"RPC_INITIAL_BACKOFF" envDefault:"100ms"`
+	MaxBackoff     time.Duration `env:"RPC_MAX_BACKOFF" envDefault:"2s"`
+	// BudgetRatio is how many retries each call earns for the shared budget, 0.1 allows one retry in ten calls
+	BudgetRatio float64 `env:"RPC_RETRY_BUDGET_RATIO" envDefault:"0.1"`
+	// BudgetMax is the most retries the budget can hold, it starts full
+	BudgetMax float64 `env:"RPC_RETRY_BUDGET_MAX" envDefault:"10"`
+}
+
+func BuildRetry(cfg *Config) error {
+	retry := &Retry{}
+	if err := env.Parse(retry); err != nil {
+		return fmt.Errorf("unable to parse retry: %v", err)
+	}
+	cfg.Retry = *retry
+
+	return nil
+}
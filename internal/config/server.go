@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// UnixPrefix marks a listen address as a unix socket path, e.g. unix:/run/api.sock
+const UnixPrefix = "unix:"
+
+// Server is the config for the public http server
+type Server struct {
+	// Address is host:port or unix:/path, it defaults to the HTTP_PORT from the local config
+	Address string `env:"HTTP_ADDRESS"`
+	// InternalAddress serves health, metrics and admin routes, it must never be exposed through the ingress
+	InternalAddress   string        `env:"HTTP_INTERNAL_ADDRESS" envDefault:":8081"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"5s"`
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"15s"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"15s"`
+	MaxHeaderBytes    int           `env:"HTTP_MAX_HEADER_BYTES" envDefault:"1048576"`
+	// MaxBodyBytes limits request bodies, lists are stored encrypted so they can't be trimmed
+	MaxBodyBytes int64 `env:"HTTP_MAX_BODY_BYTES" envDefault:"4194304"`
+	// ShutdownGrace is how long in-flight requests get to finish once the server stops accepting them
+	ShutdownGrace time.Duration `env:"HTTP_SHUTDOWN_GRACE" envDefault:"15s"`
+	// TrustForwardedFor takes the client address from the last X-Forwarded-For entry, only turn it on
+	// behind a proxy that appends to the header, otherwise clients can claim any address
+	TrustForwardedFor bool `env:"HTTP_TRUST_FORWARDED_FOR" envDefault:"false"`
+}
+
+func BuildServer(cfg *Config) error {
+	server := &Server{}
+	if err := env.Parse(server); err != nil {
+		return fmt.Errorf("unable to parse server: %v", err)
+	}
+	if server.Address == "" {
+		server.Address = fmt.Sprintf(":%d", cfg.Local.HTTPPort)
+	}
+	cfg.Server = *server
+
+	return nil
+}
+
+// Listener returns the network and address for the public server
+func (s Server) Listener() (network, address string) {
+	return listener(s.Address)
+}
+
+// InternalListener returns the network and address for the internal server
+func (s Server) InternalListener() (network, address string) {
+	return listener(s.InternalAddress)
+}
+
+func listener(address string) (string, string) {
+	if path, ok := strings.CutPrefix(address, UnixPrefix); ok {
+		return "unix", path
+	}
+
+	return "tcp", address
+}
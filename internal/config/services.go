@@ -1,20 +1,48 @@
 package config
 
 import (
-	"github.com/bugfixes/go-bugfixes/logs"
+	"fmt"
+	"time"
+
 	"github.com/caarlos0/env/v8"
 )
 
+// TLS modes for a downstream service
+const (
+	TLSInsecure = "insecure"
+	TLSServer   = "tls"
+	TLSMutual   = "mtls"
+)
+
+// TLS is the transport security used to reach a downstream service, the material comes from
+// VaultPath (ca, cert and key secrets) when it is set, otherwise from the files
+type TLS struct {
+	Mode           string        `env:"MODE" envDefault:"insecure"`
+	CAFile         string        `env:"CA_FILE"`
+	CertFile       string        `env:"CERT_FILE"`
+	KeyFile        string        `env:"KEY_FILE"`
+	VaultPath      string        `env:"VAULT_PATH"`
+	ServerName     string        `env:"SERVER_NAME"`
+	ReloadInterval time.Duration `env:"RELOAD_INTERVAL" envDefault:"1m"`
+}
+
 type Services struct {
 	Identity string `env:"IDENTITY_SERVICE" envDefault:"id-checker.todo-list:3000"`
 	Todo     string `env:"TODO_SERVICE" envDefault:"todo-service.todo-list:3000"`
 	User     string `env:"USER_SERVICE" envDefault:"user-service.todo-list:3000"`
+
+	// PropagateAccessToken sends the users access token downstream so the services can check it themselves
+	PropagateAccessToken bool `env:"PROPAGATE_ACCESS_TOKEN" envDefault:"false"`
+
+	IdentityTLS TLS `envPrefix:"IDENTITY_SERVICE_TLS_"`
+	TodoTLS     TLS `envPrefix:"TODO_SERVICE_TLS_"`
+	UserTLS     TLS `envPrefix:"USER_SERVICE_TLS_"`
 }
 
 func BuildServices(cfg *Config) error {
 	services := &Services{}
 	if err := env.Parse(services); err != nil {
-		return logs.Errorf("unable to parse services: %v", err)
+		return fmt.Errorf("unable to parse services: %v", err)
 	}
 	cfg.Services = *services
 
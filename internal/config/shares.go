@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Shares is the config for read-only public links to a snapshot of a list, they are kept in Storage
+type Shares struct {
+	Collection string `env:"SHARES_COLLECTION" envDefault:"shares"`
+	// DefaultTTL is how long a link works when the client doesn't say, MaxTTL is the longest it can ask for
+	DefaultTTL time.Duration `env:"SHARES_DEFAULT_TTL" envDefault:"168h"`
+	MaxTTL     time.Duration `env:"SHARES_MAX_TTL" envDefault:"720h"`
+	// ViewsPerMinute and ViewBurst limit how often one address can open links, which don't need an account
+	ViewsPerMinute int `env:"SHARES_VIEWS_PER_MINUTE" envDefault:"30"`
+	ViewBurst      int `env:"SHARES_VIEW_BURST" envDefault:"10"`
+	// PasswordAttemptsPerMinute limits guesses at a links password from one address
+	PasswordAttemptsPerMinute int `env:"SHARES_PASSWORD_ATTEMPTS_PER_MINUTE" envDefault:"5"`
+	// LinkPasswordAttemptsPerMinute limits guesses at a links password from every address together, it is
+	// well above the limit for one address so a single guesser can't lock everyone else out
+	LinkPasswordAttemptsPerMinute int `env:"SHARES_LINK_PASSWORD_ATTEMPTS_PER_MINUTE" envDefault:"60"`
+}
+
+func BuildShares(cfg *Config) error {
+	shares := &Shares{}
+	if err := env.Parse(shares); err != nil {
+		return fmt.Errorf("unable to parse shares: %v", err)
+	}
+	cfg.Shares = *shares
+
+	return nil
+}
+
+// Validate checks the lifetimes and limits
+func (s Shares) Validate() error {
+	var errs []error
+	if s.Collection == "" {
+		errs = append(errs, errors.New("SHARES_COLLECTION can't be empty"))
+	}
+	errs = append(errs, positive("SHARES_DEFAULT_TTL", s.DefaultTTL), positive("SHARES_MAX_TTL", s.MaxTTL))
+	if s.DefaultTTL > s.MaxTTL {
+		errs = append(errs, errors.New("SHARES_DEFAULT_TTL can't be longer than SHARES_MAX_TTL"))
+	}
+	if s.ViewsPerMinute < 1 {
+		errs = append(errs, errors.New("SHARES_VIEWS_PER_MINUTE has to be at least 1"))
+	}
+	if s.ViewBurst < 1 {
+		errs = append(errs, errors.New("SHARES_VIEW_BURST has to be at least 1"))
+	}
+	if s.PasswordAttemptsPerMinute < 1 {
+		errs = append(errs, errors.New("SHARES_PASSWORD_ATTEMPTS_PER_MINUTE has to be at least 1"))
+	}
+	if s.LinkPasswordAttemptsPerMinute < s.PasswordAttemptsPerMinute {
+		errs = append(errs, errors.New("SHARES_LINK_PASSWORD_ATTEMPTS_PER_MINUTE can't be less than SHARES_PASSWORD_ATTEMPTS_PER_MINUTE"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// Sharing is the config for lists shared between users, they are kept in Storage
+type Sharing struct {
+	ListsCollection  string `env:"SHARING_LISTS_COLLECTION" envDefault:"shared_lists"`
+	GrantsCollection string `env:"SHARING_GRANTS_COLLECTION" envDefault:"grants"`
+	// MaxGrants is how many users a list can be shared with, not counting the owner
+	MaxGrants int `env:"SHARING_MAX_GRANTS" envDefault:"50"`
+}
+
+func BuildSharing(cfg *Config) error {
+	sharing := &Sharing{}
+	if err := env.Parse(sharing); err != nil {
+		return fmt.Errorf("unable to parse sharing: %v", err)
+	}
+	cfg.Sharing = *sharing
+
+	return nil
+}
+
+// Validate checks the collections and the limit
+func (s Sharing) Validate() error {
+	var errs []error
+	if s.ListsCollection == "" {
+		errs = append(errs, errors.New("SHARING_LISTS_COLLECTION can't be empty"))
+	}
+	if s.GrantsCollection == "" {
+		errs = append(errs, errors.New("SHARING_GRANTS_COLLECTION can't be empty"))
+	}
+	if s.ListsCollection != "" && s.ListsCollection == s.GrantsCollection {
+		errs = append(errs, errors.New("SHARING_LISTS_COLLECTION and SHARING_GRANTS_COLLECTION have to be different"))
+	}
+	if s.MaxGrants < 1 {
+		errs = append(errs, errors.New("SHARING_MAX_GRANTS has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// StorageVaultURL is the vault secret the mongo url is read from
+const StorageVaultURL = "mongo-url"
+
+// Storage is the mongo database the api keeps its own records in, such as the change feed and devices,
+// without a url they are only kept in memory and are lost on a restart
+type Storage struct {
+	MongoURL  string `env:"STORAGE_MONGO_URL"`
+	Database  string `env:"STORAGE_MONGO_DB" envDefault:"todo-list"`
+	VaultPath string `env:"STORAGE_VAULT_PATH"`
+}
+
+func BuildStorage(cfg *Config) error {
+	storage := &Storage{}
+	if err := env.Parse(storage); err != nil {
+		return fmt.Errorf("unable to parse storage: %v", err)
+	}
+
+	if storage.VaultPath != "" {
+		if cfg.Config.VaultHelper == nil {
+			return errors.New("storage vault path set without vault")
+		}
+		vh := *cfg.Config.VaultHelper
+		if err := vh.GetSecrets(storage.VaultPath); err != nil {
+			return fmt.Errorf("unable to get storage secrets: %v", err)
+		}
+		u, err := vh.GetSecret(StorageVaultURL)
+		if err != nil {
+			return fmt.Errorf("unable to get storage mongo url: %v", err)
+		}
+		storage.MongoURL = u
+	}
+	cfg.Storage = *storage
+
+	return nil
+}
+
+// Validate checks the url is one the mongo driver can use
+func (s Storage) Validate() error {
+	if s.MongoURL == "" {
+		return nil
+	}
+
+	var errs []error
+	u, err := url.Parse(s.MongoURL)
+	if err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") || u.Host == "" {
+		errs = append(errs, errors.New("STORAGE_MONGO_URL has to be a mongodb:// or mongodb+srv:// url"))
+	}
+	if s.Database == "" {
+		errs = append(errs, errors.New("STORAGE_MONGO_DB can't be empty"))
+	}
+
+	return errors.Join(errs...)
+}
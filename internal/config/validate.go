@@ -0,0 +1,265 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/certs"
+)
+
+// Validate checks every section and reports all the problems at once, so a bad deploy can be fixed in one go
+func (c *Config) Validate() error {
+	var errs []error
+	for _, section := range []struct {
+		name     string
+		validate func() error
+	}{
+		{"services", c.Services.Validate},
+		{"health", c.Health.Validate},
+		{"breaker", c.Breaker.Validate},
+		{"retry", c.Retry.Validate},
+		{"https", c.HTTPS.Validate},
+		{"cors", c.CORS.Validate},
+		{"server", c.Server.Validate},
+		{"reload", c.Reload.Validate},
+		{"admin", c.Admin.Validate},
+		{"logging", c.Logging.Validate},
+		{"maintenance", c.Maintenance.Validate},
+		{"events", c.Events.Validate},
+		{"websocket", c.WebSocket.Validate},
+		{"storage", c.Storage.Validate},
+		{"changes", c.Changes.Validate},
+		{"devices", c.Devices.Validate},
+		{"conflicts", c.Conflicts.Validate},
+		{"sharing", c.Sharing.Validate},
+		{"keys", c.Keys.Validate},
+		{"mail", c.Mail.Validate},
+		{"invites", c.Invites.Validate},
+		{"shares", c.Shares.Validate},
+		{"inbox", c.Inbox.Validate},
+	} {
+		if err := section.validate(); err != nil {
+			errs = append(errs, prefixErrors(section.name, err)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the downstream addresses and their tls config
+func (s Services) Validate() error {
+	var errs []error
+	for _, svc := range []struct {
+		env     string
+		address string
+		tls     TLS
+	}{
+		{"IDENTITY_SERVICE", s.Identity, s.IdentityTLS},
+		{"TODO_SERVICE", s.Todo, s.TodoTLS},
+		{"USER_SERVICE", s.User, s.UserTLS},
+	} {
+		if err := validateTarget(svc.address); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", svc.env, err))
+		}
+		if err := svc.tls.Validate(); err != nil {
+			errs = append(errs, prefixErrors(svc.env+"_TLS", err)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the mode has the material it needs
+func (t TLS) Validate() error {
+	var errs []error
+	switch t.Mode {
+	case TLSInsecure, TLSServer:
+	case TLSMutual:
+		if t.VaultPath == "" && (t.CertFile == "" || t.KeyFile == "") {
+			errs = append(errs, errors.New("mtls needs a cert and key file or a vault path"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mode %q is not one of %s, %s or %s", t.Mode, TLSInsecure, TLSServer, TLSMutual))
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("cert and key files have to be set together"))
+	}
+	if t.ReloadInterval < 0 {
+		errs = append(errs, errors.New("reload interval can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the readiness timings
+func (h Health) Validate() error {
+	return errors.Join(
+		positive("READINESS_CHECK_TIMEOUT", h.CheckTimeout),
+		positive("READINESS_CACHE_TTL", h.CacheTTL),
+		notNegative("SHUTDOWN_DELAY", h.ShutdownDelay),
+	)
+}
+
+// Validate checks the breaker can open and close again
+func (b Breaker) Validate() error {
+	var errs []error
+	if b.FailureThreshold < 1 {
+		errs = append(errs, errors.New("BREAKER_FAILURE_THRESHOLD has to be at least 1"))
+	}
+	if b.HalfOpenProbes < 1 {
+		errs = append(errs, errors.New("BREAKER_HALF_OPEN_PROBES has to be at least 1"))
+	}
+	errs = append(errs, positive("BREAKER_OPEN_DURATION", b.OpenDuration))
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the deadlines and backoff, a zero timeout means calls have no deadline
+func (r Retry) Validate() error {
+	var errs []error
+	errs = append(errs, notNegative("RPC_TIMEOUT", r.Timeout))
+	for method, t := range r.Timeouts {
+		errs = append(errs, positive("RPC_TIMEOUTS "+method, t))
+	}
+	if r.MaxAttempts < 1 {
+		errs = append(errs, errors.New("RPC_MAX_ATTEMPTS has to be at least 1"))
+	}
+	errs = append(errs, positive("RPC_INITIAL_BACKOFF", r.InitialBackoff))
+	if r.MaxBackoff < r.InitialBackoff {
+		errs = append(errs, errors.New("RPC_MAX_BACKOFF can't be less than RPC_INITIAL_BACKOFF"))
+	}
+	if r.BudgetRatio < 0 || r.BudgetMax < 0 {
+		errs = append(errs, errors.New("RPC_RETRY_BUDGET_RATIO and RPC_RETRY_BUDGET_MAX can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the tls version and suites, and that there is somewhere to get a certificate from
+func (h HTTPS) Validate() error {
+	var errs []error
+	if _, err := certs.ParseVersion(h.MinVersion); err != nil {
+		errs = append(errs, fmt.Errorf("HTTP_TLS_MIN_VERSION: %w", err))
+	}
+	if _, err := certs.ParseCipherSuites(h.CipherSuites); err != nil {
+		errs = append(errs, fmt.Errorf("HTTP_TLS_CIPHER_SUITES: %w", err))
+	}
+	if h.Enabled && h.VaultPath == "" && (h.CertFile == "" || h.KeyFile == "") {
+		errs = append(errs, errors.New("HTTP_TLS_ENABLED needs HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE or HTTP_TLS_VAULT_PATH"))
+	}
+	errs = append(errs, notNegative("HTTP_TLS_RELOAD_INTERVAL", h.ReloadInterval))
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the listen address and limits, zero timeouts are allowed and mean no timeout
+func (s Server) Validate() error {
+	var errs []error
+	for _, l := range []struct {
+		env     string
+		address string
+	}{
+		{"HTTP_ADDRESS", s.Address},
+		{"HTTP_INTERNAL_ADDRESS", s.InternalAddress},
+	} {
+		if err := validateListen(l.address); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", l.env, err))
+		}
+	}
+	if s.Address == s.InternalAddress {
+		errs = append(errs, errors.New("HTTP_INTERNAL_ADDRESS has to be different to HTTP_ADDRESS"))
+	}
+
+	errs = append(errs,
+		notNegative("HTTP_READ_TIMEOUT", s.ReadTimeout),
+		positive("HTTP_READ_HEADER_TIMEOUT", s.ReadHeaderTimeout),
+		notNegative("HTTP_WRITE_TIMEOUT", s.WriteTimeout),
+		notNegative("HTTP_IDLE_TIMEOUT", s.IdleTimeout),
+		positive("HTTP_SHUTDOWN_GRACE", s.ShutdownGrace),
+	)
+	if s.MaxHeaderBytes < 1 {
+		errs = append(errs, errors.New("HTTP_MAX_HEADER_BYTES has to be at least 1"))
+	}
+	if s.MaxBodyBytes < 1 {
+		errs = append(errs, errors.New("HTTP_MAX_BODY_BYTES has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateListen checks a listen address, host:port where the host can be left out, or a unix socket
+func validateListen(address string) error {
+	network, address := listener(address)
+	if network == "unix" {
+		if address == "" {
+			return errors.New("unix socket needs a path")
+		}
+		return nil
+	}
+
+	return validateHostPort(address, true)
+}
+
+// validateTarget checks a grpc dial target, host:port optionally behind a dns:/// scheme, or a unix socket
+func validateTarget(target string) error {
+	if target == "" {
+		return errors.New("address is empty")
+	}
+	if path, ok := strings.CutPrefix(target, UnixPrefix); ok {
+		if strings.TrimLeft(path, "/") == "" {
+			return errors.New("unix socket needs a path")
+		}
+		return nil
+	}
+	target = strings.TrimPrefix(target, "dns:///")
+
+	return validateHostPort(target, false)
+}
+
+func validateHostPort(address string, emptyHost bool) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%q is not host:port", address)
+	}
+	if host == "" && !emptyHost {
+		return fmt.Errorf("%q has no host", address)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("%q has an invalid port", address)
+	}
+
+	return nil
+}
+
+func positive(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s has to be more than 0, got %s", name, d)
+	}
+
+	return nil
+}
+
+func notNegative(name string, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("%s can't be negative, got %s", name, d)
+	}
+
+	return nil
+}
+
+// prefixErrors splits a joined error so every problem is reported on its own line with where it came from
+func prefixErrors(prefix string, err error) []error {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, prefixErrors(prefix, e)...)
+		}
+		return errs
+	}
+
+	return []error{fmt.Errorf("%s: %w", prefix, err)}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	os.Clearenv()
+
+	cfg := &Config{}
+	cfg.Local.HTTPPort = 8080
+	for _, build := range []func(*Config) error{BuildServices, BuildHealth, BuildBreaker, BuildRetry, BuildHTTPS, BuildCORS, BuildServer, BuildReload, BuildAdmin, BuildLogging, BuildMaintenance, BuildEvents, BuildWebSocket, BuildStorage, BuildChanges, BuildDevices, BuildConflicts, BuildSharing, BuildKeys, BuildMail, BuildInvites, BuildShares, BuildInbox} {
+		if err := build(cfg); err != nil {
+			t.Fatalf("build: %v", err)
+		}
+	}
+
+	return cfg
+}
+
+func TestValidateDefaults(t *testing.T) {
+	cfg := validConfig(t)
+
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, ":8080", cfg.Server.Address)
+}
+
+func TestValidateReportsEverything(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Services.Todo = "todo-service"
+	cfg.Services.UserTLS.Mode = TLSMutual
+	cfg.Breaker.FailureThreshold = 0
+	cfg.Retry.MaxBackoff = time.Millisecond
+	cfg.HTTPS.MinVersion = "1.1"
+	cfg.CORS.AllowedOrigins = []string{"todo-list.app"}
+	cfg.Server.Address = "localhost:99999"
+	cfg.Server.ShutdownGrace = 0
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+
+	lines := strings.Split(err.Error(), "\n")
+	assert.Len(t, lines, 8)
+	for _, want := range []string{
+		"services: TODO_SERVICE",
+		"services: USER_SERVICE_TLS: mtls",
+		"breaker: BREAKER_FAILURE_THRESHOLD",
+		"retry: RPC_MAX_BACKOFF",
+		"https: HTTP_TLS_MIN_VERSION",
+		"cors: origin",
+		"server: HTTP_ADDRESS",
+		"server: HTTP_SHUTDOWN_GRACE",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestValidateTargets(t *testing.T) {
+	tests := []struct {
+		target string
+		valid  bool
+	}{
+		{"id-checker.todo-list:3000", true},
+		{"dns:///id-checker.todo-list:3000", true},
+		{"unix:/run/id-checker.sock", true},
+		{"127.0.0.1:3000", true},
+		{"", false},
+		{"id-checker", false},
+		{":3000", false},
+		{"id-checker:http", false},
+		{"unix:", false},
+	}
+	for _, tt := range tests {
+		err := validateTarget(tt.target)
+		assert.Equal(t, tt.valid, err == nil, "%q: %v", tt.target, err)
+	}
+}
+
+func TestServerListener(t *testing.T) {
+	network, address := Server{Address: "unix:/run/api.sock"}.Listener()
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/run/api.sock", address)
+
+	network, address = Server{Address: ":80"}.Listener()
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, ":80", address)
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+)
+
+// WebSocket is the config for the /ws sync channel
+type WebSocket struct {
+	// PingInterval is how often the server pings, keeping proxies from closing an idle socket, a client
+	// that sends nothing, not even a pong, for two intervals is closed
+	PingInterval time.Duration `env:"WS_PING_INTERVAL" envDefault:"30s"`
+	// WriteTimeout is how long a single message gets to be written before the client is dropped
+	WriteTimeout time.Duration `env:"WS_WRITE_TIMEOUT" envDefault:"10s"`
+	// SendBuffer is how many replies can be queued for a client that isn't reading before it is dropped
+	SendBuffer int `env:"WS_SEND_BUFFER" envDefault:"32"`
+}
+
+func BuildWebSocket(cfg *Config) error {
+	ws := &WebSocket{}
+	if err := env.Parse(ws); err != nil {
+		return fmt.Errorf("unable to parse websocket: %v", err)
+	}
+	cfg.WebSocket = *ws
+
+	return nil
+}
+
+// Validate checks the timings and buffer
+func (w WebSocket) Validate() error {
+	var errs []error
+	errs = append(errs,
+		positive("WS_PING_INTERVAL", w.PingInterval),
+		positive("WS_WRITE_TIMEOUT", w.WriteTimeout),
+	)
+	if w.SendBuffer < 1 {
+		errs = append(errs, errors.New("WS_SEND_BUFFER has to be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package conflicts
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps siblings in memory, for development and tests
+type MemoryStore struct {
+	mu       sync.Mutex
+	siblings map[string][]Sibling
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		siblings: make(map[string][]Sibling),
+	}
+}
+
+// Add implements Store
+func (m *MemoryStore) Add(_ context.Context, s Sibling) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.siblings[s.Subject] = append(m.siblings[s.Subject], s)
+	return nil
+}
+
+// List implements Store
+func (m *MemoryStore) List(_ context.Context, subject string) ([]Sibling, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Sibling(nil), m.siblings[subject]...), nil
+}
+
+// Remove implements Store
+func (m *MemoryStore) Remove(_ context.Context, subject string, ids []string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.siblings[subject])
+	m.siblings[subject] = slices.DeleteFunc(m.siblings[subject], func(s Sibling) bool {
+		return slices.Contains(ids, s.ID)
+	})
+
+	return before - len(m.siblings[subject]), nil
+}
+
+// DeleteAll implements Store
+func (m *MemoryStore) DeleteAll(_ context.Context, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.siblings, subject)
+	return nil
+}
@@ -0,0 +1,73 @@
+package conflicts
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps siblings in a collection
+type MongoStore struct {
+	siblings *mongo.Collection
+}
+
+// NewMongoStore makes sure the index exists
+func NewMongoStore(ctx context.Context, db *mongo.Database, collection string) (*MongoStore, error) {
+	m := &MongoStore{
+		siblings: db.Collection(collection),
+	}
+
+	if _, err := m.siblings.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "subject", Value: 1}, {Key: "at", Value: 1}},
+	}); err != nil {
+		return nil, fmt.Errorf("create subject index: %v", err)
+	}
+
+	return m, nil
+}
+
+// Add implements Store
+func (m *MongoStore) Add(ctx context.Context, s Sibling) error {
+	if _, err := m.siblings.InsertOne(ctx, s); err != nil {
+		return fmt.Errorf("insert sibling: %v", err)
+	}
+
+	return nil
+}
+
+// List implements Store
+func (m *MongoStore) List(ctx context.Context, subject string) ([]Sibling, error) {
+	cur, err := m.siblings.Find(ctx, bson.M{"subject": subject}, options.Find().SetSort(bson.D{{Key: "at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find siblings: %v", err)
+	}
+
+	var siblings []Sibling
+	if err := cur.All(ctx, &siblings); err != nil {
+		return nil, fmt.Errorf("read siblings: %v", err)
+	}
+
+	return siblings, nil
+}
+
+// Remove implements Store
+func (m *MongoStore) Remove(ctx context.Context, subject string, ids []string) (int, error) {
+	res, err := m.siblings.DeleteMany(ctx, bson.M{"subject": subject, "_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("remove siblings: %v", err)
+	}
+
+	return int(res.DeletedCount), nil
+}
+
+// DeleteAll implements Store
+func (m *MongoStore) DeleteAll(ctx context.Context, subject string) error {
+	if _, err := m.siblings.DeleteMany(ctx, bson.M{"subject": subject}); err != nil {
+		return fmt.Errorf("delete siblings: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package conflicts keeps the versions of a list that were written from a stale revision, so no
+// devices edits are lost, they stay as siblings of the list until a client merges them
+package conflicts
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/locks"
+)
+
+var (
+	// ErrTooMany is returned when the list already has as many siblings as it is allowed
+	ErrTooMany = errors.New("too many unresolved conflicts, resolve them first")
+	// ErrUnknownSibling is returned when resolving a sibling that doesn't exist, usually because
+	// another device has already resolved it
+	ErrUnknownSibling = errors.New("unknown or already resolved conflict")
+)
+
+// Sibling is a version of the list written from a stale revision, the server only ever sees ciphertext
+type Sibling struct {
+	ID      string `json:"id" bson:"_id"`
+	Subject string `json:"-" bson:"subject"`
+	Data    string `json:"data" bson:"data"`
+	IV      string `json:"iv" bson:"iv"`
+	// Base is the revision the device edited from
+	Base string `json:"base" bson:"base"`
+	// Revision identifies this version, the same way the lists ETag does
+	Revision string    `json:"revision" bson:"revision"`
+	Device   string    `json:"device,omitempty" bson:"device,omitempty"`
+	At       time.Time `json:"at" bson:"at"`
+}
+
+// Store keeps the siblings
+type Store interface {
+	Add(ctx context.Context, s Sibling) error
+	// List returns the subjects siblings, oldest first
+	List(ctx context.Context, subject string) ([]Sibling, error)
+	// Remove drops the siblings with the ids, returning how many there were
+	Remove(ctx context.Context, subject string, ids []string) (int, error)
+	DeleteAll(ctx context.Context, subject string) error
+}
+
+// Tracker adds and resolves siblings
+type Tracker struct {
+	store Store
+	// adding makes the count and insert in Add one step for each subject, adds handled by different
+	// instances of the api can still both see room for one more sibling
+	adding locks.Keyed
+
+	mu  sync.RWMutex
+	max int
+}
+
+// NewTracker creates a tracker over the store
+func NewTracker(store Store, maxSiblings int) *Tracker {
+	return &Tracker{
+		store: store,
+		max:   maxSiblings,
+	}
+}
+
+// SetLimit changes how many siblings a list can have, for a config reload
+func (t *Tracker) SetLimit(maxSiblings int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.max = maxSiblings
+}
+
+// Add keeps a version as a sibling, a version that is already a sibling is returned rather than added again
+// so a device retrying its write doesn't create duplicates
+func (t *Tracker) Add(ctx context.Context, s Sibling) (Sibling, error) {
+	unlock := t.adding.Lock(s.Subject)
+	defer unlock()
+
+	existing, err := t.store.List(ctx, s.Subject)
+	if err != nil {
+		return s, err
+	}
+	for _, e := range existing {
+		if e.Revision == s.Revision {
+			return e, nil
+		}
+	}
+
+	t.mu.RLock()
+	maxSiblings := t.max
+	t.mu.RUnlock()
+	if len(existing) >= maxSiblings {
+		return s, ErrTooMany
+	}
+
+	s.ID = rand.Text()
+	if s.At.IsZero() {
+		s.At = time.Now().UTC()
+	}
+	if err := t.store.Add(ctx, s); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// List returns the siblings of the subjects list
+func (t *Tracker) List(ctx context.Context, subject string) ([]Sibling, error) {
+	return t.store.List(ctx, subject)
+}
+
+// Check makes sure every id is a sibling the subject still has
+func (t *Tracker) Check(ctx context.Context, subject string, ids []string) error {
+	existing, err := t.store.List(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, e := range existing {
+		known[e.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return ErrUnknownSibling
+		}
+	}
+
+	return nil
+}
+
+// Resolve drops siblings that have been merged into the list
+func (t *Tracker) Resolve(ctx context.Context, subject string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := t.store.Remove(ctx, subject, ids)
+	return err
+}
+
+// Forget drops every sibling the subject has, for when their account is deleted
+func (t *Tracker) Forget(ctx context.Context, subject string) error {
+	return t.store.DeleteAll(ctx, subject)
+}
@@ -0,0 +1,66 @@
+package conflicts
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(NewMemoryStore(), 2)
+	ctx := context.Background()
+
+	first, err := tr.Add(ctx, Sibling{Subject: "alice", Data: "a", IV: "iv", Base: `"1"`, Revision: `"a"`})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first.ID)
+	assert.False(t, first.At.IsZero())
+
+	retry, err := tr.Add(ctx, Sibling{Subject: "alice", Data: "a", IV: "iv", Base: `"1"`, Revision: `"a"`})
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, retry.ID, "a retried write isn't kept twice")
+
+	second, err := tr.Add(ctx, Sibling{Subject: "alice", Data: "b", IV: "iv", Base: `"1"`, Revision: `"b"`})
+	assert.NoError(t, err)
+	_, err = tr.Add(ctx, Sibling{Subject: "alice", Data: "c", IV: "iv", Base: `"1"`, Revision: `"c"`})
+	assert.ErrorIs(t, err, ErrTooMany)
+
+	assert.NoError(t, tr.Check(ctx, "alice", []string{first.ID, second.ID}))
+	assert.ErrorIs(t, tr.Check(ctx, "bob", []string{first.ID}), ErrUnknownSibling)
+
+	assert.NoError(t, tr.Resolve(ctx, "alice", []string{first.ID}))
+	left, err := tr.List(ctx, "alice")
+	assert.NoError(t, err)
+	if assert.Len(t, left, 1) {
+		assert.Equal(t, second.ID, left[0].ID)
+	}
+	assert.ErrorIs(t, tr.Check(ctx, "alice", []string{first.ID}), ErrUnknownSibling)
+
+	tr.SetLimit(1)
+	_, err = tr.Add(ctx, Sibling{Subject: "alice", Data: "c", IV: "iv", Base: `"1"`, Revision: `"c"`})
+	assert.ErrorIs(t, err, ErrTooMany)
+
+	assert.NoError(t, tr.Forget(ctx, "alice"))
+	left, err = tr.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, left)
+}
+
+func TestTrackerConcurrentAdds(t *testing.T) {
+	tr := NewTracker(NewMemoryStore(), 2)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			_, _ = tr.Add(ctx, Sibling{Subject: "alice", Data: "a", IV: "iv", Base: `"1"`, Revision: strconv.Itoa(i)})
+		})
+	}
+	wg.Wait()
+
+	siblings, err := tr.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, siblings, 2, "the limit holds when devices conflict at the same time")
+}
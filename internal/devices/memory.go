@@ -0,0 +1,89 @@
+package devices
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps devices in memory, for development and tests
+type MemoryStore struct {
+	mu      sync.Mutex
+	devices map[string][]Device
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		devices: make(map[string][]Device),
+	}
+}
+
+// Insert implements Store
+func (m *MemoryStore) Insert(_ context.Context, d Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.devices[d.Subject] = append(m.devices[d.Subject], d)
+	return nil
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(_ context.Context, subject, id string) (Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.devices[subject] {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+
+	return Device{}, ErrNotFound
+}
+
+// List implements Store
+func (m *MemoryStore) List(_ context.Context, subject string) ([]Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Device(nil), m.devices[subject]...), nil
+}
+
+// Touch implements Store
+func (m *MemoryStore) Touch(_ context.Context, subject, id string, at time.Time) error {
+	return m.update(subject, id, func(d *Device) {
+		d.LastSeen = at
+	})
+}
+
+// Revoke implements Store
+func (m *MemoryStore) Revoke(_ context.Context, subject, id string, at time.Time) error {
+	return m.update(subject, id, func(d *Device) {
+		d.RevokedAt = &at
+		d.PushToken = ""
+	})
+}
+
+// DeleteAll implements Store
+func (m *MemoryStore) DeleteAll(_ context.Context, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.devices, subject)
+	return nil
+}
+
+func (m *MemoryStore) update(subject, id string, change func(*Device)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.devices[subject] {
+		if m.devices[subject][i].ID == id {
+			change(&m.devices[subject][i])
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
@@ -0,0 +1,103 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps devices in a collection
+type MongoStore struct {
+	devices *mongo.Collection
+}
+
+// NewMongoStore makes sure the index exists
+func NewMongoStore(ctx context.Context, db *mongo.Database, collection string) (*MongoStore, error) {
+	m := &MongoStore{
+		devices: db.Collection(collection),
+	}
+
+	if _, err := m.devices.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "subject", Value: 1}, {Key: "created_at", Value: 1}},
+	}); err != nil {
+		return nil, fmt.Errorf("create subject index: %v", err)
+	}
+
+	return m, nil
+}
+
+// Insert implements Store
+func (m *MongoStore) Insert(ctx context.Context, d Device) error {
+	if _, err := m.devices.InsertOne(ctx, d); err != nil {
+		return fmt.Errorf("insert device: %v", err)
+	}
+
+	return nil
+}
+
+// Get implements Store
+func (m *MongoStore) Get(ctx context.Context, subject, id string) (Device, error) {
+	d := Device{}
+	if err := m.devices.FindOne(ctx, bson.M{"_id": id, "subject": subject}).Decode(&d); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return d, ErrNotFound
+		}
+		return d, fmt.Errorf("get device: %v", err)
+	}
+
+	return d, nil
+}
+
+// List implements Store
+func (m *MongoStore) List(ctx context.Context, subject string) ([]Device, error) {
+	cur, err := m.devices.Find(ctx, bson.M{"subject": subject}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find devices: %v", err)
+	}
+
+	var devices []Device
+	if err := cur.All(ctx, &devices); err != nil {
+		return nil, fmt.Errorf("read devices: %v", err)
+	}
+
+	return devices, nil
+}
+
+// Touch implements Store
+func (m *MongoStore) Touch(ctx context.Context, subject, id string, at time.Time) error {
+	return m.update(ctx, subject, id, bson.M{"$set": bson.M{"last_seen": at}})
+}
+
+// Revoke implements Store
+func (m *MongoStore) Revoke(ctx context.Context, subject, id string, at time.Time) error {
+	return m.update(ctx, subject, id, bson.M{
+		"$set":   bson.M{"revoked_at": at},
+		"$unset": bson.M{"push_token": ""},
+	})
+}
+
+// DeleteAll implements Store
+func (m *MongoStore) DeleteAll(ctx context.Context, subject string) error {
+	if _, err := m.devices.DeleteMany(ctx, bson.M{"subject": subject}); err != nil {
+		return fmt.Errorf("delete devices: %v", err)
+	}
+
+	return nil
+}
+
+func (m *MongoStore) update(ctx context.Context, subject, id string, update bson.M) error {
+	res, err := m.devices.UpdateOne(ctx, bson.M{"_id": id, "subject": subject}, update)
+	if err != nil {
+		return fmt.Errorf("update device: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package devices keeps the devices each user syncs from, so they can be listed and a lost one revoked
+package devices
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Platforms a device can be registered as
+var Platforms = map[string]bool{
+	"android": true,
+	"ios":     true,
+	"linux":   true,
+	"macos":   true,
+	"web":     true,
+	"windows": true,
+}
+
+// Limits on what a device can be registered with
+const (
+	maxName      = 64
+	maxPublicKey = 2048
+	maxPushToken = 4096
+)
+
+var (
+	// ErrNotFound is returned for a device the user doesn't have
+	ErrNotFound = errors.New("device not found")
+	// ErrRevoked is returned for a device that has been revoked
+	ErrRevoked = errors.New("device has been revoked")
+	// ErrRequired is returned for a sync request without a device id from a user who has registered devices
+	ErrRequired = errors.New("a registered device id is required")
+	// ErrTooMany is returned when the user already has as many devices as they are allowed
+	ErrTooMany = errors.New("too many devices, revoke one first")
+	// ErrInvalid is wrapped by every problem with a device being registered
+	ErrInvalid = errors.New("invalid device")
+)
+
+// Device is one of a users devices, the push token is never sent back to clients
+type Device struct {
+	ID        string     `json:"id" bson:"_id"`
+	Subject   string     `json:"-" bson:"subject"`
+	Name      string     `json:"name" bson:"name"`
+	Platform  string     `json:"platform" bson:"platform"`
+	PublicKey string     `json:"public_key,omitempty" bson:"public_key,omitempty"`
+	PushToken string     `json:"-" bson:"push_token,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	LastSeen  time.Time  `json:"last_seen" bson:"last_seen"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the device has been revoked
+func (d Device) Revoked() bool {
+	return d.RevokedAt != nil
+}
+
+// Validate checks what the client sent when registering, the public key has to be base64
+func (d Device) Validate() error {
+	var errs []error
+	name := strings.TrimSpace(d.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxName {
+		errs = append(errs, fmt.Errorf("%w: name has to be between 1 and %d characters", ErrInvalid, maxName))
+	}
+	if !Platforms[d.Platform] {
+		errs = append(errs, fmt.Errorf("%w: unknown platform %q", ErrInvalid, d.Platform))
+	}
+	if d.PublicKey != "" {
+		_, std := base64.StdEncoding.DecodeString(d.PublicKey)
+		_, url := base64.RawURLEncoding.DecodeString(d.PublicKey)
+		if len(d.PublicKey) > maxPublicKey || (std != nil && url != nil) {
+			errs = append(errs, fmt.Errorf("%w: public_key has to be base64 and at most %d characters", ErrInvalid, maxPublicKey))
+		}
+	}
+	if len(d.PushToken) > maxPushToken {
+		errs = append(errs, fmt.Errorf("%w: push_token can be at most %d characters", ErrInvalid, maxPushToken))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Store keeps the devices, every lookup is scoped to the subject so ids can't be used across users
+type Store interface {
+	Insert(ctx context.Context, d Device) error
+	// Get returns ErrNotFound when the subject has no such device
+	Get(ctx context.Context, subject, id string) (Device, error)
+	// List returns the subjects devices, oldest first
+	List(ctx context.Context, subject string) ([]Device, error)
+	Touch(ctx context.Context, subject, id string, at time.Time) error
+	// Revoke marks the device revoked and drops its push token, ErrNotFound when there is no such device
+	Revoke(ctx context.Context, subject, id string, at time.Time) error
+	DeleteAll(ctx context.Context, subject string) error
+}
+
+// Registry registers and revokes devices and keeps track of when they were last seen
+type Registry struct {
+	store  Store
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	max      int
+	interval time.Duration
+}
+
+// NewRegistry creates a registry over the store
+func NewRegistry(store Store, maxPerUser int, lastSeenInterval time.Duration, logger *slog.Logger) *Registry {
+	return &Registry{
+		store:    store,
+		logger:   logger,
+		max:      maxPerUser,
+		interval: lastSeenInterval,
+	}
+}
+
+// SetLimits changes the per user limit and how often last seen is written, for a config reload
+func (r *Registry) SetLimits(maxPerUser int, lastSeenInterval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.max = maxPerUser
+	r.interval = lastSeenInterval
+}
+
+func (r *Registry) limits() (int, time.Duration) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.max, r.interval
+}
+
+// Register adds a device for the subject, giving it a new id
+func (r *Registry) Register(ctx context.Context, subject string, d Device) (Device, error) {
+	if err := d.Validate(); err != nil {
+		return d, err
+	}
+
+	maxPerUser, _ := r.limits()
+	existing, err := r.store.List(ctx, subject)
+	if err != nil {
+		return d, err
+	}
+	active := 0
+	for _, e := range existing {
+		if !e.Revoked() {
+			active++
+		}
+	}
+	if active >= maxPerUser {
+		return d, ErrTooMany
+	}
+
+	now := time.Now().UTC()
+	d.ID = rand.Text()
+	d.Subject = subject
+	d.Name = strings.TrimSpace(d.Name)
+	d.CreatedAt = now
+	d.LastSeen = now
+	d.RevokedAt = nil
+	if err := r.store.Insert(ctx, d); err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+// List returns every device the subject has registered, including revoked ones
+func (r *Registry) List(ctx context.Context, subject string) ([]Device, error) {
+	return r.store.List(ctx, subject)
+}
+
+// Seen checks a device a request came from is still allowed, and updates its last seen once it is stale
+func (r *Registry) Seen(ctx context.Context, subject, id string) (Device, error) {
+	d, err := r.store.Get(ctx, subject, id)
+	if err != nil {
+		return d, err
+	}
+	if d.Revoked() {
+		return d, ErrRevoked
+	}
+
+	_, interval := r.limits()
+	now := time.Now().UTC()
+	if now.Sub(d.LastSeen) >= interval {
+		// last seen is only informational, a failed write shouldn't fail the request
+		if err := r.store.Touch(ctx, subject, id, now); err != nil {
+			r.logger.Warn("touch device", "error", err)
+		} else {
+			d.LastSeen = now
+		}
+	}
+
+	return d, nil
+}
+
+// Check is Seen for the sync routes, where a request without a device id is only let through while the
+// subject has never registered a device, so leaving the id off doesn't get a revoked device back in
+func (r *Registry) Check(ctx context.Context, subject, id string) (Device, error) {
+	if id != "" {
+		return r.Seen(ctx, subject, id)
+	}
+
+	existing, err := r.store.List(ctx, subject)
+	if err != nil {
+		return Device{}, err
+	}
+	if len(existing) > 0 {
+		return Device{}, ErrRequired
+	}
+
+	return Device{}, nil
+}
+
+// Revoke stops the device being used, revoking it again keeps the original time
+func (r *Registry) Revoke(ctx context.Context, subject, id string) error {
+	d, err := r.store.Get(ctx, subject, id)
+	if err != nil {
+		return err
+	}
+	if d.Revoked() {
+		return nil
+	}
+
+	return r.store.Revoke(ctx, subject, id, time.Now().UTC())
+}
+
+// Forget drops every device the subject has, for when their account is deleted
+func (r *Registry) Forget(ctx context.Context, subject string) error {
+	return r.store.DeleteAll(ctx, subject)
+}
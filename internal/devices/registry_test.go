@@ -0,0 +1,132 @@
+package devices
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func phone() Device {
+	return Device{Name: " Phone ", Platform: "ios", PublicKey: "cHVibGljLWtleQ==", PushToken: "push"}
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry(NewMemoryStore(), 2, time.Minute, slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	d, err := r.Register(ctx, "alice", phone())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, d.ID)
+	assert.Equal(t, "Phone", d.Name)
+	assert.False(t, d.CreatedAt.IsZero())
+
+	_, err = r.Register(ctx, "alice", Device{Name: "Laptop", Platform: "linux"})
+	assert.NoError(t, err)
+	_, err = r.Register(ctx, "alice", Device{Name: "Tablet", Platform: "android"})
+	assert.ErrorIs(t, err, ErrTooMany)
+
+	// revoked devices don't count towards the limit
+	assert.NoError(t, r.Revoke(ctx, "alice", d.ID))
+	_, err = r.Register(ctx, "alice", Device{Name: "Tablet", Platform: "android"})
+	assert.NoError(t, err)
+
+	list, err := r.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, list, 3)
+	assert.True(t, list[0].Revoked())
+	assert.Empty(t, list[0].PushToken)
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, d := range []Device{
+			{Name: "", Platform: "ios"},
+			{Name: "Phone", Platform: "nokia"},
+			{Name: "Phone", Platform: "ios", PublicKey: "not base64!"},
+		} {
+			_, err := r.Register(ctx, "bob", d)
+			assert.ErrorIs(t, err, ErrInvalid)
+		}
+	})
+}
+
+func TestSeen(t *testing.T) {
+	store := NewMemoryStore()
+	r := NewRegistry(store, 5, time.Hour, slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	d, err := r.Register(ctx, "alice", phone())
+	assert.NoError(t, err)
+
+	_, err = r.Seen(ctx, "bob", d.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "ids are scoped to the user")
+
+	// a recent last seen isn't written again
+	stale := time.Now().Add(-30 * time.Minute).UTC()
+	assert.NoError(t, store.Touch(ctx, "alice", d.ID, stale))
+	seen, err := r.Seen(ctx, "alice", d.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, stale, seen.LastSeen)
+
+	r.SetLimits(5, time.Minute)
+	seen, err = r.Seen(ctx, "alice", d.ID)
+	assert.NoError(t, err)
+	assert.True(t, seen.LastSeen.After(stale))
+
+	assert.NoError(t, r.Revoke(ctx, "alice", d.ID))
+	_, err = r.Seen(ctx, "alice", d.ID)
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	assert.ErrorIs(t, r.Revoke(ctx, "bob", d.ID), ErrNotFound)
+
+	assert.NoError(t, r.Forget(ctx, "alice"))
+	_, err = r.Seen(ctx, "alice", d.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// untouchable is a store whose last seen writes fail
+type untouchable struct {
+	Store
+}
+
+func (untouchable) Touch(context.Context, string, string, time.Time) error {
+	return errors.New("store is read only")
+}
+
+func TestSeenTouchFails(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRegistry(untouchable{Store: NewMemoryStore()}, 5, 0, slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx := context.Background()
+
+	d, err := r.Register(ctx, "alice", phone())
+	assert.NoError(t, err)
+
+	_, err = r.Seen(ctx, "alice", d.ID)
+	assert.NoError(t, err, "last seen is only informational")
+	assert.Contains(t, buf.String(), `"msg":"touch device"`)
+	assert.Contains(t, buf.String(), "store is read only")
+}
+
+func TestCheck(t *testing.T) {
+	r := NewRegistry(NewMemoryStore(), 5, time.Hour, slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	_, err := r.Check(ctx, "alice", "")
+	assert.NoError(t, err, "a user without devices doesn't need to send one")
+
+	d, err := r.Register(ctx, "alice", phone())
+	assert.NoError(t, err)
+	_, err = r.Check(ctx, "alice", d.ID)
+	assert.NoError(t, err)
+	_, err = r.Check(ctx, "alice", "")
+	assert.ErrorIs(t, err, ErrRequired)
+
+	assert.NoError(t, r.Revoke(ctx, "alice", d.ID))
+	_, err = r.Check(ctx, "alice", d.ID)
+	assert.ErrorIs(t, err, ErrRevoked)
+	_, err = r.Check(ctx, "alice", "")
+	assert.ErrorIs(t, err, ErrRequired, "revoked devices still count")
+}
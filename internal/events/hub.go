@@ -0,0 +1,195 @@
+// Package events fans list changes out to the streams a user has open
+package events
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event types sent to the streams
+const (
+	ListUpdated  = "list.updated"
+	ListDeleted  = "list.deleted"
+	ListConflict = "list.conflict"
+	// InviteAccepted tells the owner of a shared list to wrap its key for a new member
+	InviteAccepted = "invite.accepted"
+	// InboxItem tells the user something was dropped in their inbox
+	InboxItem = "inbox.item"
+)
+
+// ErrTooManyStreams is returned when a subject already has as many streams open as it is allowed
+var ErrTooManyStreams = errors.New("too many event streams open")
+
+// Event is a change to a users list, IDs only increase and are unique to this process
+type Event struct {
+	ID   uint64    `json:"-"`
+	Type string    `json:"-"`
+	ETag string    `json:"etag,omitempty"`
+	At   time.Time `json:"at"`
+}
+
+// Hub keeps the streams open for each subject and the last few events sent to them, so a stream
+// that reconnects with the last id it saw can be sent what it missed, events are only kept for the
+// backlog TTL so subjects that have gone quiet don't stay in memory
+type Hub struct {
+	MaxPerSubject int
+	Backlog       int
+	BacklogTTL    time.Duration
+
+	mu      sync.Mutex
+	nextID  uint64
+	subs    map[string]map[*Subscription]struct{}
+	history map[string][]Event
+	swept   time.Time
+}
+
+// Subscription is a single open stream, C is closed when the stream falls too far behind or is closed
+type Subscription struct {
+	C <-chan Event
+
+	c       chan Event
+	hub     *Hub
+	subject string
+	once    sync.Once
+}
+
+// subscriptionBuffer is how many events a stream can fall behind before it is dropped
+const subscriptionBuffer = 16
+
+// NewHub creates a hub allowing maxPerSubject streams per subject and keeping up to backlog events for
+// each, for up to backlogTTL
+func NewHub(maxPerSubject, backlog int, backlogTTL time.Duration) *Hub {
+	return &Hub{
+		MaxPerSubject: maxPerSubject,
+		Backlog:       backlog,
+		BacklogTTL:    backlogTTL,
+		subs:          make(map[string]map[*Subscription]struct{}),
+		history:       make(map[string][]Event),
+	}
+}
+
+// SetLimits changes the limits, streams already open over the new limit are left open
+func (h *Hub) SetLimits(maxPerSubject, backlog int, backlogTTL time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.MaxPerSubject = maxPerSubject
+	h.Backlog = backlog
+	h.BacklogTTL = backlogTTL
+}
+
+// Subscribe opens a stream for subject, returning the kept events after lastID so they can be sent first
+func (h *Hub) Subscribe(subject string, lastID uint64) (*Subscription, []Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.MaxPerSubject > 0 && len(h.subs[subject]) >= h.MaxPerSubject {
+		return nil, nil, ErrTooManyStreams
+	}
+
+	c := make(chan Event, subscriptionBuffer)
+	s := &Subscription{
+		C:       c,
+		c:       c,
+		hub:     h,
+		subject: subject,
+	}
+	if h.subs[subject] == nil {
+		h.subs[subject] = make(map[*Subscription]struct{})
+	}
+	h.subs[subject][s] = struct{}{}
+
+	h.sweepLocked(time.Now())
+	var missed []Event
+	if lastID > 0 {
+		for _, e := range h.history[subject] {
+			if e.ID > lastID {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	return s, missed, nil
+}
+
+// Publish sends an event to every stream the subject has open, a stream that can't keep up is closed
+// and has to reconnect to catch up
+func (h *Hub) Publish(subject, eventType, etag string) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now().UTC()
+	h.sweepLocked(now)
+	h.nextID++
+	e := Event{
+		ID:   h.nextID,
+		Type: eventType,
+		ETag: etag,
+		At:   now,
+	}
+
+	history := append(h.history[subject], e)
+	if len(history) > h.Backlog {
+		history = history[len(history)-h.Backlog:]
+	}
+	h.history[subject] = history
+
+	for s := range h.subs[subject] {
+		select {
+		case s.c <- e:
+		default:
+			h.removeLocked(s)
+		}
+	}
+
+	return e
+}
+
+// sweepLocked drops the events older than the backlog TTL, and the subjects left with none, at most
+// once per TTL so publishing doesn't walk every subject each time
+func (h *Hub) sweepLocked(now time.Time) {
+	if now.Sub(h.swept) < h.BacklogTTL {
+		return
+	}
+	h.swept = now
+
+	cutoff := now.Add(-h.BacklogTTL)
+	for subject, history := range h.history {
+		kept := slices.DeleteFunc(history, func(e Event) bool {
+			return e.At.Before(cutoff)
+		})
+		if len(kept) == 0 {
+			delete(h.history, subject)
+			continue
+		}
+		h.history[subject] = kept
+	}
+}
+
+// Streams returns how many streams the subject has open
+func (h *Hub) Streams(subject string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subs[subject])
+}
+
+// Close removes the stream from the hub
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.removeLocked(s)
+}
+
+func (h *Hub) removeLocked(s *Subscription) {
+	s.once.Do(func() {
+		delete(h.subs[s.subject], s)
+		if len(h.subs[s.subject]) == 0 {
+			delete(h.subs, s.subject)
+		}
+		close(s.c)
+	})
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	h := NewHub(2, 3, time.Minute)
+
+	a, missed, err := h.Subscribe("alice", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, missed)
+	b, _, err := h.Subscribe("bob", 0)
+	assert.NoError(t, err)
+
+	e := h.Publish("alice", ListUpdated, `"abc"`)
+	assert.Equal(t, e, <-a.C)
+	select {
+	case <-b.C:
+		t.Fatal("bob got alices event")
+	default:
+	}
+
+	_, _, err = h.Subscribe("alice", 0)
+	assert.NoError(t, err)
+	_, _, err = h.Subscribe("alice", 0)
+	assert.ErrorIs(t, err, ErrTooManyStreams)
+
+	a.Close()
+	a.Close()
+	assert.Equal(t, 1, h.Streams("alice"))
+	_, ok := <-a.C
+	assert.False(t, ok)
+}
+
+func TestHubResume(t *testing.T) {
+	h := NewHub(0, 3, time.Minute)
+
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, h.Publish("alice", ListUpdated, "").ID)
+	}
+
+	_, missed, err := h.Subscribe("alice", ids[2])
+	assert.NoError(t, err)
+	assert.Len(t, missed, 2)
+	assert.Equal(t, ids[3], missed[0].ID)
+	assert.Equal(t, ids[4], missed[1].ID)
+
+	_, missed, err = h.Subscribe("alice", ids[4])
+	assert.NoError(t, err)
+	assert.Empty(t, missed)
+}
+
+func TestHubSlowStream(t *testing.T) {
+	h := NewHub(0, 1, time.Minute)
+	s, _, err := h.Subscribe("alice", 0)
+	assert.NoError(t, err)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		h.Publish("alice", ListUpdated, "")
+	}
+	assert.Equal(t, 0, h.Streams("alice"))
+
+	n := 0
+	for range s.C {
+		n++
+	}
+	assert.Equal(t, subscriptionBuffer, n)
+}
+
+func TestHubBacklogTTL(t *testing.T) {
+	h := NewHub(0, 3, 20*time.Millisecond)
+
+	first := h.Publish("alice", ListUpdated, "")
+	h.Publish("bob", ListUpdated, "")
+	time.Sleep(30 * time.Millisecond)
+	h.Publish("alice", ListUpdated, "")
+
+	h.mu.Lock()
+	_, kept := h.history["bob"]
+	aliceEvents := len(h.history["alice"])
+	h.mu.Unlock()
+	assert.False(t, kept, "a subject with only expired events is dropped")
+	assert.Equal(t, 1, aliceEvents)
+
+	_, missed, err := h.Subscribe("alice", first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, missed, 1, "expired events aren't resent")
+}
@@ -0,0 +1,251 @@
+// Package health provides the liveness and readiness endpoints
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Statuses reported for the service and each dependency
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusReady    = "ready"
+	StatusNotReady = "not-ready"
+	StatusShutdown = "shutting-down"
+)
+
+// Dialer returns the connection for a downstream service by name, api.Connections satisfies it
+type Dialer interface {
+	Dial(name string) (*grpc.ClientConn, error)
+}
+
+// BreakerSource returns the circuit breaker for a dependency, api.Connections satisfies it
+type BreakerSource interface {
+	Breaker(name string) *breaker.Breaker
+}
+
+// Dependency is a downstream service the api needs to be able to reach
+type Dependency struct {
+	Name    string
+	Address string
+}
+
+// Result is the outcome of checking a single dependency
+type Result struct {
+	Status    string    `json:"status"`
+	Address   string    `json:"address"`
+	Breaker   string    `json:"breaker,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the body returned by /readyz
+type Report struct {
+	Status       string            `json:"status"`
+	Dependencies map[string]Result `json:"dependencies"`
+}
+
+// Readiness checks the downstream services, results are cached so probes don't hammer them
+type Readiness struct {
+	Dialer       Dialer
+	Breakers     BreakerSource
+	Dependencies []Dependency
+	Timeout      time.Duration
+	CacheTTL     time.Duration
+
+	mu           sync.Mutex
+	cache        map[string]Result
+	shuttingDown atomic.Bool
+}
+
+// NewReadiness creates the readiness checker for every service in the config
+func NewReadiness(cfg *config.Config, d Dialer) *Readiness {
+	bs, _ := d.(BreakerSource)
+
+	return &Readiness{
+		Dialer:   d,
+		Breakers: bs,
+		Dependencies: []Dependency{
+			{Name: "identity", Address: cfg.Services.Identity},
+			{Name: "todo", Address: cfg.Services.Todo},
+			{Name: "user", Address: cfg.Services.User},
+		},
+		Timeout:  cfg.Health.CheckTimeout,
+		CacheTTL: cfg.Health.CacheTTL,
+		cache:    make(map[string]Result),
+	}
+}
+
+// Apply points the checks at the services in a new config, cached results are dropped
+func (r *Readiness) Apply(cfg *config.Config) error {
+	next := NewReadiness(cfg, r.Dialer)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Dependencies = next.Dependencies
+	r.Timeout = next.Timeout
+	r.CacheTTL = next.CacheTTL
+	r.cache = next.cache
+
+	return nil
+}
+
+// ShuttingDown flips the service to not-ready so traffic drains before the server stops
+func (r *Readiness) ShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check returns the state of every dependency
+func (r *Readiness) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	deps := r.Dependencies
+	r.mu.Unlock()
+
+	report := Report{
+		Status:       StatusReady,
+		Dependencies: make(map[string]Result, len(deps)),
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, dep := range deps {
+		wg.Add(1)
+		go func(dep Dependency) {
+			defer wg.Done()
+			res := r.check(ctx, dep)
+
+			mu.Lock()
+			report.Dependencies[dep.Name] = res
+			mu.Unlock()
+		}(dep)
+	}
+	wg.Wait()
+
+	for _, res := range report.Dependencies {
+		if res.Status != StatusOK {
+			report.Status = StatusNotReady
+		}
+	}
+	if r.shuttingDown.Load() {
+		report.Status = StatusShutdown
+	}
+
+	return report
+}
+
+func (r *Readiness) check(ctx context.Context, dep Dependency) Result {
+	var b *breaker.Breaker
+	if r.Breakers != nil {
+		b = r.Breakers.Breaker(dep.Name)
+	}
+	if b != nil && b.State() == breaker.Open {
+		return Result{
+			Status:    StatusFailing,
+			Address:   dep.Address,
+			Breaker:   breaker.Open.String(),
+			Error:     breaker.ErrOpen.Error(),
+			CheckedAt: time.Now(),
+		}
+	}
+
+	r.mu.Lock()
+	cached, ok := r.cache[dep.Name]
+	ttl, timeout := r.CacheTTL, r.Timeout
+	r.mu.Unlock()
+	if ok && time.Since(cached.CheckedAt) < ttl {
+		return cached
+	}
+
+	res := Result{
+		Status:    StatusOK,
+		Address:   dep.Address,
+		CheckedAt: time.Now(),
+	}
+	if err := r.probe(ctx, dep.Name, timeout); err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+	}
+	if b != nil {
+		res.Breaker = b.State().String()
+	}
+
+	r.mu.Lock()
+	r.cache[dep.Name] = res
+	r.mu.Unlock()
+
+	return res
+}
+
+// probe uses the grpc health protocol, falling back to the connection state when the service doesn't implement it
+func (r *Readiness) probe(ctx context.Context, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := r.Dialer.Dial(name)
+	if err != nil {
+		return err
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err == nil {
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return status.Errorf(codes.Unavailable, "health status %s", resp.GetStatus())
+		}
+		return nil
+	}
+	if status.Code(err) != codes.Unimplemented {
+		return err
+	}
+
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if state == connectivity.Idle {
+			conn.Connect()
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return status.Errorf(codes.Unavailable, "connection %s", state)
+		}
+	}
+}
+
+// HTTP is the /readyz handler
+func (r *Readiness) HTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != StatusReady {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// Live is the /livez handler, it only reports that the process is serving requests
+func Live(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(struct {
+		Status string `json:"status"`
+	}{
+		Status: StatusOK,
+	})
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func startServer(t *testing.T) (string, *grpchealth.Server) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	hs := grpchealth.NewServer()
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String(), hs
+}
+
+func TestReadiness(t *testing.T) {
+	address, hs := startServer(t)
+
+	cfg := &config.Config{}
+	cfg.Services.Identity = address
+	cfg.Services.Todo = address
+	cfg.Services.User = address
+	cfg.Health.CheckTimeout = time.Second
+
+	conns := api.NewConnections(*cfg, slog.New(slog.DiscardHandler))
+	defer func() {
+		_ = conns.Close()
+	}()
+
+	t.Run("ready", func(t *testing.T) {
+		r := NewReadiness(cfg, conns)
+		w := httptest.NewRecorder()
+		r.HTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		report := Report{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, StatusReady, report.Status)
+		assert.Equal(t, StatusOK, report.Dependencies["todo"].Status)
+	})
+
+	t.Run("dependency failing", func(t *testing.T) {
+		hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		defer hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+		r := NewReadiness(cfg, conns)
+		w := httptest.NewRecorder()
+		r.HTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		report := Report{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, StatusNotReady, report.Status)
+		assert.Equal(t, StatusFailing, report.Dependencies["user"].Status)
+		assert.NotEmpty(t, report.Dependencies["user"].Error)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		r := NewReadiness(cfg, conns)
+		r.CacheTTL = time.Minute
+		assert.Equal(t, StatusReady, r.Check(context.Background()).Status)
+
+		hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		defer hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		assert.Equal(t, StatusReady, r.Check(context.Background()).Status)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		r := NewReadiness(cfg, conns)
+		r.ShuttingDown()
+		w := httptest.NewRecorder()
+		r.HTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestLive(t *testing.T) {
+	w := httptest.NewRecorder()
+	Live(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,171 @@
+// Package inbox lets anyone with a users inbox address drop items into it without logging in. Items are
+// sealed to the users published key by the sender, so the server only ever holds ciphertext, and the
+// users client takes them out, merges them into its list and acknowledges them
+package inbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for an address nobody has
+	ErrNotFound = errors.New("inbox not found")
+	// ErrFull is returned when the inbox already holds as many items as it can
+	ErrFull = errors.New("inbox is full")
+	// ErrTooLarge is returned for an item bigger than the size cap
+	ErrTooLarge = errors.New("item is too large")
+	// ErrInvalid is wrapped by every problem with an item
+	ErrInvalid = errors.New("invalid item")
+)
+
+// Item is a sealed item waiting to be taken out, KeyID is the published key it was sealed to
+type Item struct {
+	ID         string    `json:"id" bson:"_id"`
+	Subject    string    `json:"-" bson:"subject"`
+	KeyID      string    `json:"key_id" bson:"key_id"`
+	Sealed     string    `json:"sealed" bson:"sealed"`
+	ReceivedAt time.Time `json:"received_at" bson:"received_at"`
+}
+
+// Store keeps the addresses and the items
+type Store interface {
+	// Address returns ErrNotFound when the subject has no address yet
+	Address(ctx context.Context, subject string) (string, error)
+	// SetAddress gives the subject a new address, the old one stops working
+	SetAddress(ctx context.Context, subject, address string) error
+	// Owner returns ErrNotFound when nobody has the address
+	Owner(ctx context.Context, address string) (string, error)
+	Add(ctx context.Context, i Item) error
+	Count(ctx context.Context, subject string) (int, error)
+	// Items returns the subjects items, oldest first
+	Items(ctx context.Context, subject string) ([]Item, error)
+	// Remove deletes the subjects items with the ids, returning how many there were
+	Remove(ctx context.Context, subject string, ids []string) (int, error)
+	// DeleteAll deletes the subjects address and items
+	DeleteAll(ctx context.Context, subject string) error
+}
+
+// Inbox hands out addresses and takes in items
+type Inbox struct {
+	store Store
+
+	mu       sync.RWMutex
+	maxItems int
+	maxBytes int
+}
+
+// NewInbox creates the inbox over the store, an inbox holds up to maxItems of up to maxBytes each
+func NewInbox(store Store, maxItems, maxBytes int) *Inbox {
+	return &Inbox{
+		store:    store,
+		maxItems: maxItems,
+		maxBytes: maxBytes,
+	}
+}
+
+// SetLimits changes the caps, for a config reload
+func (b *Inbox) SetLimits(maxItems, maxBytes int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maxItems = maxItems
+	b.maxBytes = maxBytes
+}
+
+// MaxBytes is the largest sealed item the inbox takes
+func (b *Inbox) MaxBytes() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.maxBytes
+}
+
+// Address returns the subjects address, giving them one the first time
+func (b *Inbox) Address(ctx context.Context, subject string) (string, error) {
+	address, err := b.store.Address(ctx, subject)
+	if errors.Is(err, ErrNotFound) {
+		return b.Rotate(ctx, subject)
+	}
+
+	return address, err
+}
+
+// Rotate gives the subject a new address, for when the old one is getting unwanted items
+func (b *Inbox) Rotate(ctx context.Context, subject string) (string, error) {
+	address := strings.ToLower(rand.Text())
+	if err := b.store.SetAddress(ctx, subject, address); err != nil {
+		return "", err
+	}
+
+	return address, nil
+}
+
+// Owner returns who the address belongs to
+func (b *Inbox) Owner(ctx context.Context, address string) (string, error) {
+	return b.store.Owner(ctx, address)
+}
+
+// Deliver adds a sealed item to the subjects inbox
+func (b *Inbox) Deliver(ctx context.Context, subject, keyID, sealed string) (Item, error) {
+	b.mu.RLock()
+	maxItems, maxBytes := b.maxItems, b.maxBytes
+	b.mu.RUnlock()
+
+	if len(sealed) > maxBytes {
+		return Item{}, ErrTooLarge
+	}
+	if _, err := base64.StdEncoding.DecodeString(sealed); err != nil || sealed == "" {
+		return Item{}, fmt.Errorf("%w: sealed has to be base64", ErrInvalid)
+	}
+	if keyID == "" {
+		return Item{}, fmt.Errorf("%w: key_id is required", ErrInvalid)
+	}
+
+	count, err := b.store.Count(ctx, subject)
+	if err != nil {
+		return Item{}, err
+	}
+	if count >= maxItems {
+		return Item{}, ErrFull
+	}
+
+	i := Item{
+		ID:         rand.Text(),
+		Subject:    subject,
+		KeyID:      keyID,
+		Sealed:     sealed,
+		ReceivedAt: time.Now().UTC(),
+	}
+	if err := b.store.Add(ctx, i); err != nil {
+		return Item{}, err
+	}
+
+	return i, nil
+}
+
+// Items returns the items waiting in the subjects inbox
+func (b *Inbox) Items(ctx context.Context, subject string) ([]Item, error) {
+	return b.store.Items(ctx, subject)
+}
+
+// Ack removes items the subjects client has merged into its list, ids that are already gone are ignored
+// so an ack can be retried
+func (b *Inbox) Ack(ctx context.Context, subject string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("%w: ids are required", ErrInvalid)
+	}
+
+	return b.store.Remove(ctx, subject, ids)
+}
+
+// Forget deletes the subjects address and items, for when their account is deleted
+func (b *Inbox) Forget(ctx context.Context, subject string) error {
+	return b.store.DeleteAll(ctx, subject)
+}
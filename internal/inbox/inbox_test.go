@@ -0,0 +1,58 @@
+package inbox
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInbox(t *testing.T) {
+	b := NewInbox(NewMemoryStore(), 2, 16)
+	ctx := context.Background()
+
+	address, err := b.Address(ctx, "alice")
+	assert.NoError(t, err)
+	again, err := b.Address(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, address, again, "the address stays until it is rotated")
+	owner, err := b.Owner(ctx, address)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", owner)
+
+	_, err = b.Deliver(ctx, "alice", "k1", "not base64!")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = b.Deliver(ctx, "alice", "k1", strings.Repeat("QUFB", 5))
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	first, err := b.Deliver(ctx, "alice", "k1", "c2VhbGVk")
+	assert.NoError(t, err)
+	_, err = b.Deliver(ctx, "alice", "k1", "c2VhbGVk")
+	assert.NoError(t, err)
+	_, err = b.Deliver(ctx, "alice", "k1", "c2VhbGVk")
+	assert.ErrorIs(t, err, ErrFull)
+
+	removed, err := b.Ack(ctx, "bob", []string{first.ID})
+	assert.NoError(t, err)
+	assert.Zero(t, removed, "only the owner can take items out")
+	removed, err = b.Ack(ctx, "alice", []string{first.ID, "gone"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	items, err := b.Items(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	rotated, err := b.Rotate(ctx, "alice")
+	assert.NoError(t, err)
+	assert.NotEqual(t, address, rotated)
+	_, err = b.Owner(ctx, address)
+	assert.ErrorIs(t, err, ErrNotFound, "the old address stops working")
+
+	assert.NoError(t, b.Forget(ctx, "alice"))
+	items, err = b.Items(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	_, err = b.Owner(ctx, rotated)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,106 @@
+package inbox
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps inboxes in memory, for development and tests
+type MemoryStore struct {
+	mu        sync.Mutex
+	addresses map[string]string
+	items     map[string][]Item
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		addresses: make(map[string]string),
+		items:     make(map[string][]Item),
+	}
+}
+
+// Address implements Store
+func (m *MemoryStore) Address(_ context.Context, subject string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	address, ok := m.addresses[subject]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return address, nil
+}
+
+// SetAddress implements Store
+func (m *MemoryStore) SetAddress(_ context.Context, subject, address string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addresses[subject] = address
+	return nil
+}
+
+// Owner implements Store
+func (m *MemoryStore) Owner(_ context.Context, address string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for subject, a := range m.addresses {
+		if a == address {
+			return subject, nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// Add implements Store
+func (m *MemoryStore) Add(_ context.Context, i Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items[i.Subject] = append(m.items[i.Subject], i)
+	return nil
+}
+
+// Count implements Store
+func (m *MemoryStore) Count(_ context.Context, subject string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.items[subject]), nil
+}
+
+// Items implements Store
+func (m *MemoryStore) Items(_ context.Context, subject string) ([]Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Item(nil), m.items[subject]...), nil
+}
+
+// Remove implements Store
+func (m *MemoryStore) Remove(_ context.Context, subject string, ids []string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.items[subject])
+	m.items[subject] = slices.DeleteFunc(m.items[subject], func(i Item) bool {
+		return slices.Contains(ids, i.ID)
+	})
+
+	return before - len(m.items[subject]), nil
+}
+
+// DeleteAll implements Store
+func (m *MemoryStore) DeleteAll(_ context.Context, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.addresses, subject)
+	delete(m.items, subject)
+	return nil
+}
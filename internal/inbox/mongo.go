@@ -0,0 +1,150 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ttlIndex is the index that drops items once they are older than the retention
+const ttlIndex = "received_at_ttl"
+
+// MongoStore keeps addresses and items in two collections, items nobody takes out are dropped after
+// the retention
+type MongoStore struct {
+	addresses *mongo.Collection
+	items     *mongo.Collection
+}
+
+// NewMongoStore makes sure the indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, addresses, items string, retentionSeconds int32) (*MongoStore, error) {
+	m := &MongoStore{
+		addresses: db.Collection(addresses),
+		items:     db.Collection(items),
+	}
+
+	if _, err := m.addresses.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "address", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return nil, fmt.Errorf("create address index: %v", err)
+	}
+	if _, err := m.items.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "subject", Value: 1}, {Key: "received_at", Value: 1}},
+	}); err != nil {
+		return nil, fmt.Errorf("create item index: %v", err)
+	}
+	if _, err := m.items.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "received_at", Value: 1}},
+		Options: options.Index().SetName(ttlIndex).SetExpireAfterSeconds(retentionSeconds),
+	}); err != nil {
+		// the index already exists with the old retention
+		if err := db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: items},
+			{Key: "index", Value: bson.D{{Key: "name", Value: ttlIndex}, {Key: "expireAfterSeconds", Value: retentionSeconds}}},
+		}).Err(); err != nil {
+			return nil, fmt.Errorf("create retention index: %v", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Address implements Store
+func (m *MongoStore) Address(ctx context.Context, subject string) (string, error) {
+	doc := struct {
+		Address string `bson:"address"`
+	}{}
+	if err := m.addresses.FindOne(ctx, bson.M{"_id": subject}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("find address: %v", err)
+	}
+
+	return doc.Address, nil
+}
+
+// SetAddress implements Store
+func (m *MongoStore) SetAddress(ctx context.Context, subject, address string) error {
+	if _, err := m.addresses.ReplaceOne(ctx, bson.M{"_id": subject}, bson.M{"_id": subject, "address": address}, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("set address: %v", err)
+	}
+
+	return nil
+}
+
+// Owner implements Store
+func (m *MongoStore) Owner(ctx context.Context, address string) (string, error) {
+	doc := struct {
+		Subject string `bson:"_id"`
+	}{}
+	if err := m.addresses.FindOne(ctx, bson.M{"address": address}).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("find address owner: %v", err)
+	}
+
+	return doc.Subject, nil
+}
+
+// Add implements Store
+func (m *MongoStore) Add(ctx context.Context, i Item) error {
+	if _, err := m.items.InsertOne(ctx, i); err != nil {
+		return fmt.Errorf("insert item: %v", err)
+	}
+
+	return nil
+}
+
+// Count implements Store
+func (m *MongoStore) Count(ctx context.Context, subject string) (int, error) {
+	n, err := m.items.CountDocuments(ctx, bson.M{"subject": subject})
+	if err != nil {
+		return 0, fmt.Errorf("count items: %v", err)
+	}
+
+	return int(n), nil
+}
+
+// Items implements Store
+func (m *MongoStore) Items(ctx context.Context, subject string) ([]Item, error) {
+	cur, err := m.items.Find(ctx, bson.M{"subject": subject}, options.Find().SetSort(bson.D{{Key: "received_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find items: %v", err)
+	}
+
+	var items []Item
+	if err := cur.All(ctx, &items); err != nil {
+		return nil, fmt.Errorf("read items: %v", err)
+	}
+
+	return items, nil
+}
+
+// Remove implements Store
+func (m *MongoStore) Remove(ctx context.Context, subject string, ids []string) (int, error) {
+	res, err := m.items.DeleteMany(ctx, bson.M{"subject": subject, "_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("remove items: %v", err)
+	}
+
+	return int(res.DeletedCount), nil
+}
+
+// DeleteAll implements Store
+func (m *MongoStore) DeleteAll(ctx context.Context, subject string) error {
+	if _, err := m.items.DeleteMany(ctx, bson.M{"subject": subject}); err != nil {
+		return fmt.Errorf("delete items: %v", err)
+	}
+	if _, err := m.addresses.DeleteOne(ctx, bson.M{"_id": subject}); err != nil {
+		return fmt.Errorf("delete address: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package invites lets the owner of a shared list invite someone by email who may not have an account yet,
+// the invite is a single use token that expires, only its hash is kept
+package invites
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+// Statuses an invite can be in
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusExpired  = "expired"
+)
+
+var (
+	// ErrNotFound is returned for a token or invite that doesn't exist, including one that was revoked
+	ErrNotFound = errors.New("invite not found")
+	// ErrExpired is returned when accepting an invite after it has expired
+	ErrExpired = errors.New("invite has expired")
+	// ErrUsed is returned when accepting an invite that has already been accepted
+	ErrUsed = errors.New("invite has already been used")
+	// ErrTooMany is returned when a list already has as many pending invites as it can
+	ErrTooMany = errors.New("too many pending invites for the list")
+	// ErrInvalid is wrapped by every problem with an invite
+	ErrInvalid = errors.New("invalid invite")
+)
+
+// Invite is an invitation to a shared list, it can be accepted once by whoever has the token
+type Invite struct {
+	ID         string     `json:"id" bson:"_id"`
+	TokenHash  string     `json:"-" bson:"token_hash"`
+	List       string     `json:"list" bson:"list"`
+	Owner      string     `json:"-" bson:"owner"`
+	Email      string     `json:"email" bson:"email"`
+	Access     string     `json:"access" bson:"access"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at" bson:"expires_at"`
+	AcceptedBy string     `json:"accepted_by,omitempty" bson:"accepted_by,omitempty"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	Status     string     `json:"status" bson:"-"`
+}
+
+// status works out whether the invite can still be accepted
+func (i Invite) status(now time.Time) string {
+	switch {
+	case i.AcceptedBy != "":
+		return StatusAccepted
+	case !now.Before(i.ExpiresAt):
+		return StatusExpired
+	}
+
+	return StatusPending
+}
+
+// Store keeps the invites
+type Store interface {
+	Insert(ctx context.Context, i Invite) error
+	// ByToken returns ErrNotFound when no invite has the token hash
+	ByToken(ctx context.Context, hash string) (Invite, error)
+	ForList(ctx context.Context, list string) ([]Invite, error)
+	// Accept marks the invite accepted when it hasn't been already, returning ErrUsed when it has
+	Accept(ctx context.Context, id, subject string, at time.Time) error
+	// Reopen undoes Accept
+	Reopen(ctx context.Context, id string) error
+	// Delete returns ErrNotFound when the list has no such invite
+	Delete(ctx context.Context, list, id string) error
+	DeleteForOwner(ctx context.Context, owner string) error
+}
+
+// Invites creates and accepts invites
+type Invites struct {
+	store Store
+
+	mu         sync.RWMutex
+	ttl        time.Duration
+	maxPending int
+}
+
+// NewInvites creates the invites over the store
+func NewInvites(store Store, ttl time.Duration, maxPending int) *Invites {
+	return &Invites{
+		store:      store,
+		ttl:        ttl,
+		maxPending: maxPending,
+	}
+}
+
+// SetLimits changes how long new invites last and how many a list can have pending, for a config reload
+func (v *Invites) SetLimits(ttl time.Duration, maxPending int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.ttl = ttl
+	v.maxPending = maxPending
+}
+
+// Hash is how a token is kept, the token itself is only ever in the email
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create makes an invite to the list, returning the token to send to the email address
+func (v *Invites) Create(ctx context.Context, list, owner, email, access string) (Invite, string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return Invite{}, "", fmt.Errorf("%w: email has to be a plain email address", ErrInvalid)
+	}
+	if access != sharing.AccessRead && access != sharing.AccessWrite {
+		return Invite{}, "", fmt.Errorf("%w: access has to be %s or %s", ErrInvalid, sharing.AccessRead, sharing.AccessWrite)
+	}
+
+	v.mu.RLock()
+	ttl, maxPending := v.ttl, v.maxPending
+	v.mu.RUnlock()
+
+	existing, err := v.ForList(ctx, list)
+	if err != nil {
+		return Invite{}, "", err
+	}
+	pending := 0
+	for _, i := range existing {
+		if i.Status == StatusPending {
+			pending++
+		}
+	}
+	if pending >= maxPending {
+		return Invite{}, "", ErrTooMany
+	}
+
+	token := rand.Text()
+	now := time.Now().UTC()
+	i := Invite{
+		ID:        rand.Text(),
+		TokenHash: Hash(token),
+		List:      list,
+		Owner:     owner,
+		Email:     email,
+		Access:    access,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := v.store.Insert(ctx, i); err != nil {
+		return Invite{}, "", err
+	}
+	i.Status = StatusPending
+
+	return i, token, nil
+}
+
+// ForList returns every invite to the list that is still kept
+func (v *Invites) ForList(ctx context.Context, list string) ([]Invite, error) {
+	invites, err := v.store.ForList(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range invites {
+		invites[i].Status = invites[i].status(now)
+	}
+
+	return invites, nil
+}
+
+// Revoke deletes the invite so it can't be accepted, access already given by it is left alone
+func (v *Invites) Revoke(ctx context.Context, list, id string) error {
+	return v.store.Delete(ctx, list, id)
+}
+
+// Accept uses up the invite for the subject
+func (v *Invites) Accept(ctx context.Context, token, subject string) (Invite, error) {
+	i, err := v.store.ByToken(ctx, Hash(token))
+	if err != nil {
+		return Invite{}, err
+	}
+
+	now := time.Now().UTC()
+	switch i.status(now) {
+	case StatusAccepted:
+		return Invite{}, ErrUsed
+	case StatusExpired:
+		return Invite{}, ErrExpired
+	}
+	if i.Owner == subject {
+		return Invite{}, fmt.Errorf("%w: the owner can't accept their own invite", ErrInvalid)
+	}
+
+	if err := v.store.Accept(ctx, i.ID, subject, now); err != nil {
+		return Invite{}, err
+	}
+	i.AcceptedBy = subject
+	i.AcceptedAt = &now
+	i.Status = StatusAccepted
+
+	return i, nil
+}
+
+// Reopen lets the invite be accepted again, for when accepting it couldn't be finished
+func (v *Invites) Reopen(ctx context.Context, id string) error {
+	return v.store.Reopen(ctx, id)
+}
+
+// Forget deletes every invite the owner sent, for when their account is deleted
+func (v *Invites) Forget(ctx context.Context, owner string) error {
+	return v.store.DeleteForOwner(ctx, owner)
+}
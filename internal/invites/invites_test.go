@@ -0,0 +1,63 @@
+package invites
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+func TestInvites(t *testing.T) {
+	store := NewMemoryStore()
+	v := NewInvites(store, time.Hour, 2)
+	ctx := context.Background()
+
+	_, _, err := v.Create(ctx, "list", "alice", "Bob <bob@example.com>", sharing.AccessRead)
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, _, err = v.Create(ctx, "list", "alice", "bob@example.com", sharing.AccessOwner)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	i, token, err := v.Create(ctx, "list", "alice", "bob@example.com", sharing.AccessWrite)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEqual(t, token, i.TokenHash, "only the hash is kept")
+	assert.Equal(t, StatusPending, i.Status)
+
+	_, err = v.Accept(ctx, token, "alice")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	accepted, err := v.Accept(ctx, token, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "list", accepted.List)
+	_, err = v.Accept(ctx, token, "carol")
+	assert.ErrorIs(t, err, ErrUsed, "an invite can only be used once")
+
+	assert.NoError(t, v.Reopen(ctx, i.ID))
+	_, err = v.Accept(ctx, token, "bob")
+	assert.NoError(t, err)
+
+	_, err = v.Accept(ctx, "not-a-token", "bob")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	v.SetLimits(-time.Minute, 2)
+	expired, expiredToken, err := v.Create(ctx, "list", "alice", "carol@example.com", sharing.AccessRead)
+	assert.NoError(t, err)
+	_, err = v.Accept(ctx, expiredToken, "carol")
+	assert.ErrorIs(t, err, ErrExpired)
+
+	v.SetLimits(time.Hour, 1)
+	_, _, err = v.Create(ctx, "list", "alice", "dave@example.com", sharing.AccessRead)
+	assert.NoError(t, err, "expired and accepted invites aren't pending")
+	_, _, err = v.Create(ctx, "list", "alice", "erin@example.com", sharing.AccessRead)
+	assert.ErrorIs(t, err, ErrTooMany)
+
+	assert.NoError(t, v.Revoke(ctx, "list", expired.ID))
+	assert.ErrorIs(t, v.Revoke(ctx, "other", i.ID), ErrNotFound)
+
+	assert.NoError(t, v.Forget(ctx, "alice"))
+	left, err := v.ForList(ctx, "list")
+	assert.NoError(t, err)
+	assert.Empty(t, left)
+}
@@ -0,0 +1,120 @@
+package invites
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps invites in memory, for development and tests
+type MemoryStore struct {
+	mu      sync.Mutex
+	invites map[string]Invite
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		invites: make(map[string]Invite),
+	}
+}
+
+// Insert implements Store
+func (m *MemoryStore) Insert(_ context.Context, i Invite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.invites[i.ID] = i
+	return nil
+}
+
+// ByToken implements Store
+func (m *MemoryStore) ByToken(_ context.Context, hash string) (Invite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, i := range m.invites {
+		if i.TokenHash == hash {
+			return i, nil
+		}
+	}
+
+	return Invite{}, ErrNotFound
+}
+
+// ForList implements Store
+func (m *MemoryStore) ForList(_ context.Context, list string) ([]Invite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Invite
+	for _, i := range m.invites {
+		if i.List == list {
+			out = append(out, i)
+		}
+	}
+
+	return out, nil
+}
+
+// Accept implements Store
+func (m *MemoryStore) Accept(_ context.Context, id, subject string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, ok := m.invites[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if i.AcceptedBy != "" {
+		return ErrUsed
+	}
+
+	i.AcceptedBy = subject
+	i.AcceptedAt = &at
+	m.invites[id] = i
+	return nil
+}
+
+// Reopen implements Store
+func (m *MemoryStore) Reopen(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, ok := m.invites[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	i.AcceptedBy = ""
+	i.AcceptedAt = nil
+	m.invites[id] = i
+	return nil
+}
+
+// Delete implements Store
+func (m *MemoryStore) Delete(_ context.Context, list, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, ok := m.invites[id]; !ok || i.List != list {
+		return ErrNotFound
+	}
+
+	delete(m.invites, id)
+	return nil
+}
+
+// DeleteForOwner implements Store
+func (m *MemoryStore) DeleteForOwner(_ context.Context, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, i := range m.invites {
+		if i.Owner == owner {
+			delete(m.invites, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package invites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// expiredRetention is how long mongo keeps an invite after it expires, so accepting it says it has
+// expired rather than that it doesn't exist and the owner still sees it listed as expired
+const expiredRetention = 7 * 24 * time.Hour
+
+// MongoStore keeps invites in a collection, mongo drops them once they have been expired for the retention
+type MongoStore struct {
+	invites *mongo.Collection
+}
+
+// NewMongoStore makes sure the indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collection string) (*MongoStore, error) {
+	m := &MongoStore{
+		invites: db.Collection(collection),
+	}
+
+	if _, err := m.invites.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "list", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "owner", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(expiredRetention.Seconds())),
+		},
+	}); err != nil {
+		// the expiry index already exists without the retention
+		if err := db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection},
+			{Key: "index", Value: bson.D{
+				{Key: "keyPattern", Value: bson.D{{Key: "expires_at", Value: 1}}},
+				{Key: "expireAfterSeconds", Value: int32(expiredRetention.Seconds())},
+			}},
+		}).Err(); err != nil {
+			return nil, fmt.Errorf("create invite indexes: %v", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Insert implements Store
+func (m *MongoStore) Insert(ctx context.Context, i Invite) error {
+	if _, err := m.invites.InsertOne(ctx, i); err != nil {
+		return fmt.Errorf("insert invite: %v", err)
+	}
+
+	return nil
+}
+
+// ByToken implements Store
+func (m *MongoStore) ByToken(ctx context.Context, hash string) (Invite, error) {
+	i := Invite{}
+	if err := m.invites.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&i); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Invite{}, ErrNotFound
+		}
+		return Invite{}, fmt.Errorf("find invite: %v", err)
+	}
+
+	return i, nil
+}
+
+// ForList implements Store
+func (m *MongoStore) ForList(ctx context.Context, list string) ([]Invite, error) {
+	cur, err := m.invites.Find(ctx, bson.M{"list": list}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find invites: %v", err)
+	}
+
+	var invites []Invite
+	if err := cur.All(ctx, &invites); err != nil {
+		return nil, fmt.Errorf("read invites: %v", err)
+	}
+
+	return invites, nil
+}
+
+// Accept implements Store, the invite is only matched while unaccepted so two accepts can't both win
+func (m *MongoStore) Accept(ctx context.Context, id, subject string, at time.Time) error {
+	res, err := m.invites.UpdateOne(ctx,
+		bson.M{"_id": id, "accepted_by": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"accepted_by": subject, "accepted_at": at}},
+	)
+	if err != nil {
+		return fmt.Errorf("accept invite: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrUsed
+	}
+
+	return nil
+}
+
+// Reopen implements Store
+func (m *MongoStore) Reopen(ctx context.Context, id string) error {
+	if _, err := m.invites.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"accepted_by": "", "accepted_at": ""}}); err != nil {
+		return fmt.Errorf("reopen invite: %v", err)
+	}
+
+	return nil
+}
+
+// Delete implements Store
+func (m *MongoStore) Delete(ctx context.Context, list, id string) error {
+	res, err := m.invites.DeleteOne(ctx, bson.M{"_id": id, "list": list})
+	if err != nil {
+		return fmt.Errorf("delete invite: %v", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteForOwner implements Store
+func (m *MongoStore) DeleteForOwner(ctx context.Context, owner string) error {
+	if _, err := m.invites.DeleteMany(ctx, bson.M{"owner": owner}); err != nil {
+		return fmt.Errorf("delete invites: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package invites
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoStoreExpired(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("expired invites are kept", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		_, err := NewMongoStore(context.Background(), mt.DB, "invites")
+		assert.NoError(mt, err)
+
+		indexes, ok := mt.GetStartedEvent().Command.Lookup("indexes").ArrayOK()
+		if !assert.True(mt, ok) {
+			return
+		}
+		values, err := indexes.Values()
+		assert.NoError(mt, err)
+		expiry := bson.Raw{}
+		for _, v := range values {
+			if _, err := v.Document().LookupErr("key", "expires_at"); err == nil {
+				expiry = v.Document()
+			}
+		}
+		seconds, ok := expiry.Lookup("expireAfterSeconds").AsInt64OK()
+		assert.True(mt, ok)
+		assert.Equal(mt, int64(expiredRetention.Seconds()), seconds, "mongo mustn't drop an invite the moment it expires")
+	})
+
+	mt.Run("accepting an expired invite", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		store, err := NewMongoStore(context.Background(), mt.DB, "invites")
+		assert.NoError(mt, err)
+		v := NewInvites(store, time.Hour, 5)
+
+		expired := time.Now().Add(-time.Hour).UTC().Truncate(time.Millisecond)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invites", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: "invite"},
+			{Key: "token_hash", Value: Hash("token")},
+			{Key: "list", Value: "list"},
+			{Key: "owner", Value: "alice"},
+			{Key: "email", Value: "bob@example.com"},
+			{Key: "access", Value: "read"},
+			{Key: "created_at", Value: expired.Add(-time.Hour)},
+			{Key: "expires_at", Value: expired},
+		}))
+
+		_, err = v.Accept(context.Background(), "token", "bob")
+		assert.ErrorIs(mt, err, ErrExpired)
+	})
+}
@@ -0,0 +1,258 @@
+// Package keys is the directory of users identity public keys. Every change to a users key is appended
+// to their history and each entry hashes the one before it, so a client that remembers a hash can tell
+// when the history it is shown has been rewritten or a key swapped out underneath it
+package keys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithms a key can use, both are what WebCrypto exports, ed25519 as the raw 32 byte key and
+// ecdsa-p256 as spki with a raw r||s signature over sha-256
+const (
+	AlgorithmEd25519 = "ed25519"
+	AlgorithmP256    = "ecdsa-p256"
+)
+
+// Operations in a keys history
+const (
+	OperationPublish = "publish"
+	// OperationWithdraw is the user deleting their account, they no longer have a key
+	OperationWithdraw = "withdraw"
+)
+
+// appendAttempts is how many times a publish is retried when another one got the same sequence first
+const appendAttempts = 3
+
+const maxKeyID = 128
+
+var (
+	// ErrNotFound is returned for a user who hasn't published a key
+	ErrNotFound = errors.New("no key published")
+	// ErrInvalid is wrapped by every problem with a key
+	ErrInvalid = errors.New("invalid key")
+	// ErrConflict is returned by Store.Append when the sequence is already taken
+	ErrConflict = errors.New("history entry already exists")
+)
+
+// Key is a users identity public key, Signature is the key signing its own SignedMessage
+type Key struct {
+	KeyID     string `json:"key_id" bson:"key_id"`
+	Algorithm string `json:"algorithm" bson:"algorithm"`
+	PublicKey string `json:"public_key" bson:"public_key"`
+	Signature string `json:"signature" bson:"signature"`
+}
+
+// Entry is one change in a users history, Hash is the hex sha-256 of the lines
+// "todo-list-key-history:v1", subject, seq, operation, key id, algorithm, public key, signature,
+// at in RFC 3339 with nanoseconds and previous, joined with newlines and empty for a missing key
+type Entry struct {
+	Subject   string    `json:"subject" bson:"subject"`
+	Seq       uint64    `json:"seq" bson:"seq"`
+	Operation string    `json:"operation" bson:"operation"`
+	Key       *Key      `json:"key,omitempty" bson:"key,omitempty"`
+	At        time.Time `json:"at" bson:"at"`
+	Previous  string    `json:"previous,omitempty" bson:"previous,omitempty"`
+	Hash      string    `json:"hash" bson:"hash"`
+}
+
+// Store keeps the histories, entries are only ever added
+type Store interface {
+	// Append adds the entry, returning ErrConflict when the subject already has an entry with its seq
+	Append(ctx context.Context, e Entry) error
+	// Latest returns ErrNotFound when the subject has no history
+	Latest(ctx context.Context, subject string) (Entry, error)
+	// History returns the subjects entries, oldest first
+	History(ctx context.Context, subject string) ([]Entry, error)
+}
+
+// Directory publishes and looks up keys
+type Directory struct {
+	store Store
+}
+
+// NewDirectory creates a directory over the store
+func NewDirectory(store Store) *Directory {
+	return &Directory{store: store}
+}
+
+// SignedMessage is what a key signs to show it belongs to the subject
+func SignedMessage(subject string, k Key) []byte {
+	return []byte(strings.Join([]string{"todo-list-key:v1", subject, k.KeyID, k.Algorithm, k.PublicKey}, "\n"))
+}
+
+// Publish makes the key the subjects current one, publishing the current key again changes nothing.
+// It reports whether a new entry was added
+func (d *Directory) Publish(ctx context.Context, subject string, k Key) (Entry, bool, error) {
+	if err := k.Verify(subject); err != nil {
+		return Entry{}, false, err
+	}
+
+	for range appendAttempts {
+		latest, err := d.store.Latest(ctx, subject)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return Entry{}, false, err
+		}
+		if err == nil && latest.Operation == OperationPublish && *latest.Key == k {
+			return latest, false, nil
+		}
+
+		e, err := d.append(ctx, latest, subject, OperationPublish, &k)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		return e, err == nil, err
+	}
+
+	return Entry{}, false, ErrConflict
+}
+
+// Current returns the entry that published the subjects key
+func (d *Directory) Current(ctx context.Context, subject string) (Entry, error) {
+	latest, err := d.store.Latest(ctx, subject)
+	if err != nil {
+		return Entry{}, err
+	}
+	if latest.Operation != OperationPublish {
+		return Entry{}, ErrNotFound
+	}
+
+	return latest, nil
+}
+
+// History returns every change to the subjects key, oldest first
+func (d *Directory) History(ctx context.Context, subject string) ([]Entry, error) {
+	history, err := d.store.History(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return history, nil
+}
+
+// Withdraw records that the subject no longer has a key, for when their account is deleted,
+// the history is kept so anyone who saw the key can see it was withdrawn
+func (d *Directory) Withdraw(ctx context.Context, subject string) error {
+	for range appendAttempts {
+		latest, err := d.store.Latest(ctx, subject)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		if latest.Operation == OperationWithdraw {
+			return nil
+		}
+
+		_, err = d.append(ctx, latest, subject, OperationWithdraw, nil)
+		if !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+
+	return ErrConflict
+}
+
+// append adds the entry after latest, which is empty for a subjects first entry
+func (d *Directory) append(ctx context.Context, latest Entry, subject, operation string, k *Key) (Entry, error) {
+	e := Entry{
+		Subject:   subject,
+		Seq:       latest.Seq + 1,
+		Operation: operation,
+		Key:       k,
+		// storage keeps milliseconds, the hash has to match what is read back
+		At:       time.Now().UTC().Truncate(time.Millisecond),
+		Previous: latest.Hash,
+	}
+	e.Hash = e.digest()
+
+	if err := d.store.Append(ctx, e); err != nil {
+		return Entry{}, err
+	}
+
+	return e, nil
+}
+
+func (e Entry) digest() string {
+	k := Key{}
+	if e.Key != nil {
+		k = *e.Key
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		"todo-list-key-history:v1",
+		e.Subject,
+		strconv.FormatUint(e.Seq, 10),
+		e.Operation,
+		k.KeyID,
+		k.Algorithm,
+		k.PublicKey,
+		k.Signature,
+		e.At.UTC().Format(time.RFC3339Nano),
+		e.Previous,
+	}, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks the key is well formed and its signature was made by it for the subject
+func (k Key) Verify(subject string) error {
+	if k.KeyID == "" || len(k.KeyID) > maxKeyID {
+		return fmt.Errorf("%w: key_id has to be between 1 and %d characters", ErrInvalid, maxKeyID)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil {
+		return fmt.Errorf("%w: public_key has to be base64", ErrInvalid)
+	}
+	sig, err := base64.StdEncoding.DecodeString(k.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature has to be base64", ErrInvalid)
+	}
+	msg := SignedMessage(subject, k)
+
+	switch k.Algorithm {
+	case AlgorithmEd25519:
+		if len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: an ed25519 public_key is %d bytes", ErrInvalid, ed25519.PublicKeySize)
+		}
+		if !ed25519.Verify(pub, msg, sig) {
+			return fmt.Errorf("%w: signature doesn't match the key", ErrInvalid)
+		}
+	case AlgorithmP256:
+		parsed, err := x509.ParsePKIXPublicKey(pub)
+		ec, ok := parsed.(*ecdsa.PublicKey)
+		if err != nil || !ok || ec.Curve != elliptic.P256() {
+			return fmt.Errorf("%w: an ecdsa-p256 public_key has to be a p-256 spki", ErrInvalid)
+		}
+		if len(sig) != 64 {
+			return fmt.Errorf("%w: an ecdsa-p256 signature is the 64 byte r||s", ErrInvalid)
+		}
+		digest := sha256.Sum256(msg)
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(ec, digest[:], r, s) {
+			return fmt.Errorf("%w: signature doesn't match the key", ErrInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: algorithm has to be %s or %s", ErrInvalid, AlgorithmEd25519, AlgorithmP256)
+	}
+
+	return nil
+}
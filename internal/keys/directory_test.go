@@ -0,0 +1,94 @@
+package keys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ed25519Key(t *testing.T, subject, id string) Key {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	k := Key{KeyID: id, Algorithm: AlgorithmEd25519, PublicKey: base64.StdEncoding.EncodeToString(pub)}
+	k.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, SignedMessage(subject, k)))
+
+	return k
+}
+
+func TestDirectory(t *testing.T) {
+	d := NewDirectory(NewMemoryStore())
+	ctx := context.Background()
+
+	_, err := d.Current(ctx, "alice")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	first := ed25519Key(t, "alice", "one")
+	e1, created, err := d.Publish(ctx, "alice", first)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, uint64(1), e1.Seq)
+
+	_, created, err = d.Publish(ctx, "alice", first)
+	assert.NoError(t, err)
+	assert.False(t, created, "publishing the same key again changes nothing")
+
+	_, _, err = d.Publish(ctx, "bob", first)
+	assert.ErrorIs(t, err, ErrInvalid, "a key signed for another subject is rejected")
+
+	second := ed25519Key(t, "alice", "two")
+	e2, _, err := d.Publish(ctx, "alice", second)
+	assert.NoError(t, err)
+	assert.Equal(t, e1.Hash, e2.Previous)
+
+	current, err := d.Current(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "two", current.Key.KeyID)
+
+	assert.NoError(t, d.Withdraw(ctx, "alice"))
+	_, err = d.Current(ctx, "alice")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	history, err := d.History(ctx, "alice")
+	assert.NoError(t, err)
+	if assert.Len(t, history, 3) {
+		previous := ""
+		for _, e := range history {
+			assert.Equal(t, previous, e.Previous)
+			assert.Equal(t, e.digest(), e.Hash)
+			previous = e.Hash
+		}
+		assert.Equal(t, OperationWithdraw, history[2].Operation)
+	}
+}
+
+func TestVerifyP256(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	spki, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+
+	k := Key{KeyID: "web", Algorithm: AlgorithmP256, PublicKey: base64.StdEncoding.EncodeToString(spki)}
+	digest := sha256.Sum256(SignedMessage("alice", k))
+	r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+	assert.NoError(t, err)
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	k.Signature = base64.StdEncoding.EncodeToString(sig)
+
+	assert.NoError(t, k.Verify("alice"))
+	assert.ErrorIs(t, k.Verify("bob"), ErrInvalid)
+
+	k.Algorithm = "rsa"
+	assert.ErrorIs(t, k.Verify("alice"), ErrInvalid)
+}
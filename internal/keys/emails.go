@@ -0,0 +1,145 @@
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrEmailNotFound is returned for an address nobody has verified and for a verification token that
+	// doesn't exist
+	ErrEmailNotFound = errors.New("no verified email")
+	// ErrEmailExpired is returned when verifying with a token after it has expired
+	ErrEmailExpired = errors.New("email verification has expired")
+)
+
+// Email is the address a user can be found by, Address is only set once it has been verified and
+// Pending is an address waiting for its token to come back
+type Email struct {
+	Subject    string     `json:"-" bson:"_id"`
+	Address    string     `json:"address,omitempty" bson:"address,omitempty"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+	Pending    string     `json:"pending,omitempty" bson:"pending,omitempty"`
+	TokenHash  string     `json:"-" bson:"token_hash,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
+
+// EmailStore keeps each users email
+type EmailStore interface {
+	// Claim replaces the subjects pending address, leaving the verified one until the new one is verified
+	Claim(ctx context.Context, subject, pending, hash string, expires time.Time) error
+	// ByToken returns ErrEmailNotFound when no pending address has the token hash
+	ByToken(ctx context.Context, hash string) (Email, error)
+	// Verify makes the pending address with the token hash the subjects address and takes it from
+	// anyone else who had verified it, returning ErrEmailNotFound when the claim has been replaced
+	Verify(ctx context.Context, subject, hash, address string, at time.Time) error
+	// ByAddress returns the subject who verified the address, or ErrEmailNotFound
+	ByAddress(ctx context.Context, address string) (string, error)
+	Delete(ctx context.Context, subject string) error
+}
+
+// Emails lets a user verify an email address so others can find their key by it
+type Emails struct {
+	store EmailStore
+
+	mu  sync.RWMutex
+	ttl time.Duration
+}
+
+// NewEmails creates the email index over the store, verification tokens last for the ttl
+func NewEmails(store EmailStore, ttl time.Duration) *Emails {
+	return &Emails{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// SetTTL changes how long new verification tokens last, for a config reload
+func (e *Emails) SetTTL(ttl time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.ttl = ttl
+}
+
+// hashToken is how a verification token is kept, the token itself is only ever in the email
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalise lower cases the address so a lookup doesn't depend on how it was typed
+func normalise(address string) (string, error) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil || addr.Name != "" || addr.Address != address {
+		return "", fmt.Errorf("%w: email has to be a plain email address", ErrInvalid)
+	}
+
+	return strings.ToLower(address), nil
+}
+
+// Claim starts verifying the address for the subject, returning the token to send to it
+func (e *Emails) Claim(ctx context.Context, subject, address string) (Email, string, error) {
+	address, err := normalise(address)
+	if err != nil {
+		return Email{}, "", err
+	}
+
+	e.mu.RLock()
+	ttl := e.ttl
+	e.mu.RUnlock()
+
+	token := rand.Text()
+	expires := time.Now().UTC().Add(ttl)
+	if err := e.store.Claim(ctx, subject, address, hashToken(token), expires); err != nil {
+		return Email{}, "", err
+	}
+
+	return Email{Subject: subject, Pending: address, ExpiresAt: &expires}, token, nil
+}
+
+// Verify makes the address the token was sent to the subjects, anyone who verified it before stops
+// being found by it
+func (e *Emails) Verify(ctx context.Context, subject, token string) (Email, error) {
+	hash := hashToken(token)
+	pending, err := e.store.ByToken(ctx, hash)
+	if err != nil {
+		return Email{}, err
+	}
+	if pending.Subject != subject {
+		return Email{}, ErrEmailNotFound
+	}
+	if pending.ExpiresAt == nil || !time.Now().Before(*pending.ExpiresAt) {
+		return Email{}, ErrEmailExpired
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if err := e.store.Verify(ctx, subject, hash, pending.Pending, now); err != nil {
+		return Email{}, err
+	}
+
+	return Email{Subject: subject, Address: pending.Pending, VerifiedAt: &now}, nil
+}
+
+// Subject returns who verified the address
+func (e *Emails) Subject(ctx context.Context, address string) (string, error) {
+	address, err := normalise(address)
+	if err != nil {
+		return "", err
+	}
+
+	return e.store.ByAddress(ctx, address)
+}
+
+// Forget deletes the subjects email, for when their account is deleted
+func (e *Emails) Forget(ctx context.Context, subject string) error {
+	return e.store.Delete(ctx, subject)
+}
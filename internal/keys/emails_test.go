@@ -0,0 +1,83 @@
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmails(t *testing.T) {
+	e := NewEmails(NewMemoryEmailStore(), time.Hour)
+	ctx := context.Background()
+
+	t.Run("only a plain address", func(t *testing.T) {
+		_, _, err := e.Claim(ctx, "alice", "Alice <alice@example.com>")
+		assert.ErrorIs(t, err, ErrInvalid)
+	})
+
+	_, token, err := e.Claim(ctx, "alice", "Alice@Example.com")
+	assert.NoError(t, err)
+
+	t.Run("not found until verified", func(t *testing.T) {
+		_, err := e.Subject(ctx, "alice@example.com")
+		assert.ErrorIs(t, err, ErrEmailNotFound)
+	})
+
+	t.Run("only the claimant can verify", func(t *testing.T) {
+		_, err := e.Verify(ctx, "mallory", token)
+		assert.ErrorIs(t, err, ErrEmailNotFound)
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		verified, err := e.Verify(ctx, "alice", token)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice@example.com", verified.Address)
+
+		subject, err := e.Subject(ctx, "ALICE@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", subject)
+	})
+
+	t.Run("a token is single use", func(t *testing.T) {
+		_, err := e.Verify(ctx, "alice", token)
+		assert.ErrorIs(t, err, ErrEmailNotFound)
+	})
+
+	t.Run("a new claim keeps the verified address", func(t *testing.T) {
+		_, _, err := e.Claim(ctx, "alice", "alice@example.org")
+		assert.NoError(t, err)
+
+		subject, err := e.Subject(ctx, "alice@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", subject)
+	})
+
+	t.Run("the latest to verify an address is found by it", func(t *testing.T) {
+		_, token, err := e.Claim(ctx, "bob", "alice@example.com")
+		assert.NoError(t, err)
+		_, err = e.Verify(ctx, "bob", token)
+		assert.NoError(t, err)
+
+		subject, err := e.Subject(ctx, "alice@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob", subject)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		e.SetTTL(-time.Minute)
+		_, token, err := e.Claim(ctx, "carol", "carol@example.com")
+		assert.NoError(t, err)
+
+		_, err = e.Verify(ctx, "carol", token)
+		assert.ErrorIs(t, err, ErrEmailExpired)
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		assert.NoError(t, e.Forget(ctx, "bob"))
+
+		_, err := e.Subject(ctx, "alice@example.com")
+		assert.ErrorIs(t, err, ErrEmailNotFound, "bob took the address from alice")
+	})
+}
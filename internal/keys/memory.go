@@ -0,0 +1,139 @@
+package keys
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps histories in memory, for development and tests
+type MemoryStore struct {
+	mu        sync.Mutex
+	histories map[string][]Entry
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		histories: make(map[string][]Entry),
+	}
+}
+
+// Append implements Store
+func (m *MemoryStore) Append(_ context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if uint64(len(m.histories[e.Subject]))+1 != e.Seq {
+		return ErrConflict
+	}
+
+	m.histories[e.Subject] = append(m.histories[e.Subject], e)
+	return nil
+}
+
+// Latest implements Store
+func (m *MemoryStore) Latest(_ context.Context, subject string) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history := m.histories[subject]
+	if len(history) == 0 {
+		return Entry{}, ErrNotFound
+	}
+
+	return history[len(history)-1], nil
+}
+
+// History implements Store
+func (m *MemoryStore) History(_ context.Context, subject string) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Entry(nil), m.histories[subject]...), nil
+}
+
+// MemoryEmailStore keeps emails in memory, for development and tests
+type MemoryEmailStore struct {
+	mu     sync.Mutex
+	emails map[string]Email
+}
+
+// NewMemoryEmailStore creates an empty store
+func NewMemoryEmailStore() *MemoryEmailStore {
+	return &MemoryEmailStore{
+		emails: make(map[string]Email),
+	}
+}
+
+// Claim implements EmailStore
+func (m *MemoryEmailStore) Claim(_ context.Context, subject, pending, hash string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.emails[subject]
+	e.Subject = subject
+	e.Pending = pending
+	e.TokenHash = hash
+	e.ExpiresAt = &expires
+	m.emails[subject] = e
+	return nil
+}
+
+// ByToken implements EmailStore
+func (m *MemoryEmailStore) ByToken(_ context.Context, hash string) (Email, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.emails {
+		if e.TokenHash == hash {
+			return e, nil
+		}
+	}
+
+	return Email{}, ErrEmailNotFound
+}
+
+// Verify implements EmailStore
+func (m *MemoryEmailStore) Verify(_ context.Context, subject, hash, address string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.emails[subject]
+	if !ok || e.TokenHash != hash {
+		return ErrEmailNotFound
+	}
+
+	for other, e := range m.emails {
+		if e.Address == address {
+			e.Address = ""
+			e.VerifiedAt = nil
+			m.emails[other] = e
+		}
+	}
+	m.emails[subject] = Email{Subject: subject, Address: address, VerifiedAt: &at}
+	return nil
+}
+
+// ByAddress implements EmailStore
+func (m *MemoryEmailStore) ByAddress(_ context.Context, address string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.emails {
+		if e.Address == address {
+			return e.Subject, nil
+		}
+	}
+
+	return "", ErrEmailNotFound
+}
+
+// Delete implements EmailStore
+func (m *MemoryEmailStore) Delete(_ context.Context, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.emails, subject)
+	return nil
+}
@@ -0,0 +1,175 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps histories in a collection, the unique index stops two publishes taking the same place
+type MongoStore struct {
+	entries *mongo.Collection
+}
+
+// NewMongoStore makes sure the index exists
+func NewMongoStore(ctx context.Context, db *mongo.Database, collection string) (*MongoStore, error) {
+	m := &MongoStore{
+		entries: db.Collection(collection),
+	}
+
+	if _, err := m.entries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "subject", Value: 1}, {Key: "seq", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return nil, fmt.Errorf("create subject index: %v", err)
+	}
+
+	return m, nil
+}
+
+// Append implements Store
+func (m *MongoStore) Append(ctx context.Context, e Entry) error {
+	if _, err := m.entries.InsertOne(ctx, e); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConflict
+		}
+		return fmt.Errorf("insert key entry: %v", err)
+	}
+
+	return nil
+}
+
+// Latest implements Store
+func (m *MongoStore) Latest(ctx context.Context, subject string) (Entry, error) {
+	e := Entry{}
+	err := m.entries.FindOne(ctx, bson.M{"subject": subject}, options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})).Decode(&e)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Entry{}, ErrNotFound
+		}
+		return Entry{}, fmt.Errorf("find latest key entry: %v", err)
+	}
+
+	return e, nil
+}
+
+// History implements Store
+func (m *MongoStore) History(ctx context.Context, subject string) ([]Entry, error) {
+	cur, err := m.entries.Find(ctx, bson.M{"subject": subject}, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find key history: %v", err)
+	}
+
+	var history []Entry
+	if err := cur.All(ctx, &history); err != nil {
+		return nil, fmt.Errorf("read key history: %v", err)
+	}
+
+	return history, nil
+}
+
+// MongoEmailStore keeps emails in a collection with one document for each user
+type MongoEmailStore struct {
+	emails *mongo.Collection
+}
+
+// NewMongoEmailStore makes sure the indexes exist
+func NewMongoEmailStore(ctx context.Context, db *mongo.Database, collection string) (*MongoEmailStore, error) {
+	m := &MongoEmailStore{
+		emails: db.Collection(collection),
+	}
+
+	if _, err := m.emails.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "address", Value: 1}, {Key: "verified_at", Value: -1}},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("create email indexes: %v", err)
+	}
+
+	return m, nil
+}
+
+// Claim implements EmailStore
+func (m *MongoEmailStore) Claim(ctx context.Context, subject, pending, hash string, expires time.Time) error {
+	if _, err := m.emails.UpdateOne(ctx,
+		bson.M{"_id": subject},
+		bson.M{"$set": bson.M{"pending": pending, "token_hash": hash, "expires_at": expires}},
+		options.Update().SetUpsert(true),
+	); err != nil {
+		return fmt.Errorf("claim email: %v", err)
+	}
+
+	return nil
+}
+
+// ByToken implements EmailStore
+func (m *MongoEmailStore) ByToken(ctx context.Context, hash string) (Email, error) {
+	e := Email{}
+	if err := m.emails.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&e); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Email{}, ErrEmailNotFound
+		}
+		return Email{}, fmt.Errorf("find email token: %v", err)
+	}
+
+	return e, nil
+}
+
+// Verify implements EmailStore, the token is matched so a claim made since can't be verified by an old one
+func (m *MongoEmailStore) Verify(ctx context.Context, subject, hash, address string, at time.Time) error {
+	if _, err := m.emails.UpdateMany(ctx,
+		bson.M{"address": address, "_id": bson.M{"$ne": subject}},
+		bson.M{"$unset": bson.M{"address": "", "verified_at": ""}},
+	); err != nil {
+		return fmt.Errorf("release email: %v", err)
+	}
+
+	res, err := m.emails.UpdateOne(ctx,
+		bson.M{"_id": subject, "token_hash": hash},
+		bson.M{
+			"$set":   bson.M{"address": address, "verified_at": at},
+			"$unset": bson.M{"pending": "", "token_hash": "", "expires_at": ""},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("verify email: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrEmailNotFound
+	}
+
+	return nil
+}
+
+// ByAddress implements EmailStore
+func (m *MongoEmailStore) ByAddress(ctx context.Context, address string) (string, error) {
+	e := Email{}
+	err := m.emails.FindOne(ctx, bson.M{"address": address}, options.FindOne().SetSort(bson.D{{Key: "verified_at", Value: -1}})).Decode(&e)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", ErrEmailNotFound
+		}
+		return "", fmt.Errorf("find email: %v", err)
+	}
+
+	return e.Subject, nil
+}
+
+// Delete implements EmailStore
+func (m *MongoEmailStore) Delete(ctx context.Context, subject string) error {
+	if _, err := m.emails.DeleteOne(ctx, bson.M{"_id": subject}); err != nil {
+		return fmt.Errorf("delete email: %v", err)
+	}
+
+	return nil
+}
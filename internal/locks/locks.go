@@ -0,0 +1,45 @@
+// Package locks serialises work on the same key within one process, other instances of the api aren't
+// covered so anything that has to hold across them needs a check in storage as well
+package locks
+
+import "sync"
+
+// Keyed hands out a mutex per key, the zero value is ready to use
+type Keyed struct {
+	mu    sync.Mutex
+	locks map[string]*lock
+}
+
+type lock struct {
+	sync.Mutex
+	// waiters is how many callers hold or are waiting for the lock, it is dropped once there are none
+	waiters int
+}
+
+// Lock blocks until no one else holds the keys lock, calling the returned func releases it
+func (k *Keyed) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*lock)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &lock{}
+		k.locks[key] = l
+	}
+	l.waiters++
+	k.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		k.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,58 @@
+package locks
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyed(t *testing.T) {
+	k := &Keyed{}
+
+	t.Run("same key waits", func(t *testing.T) {
+		unlock := k.Lock("alice")
+		locked := make(chan struct{})
+		go func() {
+			k.Lock("alice")()
+			close(locked)
+		}()
+
+		select {
+		case <-locked:
+			t.Fatal("locked while another caller held the lock")
+		case <-time.After(20 * time.Millisecond):
+		}
+		unlock()
+		<-locked
+	})
+
+	t.Run("other keys don't wait", func(t *testing.T) {
+		unlock := k.Lock("alice")
+		defer unlock()
+
+		k.Lock("bob")()
+	})
+
+	t.Run("serialises", func(t *testing.T) {
+		counter := 0
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Go(func() {
+				unlock := k.Lock("alice")
+				defer unlock()
+				c := counter
+				time.Sleep(time.Microsecond)
+				counter = c + 1
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, 50, counter)
+	})
+
+	t.Run("unused keys are dropped", func(t *testing.T) {
+		k.Lock("carol")()
+		assert.Empty(t, k.locks)
+	})
+}
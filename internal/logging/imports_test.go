@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bugfixesLogs is only used by cmd, everything else logs through a components slog logger
+const bugfixesLogs = "github.com/bugfixes/go-bugfixes/logs"
+
+func TestNoBugfixesLogsOutsideCmd(t *testing.T) {
+	root := filepath.Join("..", "..")
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == filepath.Join(root, "cmd") || d.Name() == "vendor" || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range f.Imports {
+			if imported, _ := strconv.Unquote(spec.Path.Value); imported == bugfixesLogs {
+				t.Errorf("%s imports %s, log through a components *slog.Logger instead", path, bugfixesLogs)
+			}
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+}
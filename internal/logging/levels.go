@@ -0,0 +1,225 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/metrics"
+)
+
+// Components that can be given their own level, Global is every component without one
+const (
+	Global           = "global"
+	ComponentHTTP    = "http"
+	ComponentList    = "api.list"
+	ComponentAccount = "api.account"
+	ComponentAuth    = "auth"
+	ComponentChanges = "changes"
+	ComponentDevices = "devices"
+	ComponentSharing = "sharing"
+	ComponentKeys    = "keys"
+	ComponentInbox   = "inbox"
+	ComponentService = "service"
+	ComponentAdmin   = "admin"
+)
+
+var components = map[string]bool{
+	Global:           true,
+	ComponentHTTP:    true,
+	ComponentList:    true,
+	ComponentAccount: true,
+	ComponentAuth:    true,
+	ComponentChanges: true,
+	ComponentDevices: true,
+	ComponentSharing: true,
+	ComponentKeys:    true,
+	ComponentInbox:   true,
+	ComponentService: true,
+	ComponentAdmin:   true,
+}
+
+// Levels is the level of every component, changed at runtime through the admin endpoint,
+// a change can expire and fall back to the level underneath it
+type Levels struct {
+	base slog.Level
+
+	mu        sync.RWMutex
+	overrides map[string]*override
+}
+
+type override struct {
+	level   slog.Level
+	expires time.Time
+	timer   *time.Timer
+}
+
+// LevelState is the current level of a component
+type LevelState struct {
+	Level   string     `json:"level"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// NewLevels starts every component at base
+func NewLevels(base slog.Level) *Levels {
+	l := &Levels{
+		base:      base,
+		overrides: make(map[string]*override),
+	}
+	l.publish()
+
+	return l
+}
+
+// ParseLevel turns debug, info, warn or error into a level
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("unknown log level: %s", s)
+	}
+
+	return level, nil
+}
+
+// Logger creates a logger whose level follows the component
+func (l *Levels) Logger(w io.Writer, component string) *slog.Logger {
+	return New(w, l.Leveler(component)).With("component", component)
+}
+
+// Leveler returns the level for a component, it changes as the levels are set
+func (l *Levels) Leveler(component string) slog.Leveler {
+	return componentLevel{levels: l, component: component}
+}
+
+type componentLevel struct {
+	levels    *Levels
+	component string
+}
+
+func (c componentLevel) Level() slog.Level {
+	return c.levels.Level(c.component)
+}
+
+// Level is the level a component is logging at
+func (l *Levels) Level(component string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if o, ok := l.overrides[component]; ok {
+		return o.level
+	}
+	if o, ok := l.overrides[Global]; ok {
+		return o.level
+	}
+
+	return l.base
+}
+
+// Set changes the level of a component, or every component without its own level when it is Global,
+// a ttl above 0 reverts it once it has passed
+func (l *Levels) Set(component string, level slog.Level, ttl time.Duration) error {
+	if !components[component] {
+		return fmt.Errorf("unknown log component: %s", component)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stop(component)
+	o := &override{level: level}
+	if ttl > 0 {
+		o.expires = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() {
+			l.expire(component, o)
+		})
+	}
+	l.overrides[component] = o
+	l.publishLocked()
+
+	return nil
+}
+
+// Reset puts a component back to the level underneath it
+func (l *Levels) Reset(component string) error {
+	if !components[component] {
+		return fmt.Errorf("unknown log component: %s", component)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stop(component)
+	delete(l.overrides, component)
+	l.publishLocked()
+
+	return nil
+}
+
+func (l *Levels) expire(component string, o *override) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// a newer Set replaced it before the timer fired
+	if l.overrides[component] != o {
+		return
+	}
+	delete(l.overrides, component)
+	l.publishLocked()
+}
+
+func (l *Levels) stop(component string) {
+	if o, ok := l.overrides[component]; ok && o.timer != nil {
+		o.timer.Stop()
+	}
+}
+
+// Snapshot returns the level of every component
+func (l *Levels) Snapshot() map[string]LevelState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.snapshotLocked()
+}
+
+func (l *Levels) snapshotLocked() map[string]LevelState {
+	global := LevelState{Level: strings.ToLower(l.base.String())}
+	if o, ok := l.overrides[Global]; ok {
+		global = o.state()
+	}
+
+	out := make(map[string]LevelState, len(components))
+	for c := range components {
+		out[c] = global
+		if o, ok := l.overrides[c]; ok {
+			out[c] = o.state()
+		}
+	}
+
+	return out
+}
+
+func (o *override) state() LevelState {
+	s := LevelState{Level: strings.ToLower(o.level.String())}
+	if !o.expires.IsZero() {
+		expires := o.expires
+		s.Expires = &expires
+	}
+
+	return s
+}
+
+func (l *Levels) publish() {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	l.publishLocked()
+}
+
+func (l *Levels) publishLocked() {
+	for c, state := range l.snapshotLocked() {
+		metrics.SetLogLevel(c, state.Level)
+	}
+}
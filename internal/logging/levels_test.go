@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"expvar"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevels(t *testing.T) {
+	l := NewLevels(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	auth := l.Logger(&buf, ComponentAuth)
+	auth.Debug("hidden")
+	assert.Empty(t, buf.String())
+
+	assert.NoError(t, l.Set(ComponentAuth, slog.LevelDebug, 0))
+	auth.Debug("shown")
+	assert.Contains(t, buf.String(), `"component":"auth"`)
+	assert.Equal(t, slog.LevelInfo, l.Level(ComponentList), "other components keep the global level")
+
+	assert.NoError(t, l.Set(Global, slog.LevelWarn, 0))
+	assert.Equal(t, slog.LevelWarn, l.Level(ComponentList))
+	assert.Equal(t, slog.LevelDebug, l.Level(ComponentAuth), "a component level wins over the global one")
+	assert.Equal(t, `"warn"`, expvar.Get("log_level").(*expvar.Map).Get(ComponentList).String())
+
+	assert.NoError(t, l.Reset(ComponentAuth))
+	assert.Equal(t, slog.LevelWarn, l.Level(ComponentAuth))
+
+	assert.Error(t, l.Set("nope", slog.LevelDebug, 0))
+	assert.Error(t, l.Reset("nope"))
+}
+
+func TestLevelsTTL(t *testing.T) {
+	l := NewLevels(slog.LevelInfo)
+
+	assert.NoError(t, l.Set(ComponentHTTP, slog.LevelDebug, 20*time.Millisecond))
+	state := l.Snapshot()[ComponentHTTP]
+	assert.Equal(t, "debug", state.Level)
+	assert.NotNil(t, state.Expires)
+
+	assert.Eventually(t, func() bool {
+		return l.Level(ComponentHTTP) == slog.LevelInfo
+	}, time.Second, 5*time.Millisecond)
+
+	// a later set isn't reverted by the timer of the one it replaced
+	assert.NoError(t, l.Set(ComponentHTTP, slog.LevelDebug, 20*time.Millisecond))
+	assert.NoError(t, l.Set(ComponentHTTP, slog.LevelError, 0))
+	time.Sleep(40 * time.Millisecond)
+	assert.Equal(t, slog.LevelError, l.Level(ComponentHTTP))
+}
@@ -0,0 +1,132 @@
+// Package logging provides the structured logger, every record passes through the redaction handler.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces any value that should never be logged
+const Redacted = "[REDACTED]"
+
+// sensitive are the keys (compared lower case) whose values never get logged
+var sensitive = map[string]bool{
+	"authorization":       true,
+	"x-user-access-token": true,
+	"access_token":        true,
+	"accesstoken":         true,
+	"token":               true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"data":                true,
+	"iv":                  true,
+	"ciphertext":          true,
+	"password":            true,
+}
+
+// IsSensitive reports whether a key is one that has to be redacted
+func IsSensitive(key string) bool {
+	return sensitive[strings.ToLower(key)]
+}
+
+// New creates a JSON logger that redacts secrets before they are written
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&redactHandler{
+		next: slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+		}),
+	})
+}
+
+// HashSubject returns a stable identifier for a subject that can't be reversed from the logs
+func HashSubject(subject string) string {
+	if subject == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RedactHeaders returns a copy of the headers with the sensitive ones removed and the subject hashed
+func RedactHeaders(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if IsSensitive(k) {
+			out[k] = []string{Redacted}
+			continue
+		}
+		if strings.EqualFold(k, "X-User-Subject") {
+			out[k] = []string{HashSubject(strings.Join(v, ","))}
+			continue
+		}
+		out[k] = append([]string(nil), v...)
+	}
+
+	return out
+}
+
+type redactHandler struct {
+	next slog.Handler
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(redact(a))
+		return true
+	})
+
+	return h.next.Handle(ctx, nr)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		clean = append(clean, redact(a))
+	}
+
+	return &redactHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redact(a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]any, 0, len(group))
+		for _, g := range group {
+			clean = append(clean, redact(g))
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindAny:
+		switch h := v.Any().(type) {
+		case http.Header:
+			return slog.Any(a.Key, RedactHeaders(h))
+		case *http.Request:
+			return slog.Group(a.Key,
+				slog.String("method", h.Method),
+				slog.String("path", h.URL.Path),
+				slog.Any("headers", RedactHeaders(h.Header)))
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: v}
+}
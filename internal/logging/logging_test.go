@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedaction(t *testing.T) {
+	t.Run("sensitive keys", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := New(buf, slog.LevelDebug)
+
+		logger.Info("test",
+			"authorization", "Bearer secret-token",
+			"x-user-access-token", "secret-token",
+			"data", "secret-ciphertext",
+			"subject", "visible")
+
+		assert.NotContains(t, buf.String(), "secret-token")
+		assert.NotContains(t, buf.String(), "secret-ciphertext")
+		assert.Contains(t, buf.String(), "visible")
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := New(buf, slog.LevelDebug)
+
+		h := http.Header{}
+		h.Set("Authorization", "Bearer secret-token")
+		h.Set("X-User-Access-Token", "secret-token")
+		h.Set("Cookie", "session=secret-cookie")
+		h.Set("Accept", "application/json")
+
+		logger.Info("test", "headers", h)
+		logger.With("headers", h).Info("with")
+		logger.Info("test", slog.Group("request", "headers", h))
+
+		assert.NotContains(t, buf.String(), "secret-token")
+		assert.NotContains(t, buf.String(), "secret-cookie")
+		assert.Contains(t, buf.String(), "application/json")
+	})
+
+	t.Run("requests", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := New(buf, slog.LevelDebug)
+
+		r, _ := http.NewRequest(http.MethodGet, "/list", nil)
+		r.Header.Set("X-User-Access-Token", "secret-token")
+
+		logger.Info("test", "request", r)
+
+		assert.NotContains(t, buf.String(), "secret-token")
+		assert.Contains(t, buf.String(), "/list")
+	})
+}
+
+func TestHashSubject(t *testing.T) {
+	assert.Equal(t, "", HashSubject(""))
+	assert.Equal(t, HashSubject("subject"), HashSubject("subject"))
+	assert.NotEqual(t, HashSubject("subject"), HashSubject("other"))
+	assert.NotContains(t, HashSubject("subject"), "subject")
+}
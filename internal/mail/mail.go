@@ -0,0 +1,167 @@
+// Package mail sends the emails the api needs, such as invites, as plain text
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a message
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// SMTP sends through an smtp server, upgrading with STARTTLS whenever the server offers it
+type SMTP struct {
+	host    string
+	addr    string
+	from    *mail.Address
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// NewSMTP creates a sender for host:port, the username and password are only used when set.
+// Plain auth is refused by net/smtp unless the connection is encrypted or to localhost
+func NewSMTP(host string, port int, username, password, from string, timeout time.Duration) (*SMTP, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("parse from address: %v", err)
+	}
+
+	s := &SMTP{
+		host:    host,
+		addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		from:    addr,
+		timeout: timeout,
+	}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s, nil
+}
+
+// Send implements Sender
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return fmt.Errorf("parse to address: %v", err)
+	}
+	data, err := s.compose(to, m)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("dial smtp: %v", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp greeting: %v", err)
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("smtp starttls: %v", err)
+		}
+	}
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return fmt.Errorf("smtp auth: %v", err)
+		}
+	}
+	if err := c.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("smtp mail from: %v", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp rcpt to: %v", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp end data: %v", err)
+	}
+
+	return c.Quit()
+}
+
+// compose builds the message, the body is quoted-printable so long lines and non-ascii survive
+func (s *SMTP) compose(to *mail.Address, m Message) ([]byte, error) {
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, errors.New("subject can't contain a line break")
+	}
+
+	buf := &bytes.Buffer{}
+	headers := [][2]string{
+		{"From", s.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", rand.Text(), s.host)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `text/plain; charset="utf-8"`},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(buf)
+	if _, err := qp.Write([]byte(m.Body)); err != nil {
+		return nil, fmt.Errorf("encode body: %v", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("encode body: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Log writes messages to the log instead of sending them, for development without an smtp server.
+// The body isn't logged, it can hold links such as an invites accept url that work for whoever has them
+type Log struct {
+	Logger *slog.Logger
+}
+
+// Send implements Sender
+func (l Log) Send(_ context.Context, m Message) error {
+	l.Logger.Info("email not sent, there is no smtp server", "to", m.To, "subject", m.Subject)
+	return nil
+}
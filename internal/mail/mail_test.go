@@ -0,0 +1,114 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime/quotedprintable"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sink is an smtp server that accepts every message, without STARTTLS or auth
+func sink(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = lis.Close()
+	})
+
+	got := make(chan string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+		reply("220 sink ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 sink")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				data := &strings.Builder{}
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				got <- data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return lis.Addr().String(), got
+}
+
+func TestSMTPSend(t *testing.T) {
+	addr, got := sink(t)
+	host, port, err := net.SplitHostPort(addr)
+	assert.NoError(t, err)
+	p, _ := net.LookupPort("tcp", port)
+
+	s, err := NewSMTP(host, p, "", "", "Todo Lists <no-reply@todo-list.app>", time.Second)
+	assert.NoError(t, err)
+
+	body := "You have been invited, open https://todo-list.app/invites/" + strings.Repeat("x", 100)
+	assert.NoError(t, s.Send(context.Background(), Message{To: "bob@example.com", Subject: "You're invited", Body: body}))
+
+	select {
+	case data := <-got:
+		headers, encoded, _ := strings.Cut(data, "\r\n\r\n")
+		assert.Contains(t, headers, "To: <bob@example.com>")
+		assert.Contains(t, headers, `From: "Todo Lists" <no-reply@todo-list.app>`)
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(encoded)))
+		assert.NoError(t, err)
+		// the data writer ends the message with a line break
+		assert.Equal(t, body, strings.TrimSuffix(string(decoded), "\r\n"))
+	case <-time.After(time.Second):
+		t.Fatal("nothing was delivered")
+	}
+
+	assert.Error(t, s.Send(context.Background(), Message{To: "bob@example.com", Subject: "a\r\nBcc: eve@example.com"}))
+}
+
+func TestLogSend(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := Log{Logger: slog.New(slog.NewTextHandler(buf, nil))}
+
+	assert.NoError(t, l.Send(context.Background(), Message{
+		To:      "bob@example.com",
+		Subject: "Alice shared a list with you",
+		Body:    "https://todo-list.app/invites/secret-token",
+	}))
+	assert.Contains(t, buf.String(), "bob@example.com")
+	assert.NotContains(t, buf.String(), "secret-token", "the body can hold a live link")
+}
@@ -0,0 +1,55 @@
+// Package metrics exposes the service counters and gauges through expvar
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+)
+
+var (
+	// BreakerState is the state of each circuit breaker, 0 closed, 1 open, 2 half-open
+	BreakerState = expvar.NewMap("breaker_state")
+	// BreakerTrips counts how many times each circuit breaker has opened
+	BreakerTrips = expvar.NewMap("breaker_trips_total")
+	// BuildInfo is the service, version, hash and go version of the running build
+	BuildInfo = expvar.NewMap("build_info")
+	// LogLevel is the level each log component is currently logging at
+	LogLevel = expvar.NewMap("log_level")
+	// MaintenanceMode is off, read-only or full
+	MaintenanceMode = expvar.NewString("maintenance_mode")
+	// ChangeRecordFailures counts changes that were made but couldn't be added to the change feed
+	ChangeRecordFailures = expvar.NewInt("change_record_failures_total")
+)
+
+// SetBreakerState records the current state of a breaker
+func SetBreakerState(name string, state int64) {
+	v := new(expvar.Int)
+	v.Set(state)
+	BreakerState.Set(name, v)
+}
+
+// SetBuildInfo records the running build
+func SetBuildInfo(info map[string]string) {
+	for k, v := range info {
+		s := new(expvar.String)
+		s.Set(v)
+		BuildInfo.Set(k, s)
+	}
+}
+
+// SetLogLevel records the level of a log component
+func SetLogLevel(component, level string) {
+	s := new(expvar.String)
+	s.Set(level)
+	LogLevel.Set(component, s)
+}
+
+// SetMaintenanceMode records the maintenance mode
+func SetMaintenanceMode(mode string) {
+	MaintenanceMode.Set(mode)
+}
+
+// Handler serves every published metric as JSON
+func Handler() http.Handler {
+	return expvar.Handler()
+}
@@ -0,0 +1,101 @@
+// Package ratelimit limits how often each caller can make a request, each key gets its own token bucket
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idle is how long a bucket is kept after its last use, a full bucket is the same as a new one
+const idle = 10 * time.Minute
+
+// Limiter keeps a token bucket per key
+type Limiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+type bucket struct {
+	*rate.Limiter
+	used time.Time
+}
+
+// New allows perMinute requests per key, with up to burst at once
+func New(perMinute, burst int) *Limiter {
+	return NewWithClock(perMinute, burst, time.Now)
+}
+
+// NewWithClock is New with the time coming from now, so a test can stop the buckets refilling
+func NewWithClock(perMinute, burst int, now func() time.Time) *Limiter {
+	return &Limiter{
+		limit:   perMinuteLimit(perMinute),
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     now,
+	}
+}
+
+// SetLimits changes the rate for every key, for a config reload
+func (l *Limiter) SetLimits(perMinute, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = perMinuteLimit(perMinute)
+	l.burst = burst
+	for _, b := range l.buckets {
+		b.SetLimit(l.limit)
+		b.SetBurst(l.burst)
+	}
+}
+
+// Allow takes a token for the key, when there isn't one it returns how long until there will be
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{Limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.used = now
+
+	r := b.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Minute
+	}
+	if wait := r.DelayFrom(now); wait > 0 {
+		// the token is only taken when it is used
+		r.CancelAt(now)
+		return false, wait
+	}
+
+	return true, 0
+}
+
+// sweep drops the buckets that haven't been used for a while, at most once per idle period
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idle {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.used) > idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func perMinuteLimit(perMinute int) rate.Limit {
+	return rate.Limit(float64(perMinute) / time.Minute.Seconds())
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewWithClock(1, 2, func() time.Time {
+		return now
+	})
+
+	for range 2 {
+		ok, _ := l.Allow("alice")
+		assert.True(t, ok)
+	}
+	ok, wait := l.Allow("alice")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, wait)
+
+	ok, _ = l.Allow("bob")
+	assert.True(t, ok, "each key has its own bucket")
+
+	t.Run("refills", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		ok, _ := l.Allow("alice")
+		assert.True(t, ok)
+		ok, _ = l.Allow("alice")
+		assert.False(t, ok, "only one token a minute")
+	})
+
+	l.SetLimits(1, 3)
+	for range 3 {
+		ok, _ = l.Allow("carol")
+		assert.True(t, ok)
+	}
+	ok, _ = l.Allow("carol")
+	assert.False(t, ok)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+)
+
+// adminOnly lets a request through when it has the admin token as a bearer token,
+// the admin routes don't exist at all when no token is configured
+func adminOnly(current func() *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := current().Admin.Token
+			if token == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type logLevelRequest struct {
+	Component string `json:"component"`
+	Level     string `json:"level"`
+	// TTL reverts the level once it has passed, e.g. 15m, it is kept until reset when empty
+	TTL string `json:"ttl"`
+}
+
+// logLevelRoutes reads and changes the log levels, every change is logged
+func logLevelRoutes(levels *logging.Levels, logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+			writeLevels(w, levels)
+		})
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			req := logLevelRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if req.Component == "" {
+				req.Component = logging.Global
+			}
+
+			level, err := logging.ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+					http.Error(w, "invalid ttl", http.StatusBadRequest)
+					return
+				}
+			}
+
+			if err := levels.Set(req.Component, level, ttl); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.Info("log level changed", "log_component", req.Component, "level", req.Level, "ttl", ttl.String())
+			writeLevels(w, levels)
+		})
+		r.Delete("/{component}", func(w http.ResponseWriter, r *http.Request) {
+			component := chi.URLParam(r, "component")
+			if err := levels.Reset(component); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			logger.Info("log level reset", "log_component", component)
+			writeLevels(w, levels)
+		})
+	}
+}
+
+func writeLevels(w http.ResponseWriter, levels *logging.Levels) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(levels.Snapshot())
+}
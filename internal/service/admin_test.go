@@ -0,0 +1,74 @@
+package service
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/health"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+	"github.com/todo-lists-app/todo-lists-api/internal/version"
+)
+
+const testAdminToken = "0123456789abcdef"
+
+func adminService(token string) *Service {
+	cfg := &config.Config{}
+	cfg.Admin.Token = token
+
+	return &Service{
+		Config:      cfg,
+		Readiness:   &health.Readiness{},
+		Version:     version.New("api", "dev", "unknown"),
+		Levels:      logging.NewLevels(slog.LevelInfo),
+		Maintenance: newMaintenance(config.Maintenance{Mode: config.MaintenanceOff, RetryAfter: time.Minute}),
+	}
+}
+
+func adminRequest(h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestAdminAuth(t *testing.T) {
+	h := adminService("").internalRouter()
+	assert.Equal(t, http.StatusNotFound, adminRequest(h, http.MethodGet, "/admin/log-level", testAdminToken, "").Code)
+
+	h = adminService(testAdminToken).internalRouter()
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(h, http.MethodGet, "/admin/log-level", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(h, http.MethodGet, "/admin/log-level", "wrong", "").Code)
+	assert.Equal(t, http.StatusOK, adminRequest(h, http.MethodGet, "/admin/log-level", testAdminToken, "").Code)
+}
+
+func TestAdminLogLevel(t *testing.T) {
+	s := adminService(testAdminToken)
+	h := s.internalRouter()
+
+	w := adminRequest(h, http.MethodPut, "/admin/log-level", testAdminToken, `{"component":"api.list","level":"debug","ttl":"10m"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"api.list":{"level":"debug","expires"`)
+	assert.Equal(t, slog.LevelDebug, s.Levels.Level(logging.ComponentList))
+
+	w = adminRequest(h, http.MethodPut, "/admin/log-level", testAdminToken, `{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, slog.LevelWarn, s.Levels.Level(logging.ComponentAuth))
+
+	w = adminRequest(h, http.MethodDelete, "/admin/log-level/api.list", testAdminToken, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, slog.LevelWarn, s.Levels.Level(logging.ComponentList))
+
+	for _, body := range []string{`{"level":"loud"}`, `{"component":"db","level":"debug"}`, `{"level":"debug","ttl":"soon"}`, `nope`} {
+		assert.Equal(t, http.StatusBadRequest, adminRequest(h, http.MethodPut, "/admin/log-level", testAdminToken, body).Code, body)
+	}
+}
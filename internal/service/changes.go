@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/metrics"
+)
+
+// problemCursorExpired identifies the problem document for a cursor that has to resync
+const problemCursorExpired = "urn:todo-list:problem:cursor-expired"
+
+// recordChange adds a change the caller made to their feed, the change has already happened so a
+// failure is logged and counted rather than failing the request
+func (s *Service) recordChange(ctx context.Context, resource, operation, revision string) {
+	c, _ := api.CallerFromContext(ctx)
+
+	s.record(ctx, changes.Record{
+		Subject:   c.Subject,
+		Resource:  resource,
+		Operation: operation,
+		Revision:  revision,
+		Device:    c.Device,
+	})
+}
+
+// recordSharedChange adds a change the caller made to a shared list to the feed of each member
+func (s *Service) recordSharedChange(ctx context.Context, members []string, id, operation, revision string) {
+	c, _ := api.CallerFromContext(ctx)
+
+	for _, member := range members {
+		s.record(ctx, changes.Record{
+			Subject:   member,
+			Resource:  changes.ResourceSharedList,
+			ID:        id,
+			Operation: operation,
+			Revision:  revision,
+			Device:    c.Device,
+		})
+	}
+}
+
+func (s *Service) record(ctx context.Context, rec changes.Record) {
+	// a client hanging up once its change is made shouldn't lose the record
+	if _, err := s.Changes.Record(context.WithoutCancel(ctx), rec); err != nil {
+		metrics.ChangeRecordFailures.Add(1)
+		s.changesLog.Error("record change", "resource", rec.Resource, "operation", rec.Operation, "error", err)
+	}
+}
+
+// listChanges serves the callers changes after since a page at a time, with wait set it is held open
+// until there is a change or wait runs out, which answers with an empty page and the same cursor
+func (s *Service) listChanges(w http.ResponseWriter, r *http.Request) {
+	cfg := s.current()
+	q := r.URL.Query()
+
+	limit := cfg.Changes.PageSize
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 {
+			http.Error(w, "limit has to be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(l, cfg.Changes.PageSize)
+	}
+
+	var wait time.Duration
+	if v := q.Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "wait has to be a duration such as 20s", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, cfg.Changes.MaxWait)
+	}
+	if wait > 0 {
+		// the servers write timeout would otherwise cut a long poll off
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + cfg.Server.WriteTimeout))
+	}
+
+	c := caller(r)
+	page, err := s.Changes.Wait(r.Context(), c.Subject, q.Get("since"), limit, wait, cfg.Changes.PollInterval)
+	if err != nil {
+		switch {
+		case errors.Is(err, changes.ErrBadCursor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, changes.ErrCursorExpired):
+			writeProblem(w, problem{
+				Type:   problemCursorExpired,
+				Title:  "Cursor expired",
+				Status: http.StatusGone,
+				Detail: "changes after this cursor are no longer kept, fetch everything again and start a new cursor",
+			})
+		case r.Context().Err() != nil:
+		default:
+			s.changesLog.Error("list changes", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(page)
+}
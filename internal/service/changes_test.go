@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+)
+
+func changesService(t *testing.T) (*Service, http.Handler) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Changes = config.Changes{PageSize: 2, MaxWait: time.Second, PollInterval: time.Minute, Retention: time.Minute}
+	s := &Service{
+		Config:     cfg,
+		Changes:    changes.NewFeed(changes.NewMemoryStore(cfg.Changes.Retention)),
+		changesLog: slog.New(slog.DiscardHandler),
+	}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := api.WithCaller(r.Context(), api.Caller{Subject: "alice", Device: r.Header.Get("X-Device-ID")})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Get("/changes", s.listChanges)
+
+	return s, r
+}
+
+func getChanges(t *testing.T, h http.Handler, query string) (*httptest.ResponseRecorder, changes.Page) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/changes"+query, nil))
+
+	page := changes.Page{}
+	if w.Code == http.StatusOK {
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	}
+	return w, page
+}
+
+func TestListChanges(t *testing.T) {
+	s, h := changesService(t)
+
+	ctx := api.WithCaller(context.Background(), api.Caller{Subject: "alice", Device: "laptop"})
+	s.recordChange(ctx, changes.ResourceList, changes.OperationCreate, `"a"`)
+	s.recordChange(ctx, changes.ResourceList, changes.OperationUpdate, `"b"`)
+	s.recordChange(ctx, changes.ResourceAccount, changes.OperationDelete, "")
+
+	w, first := getChanges(t, h, "?limit=5")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Len(t, first.Changes, 2, "limit is capped at the page size")
+	assert.True(t, first.More)
+	assert.Equal(t, changes.OperationCreate, first.Changes[0].Operation)
+	assert.Equal(t, "laptop", first.Changes[0].Device)
+
+	_, rest := getChanges(t, h, "?since="+first.Cursor)
+	assert.False(t, rest.More)
+	if assert.Len(t, rest.Changes, 1) {
+		assert.Equal(t, changes.ResourceAccount, rest.Changes[0].Resource)
+	}
+
+	for _, query := range []string{"?since=abc", "?limit=0", "?wait=soon"} {
+		w, _ := getChanges(t, h, query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestListChangesExpired(t *testing.T) {
+	s, h := changesService(t)
+	_, start := getChanges(t, h, "")
+
+	_, err := s.Changes.Record(context.Background(), changes.Record{Subject: "alice", At: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	s.recordChange(api.WithCaller(context.Background(), api.Caller{Subject: "alice"}), changes.ResourceList, changes.OperationUpdate, `"b"`)
+
+	w, _ := getChanges(t, h, "?since="+start.Cursor)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), problemCursorExpired)
+}
+
+func TestListChangesLongPoll(t *testing.T) {
+	s, h := changesService(t)
+	_, empty := getChanges(t, h, "")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.recordChange(api.WithCaller(context.Background(), api.Caller{Subject: "alice"}), changes.ResourceList, changes.OperationUpdate, `"a"`)
+	}()
+	w, page := getChanges(t, h, "?since="+empty.Cursor+"&wait=30s")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, page.Changes, 1)
+
+	start := time.Now()
+	_, page = getChanges(t, h, "?since="+page.Cursor+"&wait=50ms")
+	assert.Empty(t, page.Changes)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-chi/cors"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+)
+
+// corsPolicy applies the configured cross-origin policy, preflight requests are answered here,
+// the policy is swapped in one go when the config is reloaded
+type corsPolicy struct {
+	policy atomic.Pointer[cors.Cors]
+}
+
+func newCORSPolicy(c config.CORS) *corsPolicy {
+	p := &corsPolicy{}
+	p.policy.Store(buildCORS(c))
+
+	return p
+}
+
+func buildCORS(c config.CORS) *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	})
+}
+
+// Apply switches to the policy in a new config
+func (p *corsPolicy) Apply(cfg *config.Config) error {
+	p.policy.Store(buildCORS(cfg.CORS))
+	return nil
+}
+
+// Handler is the middleware
+func (p *corsPolicy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.policy.Load().Handler(next).ServeHTTP(w, r)
+	})
+}
+
+// originAllowed matches an origin against the policy the same way the cors middleware does, for the
+// requests browsers don't apply cors to, such as websocket upgrades
+func originAllowed(c config.CORS, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range c.AllowedOrigins {
+		o = strings.ToLower(o)
+		if o == "*" || o == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(o, "*"); ok {
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+)
+
+func corsRouter(p *corsPolicy) http.Handler {
+	r := chi.NewRouter()
+	r.Use(p.Handler)
+	r.Get("/list", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return r
+}
+
+func preflight(h http.Handler, origin, method string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, "/list", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	req.Header.Set("Access-Control-Request-Headers", "X-User-Subject")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := corsRouter(newCORSPolicy(config.CORS{
+		AllowedOrigins:   []string{"https://todo-list.app", "https://*.todo-list.app"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "X-User-Subject"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		allowed bool
+	}{
+		{"exact origin", "https://todo-list.app", "GET", true},
+		{"wildcard subdomain", "https://beta.todo-list.app", "POST", true},
+		{"other origin", "https://evil.example", "GET", false},
+		{"wrong scheme", "http://beta.todo-list.app", "GET", false},
+		{"suffix attack", "https://todo-list.app.evil.example", "GET", false},
+		{"method not allowed", "https://todo-list.app", "DELETE", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := preflight(h, tt.origin, tt.method)
+			got := w.Header().Get("Access-Control-Allow-Origin")
+			if !tt.allowed {
+				if got != "" {
+					t.Errorf("allow origin = %q, want none", got)
+				}
+				return
+			}
+
+			if got != tt.origin {
+				t.Errorf("allow origin = %q, want %q", got, tt.origin)
+			}
+			if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("credentials not allowed")
+			}
+			if w.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("max age = %q", w.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func TestCORSApply(t *testing.T) {
+	p := newCORSPolicy(config.CORS{
+		AllowedOrigins: []string{"https://todo-list.app"},
+		AllowedMethods: []string{"GET"},
+		AllowedHeaders: []string{"X-User-Subject"},
+	})
+	h := corsRouter(p)
+
+	if got := preflight(h, "https://new.todo-list.app", "GET").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("allowed %q before reload", got)
+	}
+
+	cfg := &config.Config{}
+	cfg.CORS = config.CORS{
+		AllowedOrigins: []string{"https://new.todo-list.app"},
+		AllowedMethods: []string{"GET"},
+		AllowedHeaders: []string{"X-User-Subject"},
+	}
+	if err := p.Apply(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := preflight(h, "https://new.todo-list.app", "GET").Header().Get("Access-Control-Allow-Origin"); got != "https://new.todo-list.app" {
+		t.Errorf("allow origin = %q after reload", got)
+	}
+	if got := preflight(h, "https://todo-list.app", "GET").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("old origin still allowed after reload")
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+)
+
+// problemDevice identifies the problem document for a request from a device that can't sync
+const problemDevice = "urn:todo-list:problem:device"
+
+// registerDevice is what a client sends to register, the push token is accepted but never sent back
+type registerDevice struct {
+	Name      string `json:"name"`
+	Platform  string `json:"platform"`
+	PublicKey string `json:"public_key"`
+	PushToken string `json:"push_token"`
+}
+
+// deviceRoutes lets a user register, list and revoke the devices they sync from
+func deviceRoutes(registry *devices.Registry, logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			list, err := registry.List(r.Context(), caller(r).Subject)
+			if err != nil {
+				logger.Error("list devices", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []devices.Device{}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(list)
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			req := registerDevice{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid device", err)
+				return
+			}
+
+			d, err := registry.Register(r.Context(), caller(r).Subject, devices.Device{
+				Name:      req.Name,
+				Platform:  req.Platform,
+				PublicKey: req.PublicKey,
+				PushToken: req.PushToken,
+			})
+			if err != nil {
+				switch {
+				case errors.Is(err, devices.ErrInvalid):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				case errors.Is(err, devices.ErrTooMany):
+					http.Error(w, err.Error(), http.StatusConflict)
+				default:
+					logger.Error("register device", "error", err)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+
+			w.Header().Set("Location", "/devices/"+d.ID)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(d)
+		})
+
+		r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if err := registry.Revoke(r.Context(), caller(r).Subject, chi.URLParam(r, "id")); err != nil {
+				if errors.Is(err, devices.ErrNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				logger.Error("revoke device", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// deviceRejected answers a request from a device that is unknown or revoked, or that didn't say which it is
+func deviceRejected(w http.ResponseWriter, err error) {
+	title, detail := "Unknown device", "this device can't sync, register it again to get a new id"
+	switch {
+	case errors.Is(err, devices.ErrRevoked):
+		title = "Device revoked"
+	case errors.Is(err, devices.ErrRequired):
+		title, detail = "Device required", "send the id of a registered device in X-Device-ID"
+	}
+
+	writeProblem(w, problem{
+		Type:   problemDevice,
+		Title:  title,
+		Status: http.StatusForbidden,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+)
+
+func devicesRouter(t *testing.T) *routeTest {
+	t.Helper()
+
+	rt := newRouteTest(t)
+	rt.router.Route("/devices", func(r chi.Router) {
+		r.Use(rt.auth)
+		deviceRoutes(rt.Devices, rt.logger)(r)
+	})
+	// stands in for the sync routes
+	rt.router.Route("/changes", func(r chi.Router) {
+		r.Use(rt.auth)
+		r.Use(requireDevice(rt.Devices, rt.logger))
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	return rt
+}
+
+func deviceRequest(method, path, device, body string) *http.Request {
+	req := userRequest(method, path, "alice", body)
+	if device != "" {
+		req.Header.Set("X-Device-ID", device)
+	}
+
+	return req
+}
+
+func TestDeviceRoutes(t *testing.T) {
+	rt := devicesRouter(t)
+
+	t.Run("sync without a device before one is registered", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodGet, "/changes/", "", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	d := devices.Device{}
+	t.Run("register", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodPost, "/devices/", "", `{"name":"Phone","platform":"ios","public_key":"a2V5","push_token":"secret-push"}`))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NotContains(t, w.Body.String(), "secret-push")
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&d))
+		assert.Equal(t, "/devices/"+d.ID, w.Header().Get("Location"))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodGet, "/devices/", d.ID, ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list []devices.Device
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		assert.Len(t, list, 1)
+	})
+
+	t.Run("register limits", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			body string
+			code int
+		}{
+			{"invalid", `{"name":"","platform":"ios"}`, http.StatusBadRequest},
+			{"malformed", `{`, http.StatusBadRequest},
+			{"second device", `{"name":"Laptop","platform":"linux"}`, http.StatusCreated},
+			{"over the limit", `{"name":"Tablet","platform":"android"}`, http.StatusConflict},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				w := rt.serve(deviceRequest(http.MethodPost, "/devices/", "", tt.body))
+				assert.Equal(t, tt.code, w.Code)
+			})
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodDelete, "/devices/"+d.ID, "", ""))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = rt.serve(deviceRequest(http.MethodDelete, "/devices/unknown", "", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		list, err := rt.Devices.List(context.Background(), "alice")
+		assert.NoError(t, err)
+		assert.True(t, list[0].Revoked())
+	})
+
+	t.Run("revoked device can't make requests", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodGet, "/devices/", d.ID, ""))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), problemDevice)
+		assert.Contains(t, w.Body.String(), "Device revoked")
+	})
+
+	t.Run("unknown device can't make requests", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodGet, "/devices/", "made-up", ""))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("revoked device can't sync", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodGet, "/changes/", d.ID, ""))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Device revoked")
+	})
+
+	t.Run("leaving the device off doesn't get around revoking it", func(t *testing.T) {
+		w := rt.serve(deviceRequest(http.MethodGet, "/changes/", "", ""))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), problemDevice)
+		assert.Contains(t, w.Body.String(), "Device required")
+	})
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	rpprof "runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultCPUProfile is how long an on-demand cpu profile runs when no duration is asked for
+const defaultCPUProfile = 30 * time.Second
+
+// diagnosticsRoutes mounts pprof, goroutine dumps, runtime metrics and on-demand cpu profiles,
+// they are only ever mounted behind adminOnly
+func diagnosticsRoutes(profileDir func() string, logger *slog.Logger) func(chi.Router) {
+	capture := &cpuCapture{logger: logger}
+
+	return func(r chi.Router) {
+		r.Get("/pprof/", pprof.Index)
+		r.Get("/pprof/cmdline", pprof.Cmdline)
+		r.Get("/pprof/profile", pprof.Profile)
+		r.Get("/pprof/symbol", pprof.Symbol)
+		r.Post("/pprof/symbol", pprof.Symbol)
+		r.Get("/pprof/trace", pprof.Trace)
+		// pprof.Index only finds named profiles under /debug/pprof/, so they are looked up here instead
+		r.Get("/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+			pprof.Handler(chi.URLParam(r, "profile")).ServeHTTP(w, r)
+		})
+
+		r.Get("/goroutines", goroutineDump(logger))
+		r.Get("/runtime-metrics", runtimeMetrics)
+		r.Post("/cpu-profile", func(w http.ResponseWriter, r *http.Request) {
+			capture.start(w, r, profileDir())
+		})
+	}
+}
+
+// goroutineDump writes the stack of every goroutine as text
+func goroutineDump(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := rpprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+			logger.Error("goroutine dump", "error", err)
+		}
+	}
+}
+
+// histogramSummary stands in for a runtime metrics histogram, the buckets are too large to be useful here
+type histogramSummary struct {
+	Count uint64 `json:"count"`
+}
+
+// runtimeMetrics writes a snapshot of every runtime/metrics value
+func runtimeMetrics(w http.ResponseWriter, _ *http.Request) {
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i, d := range descs {
+		samples[i].Name = d.Name
+	}
+	metrics.Read(samples)
+
+	out := make(map[string]interface{}, len(samples))
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			out[s.Name] = s.Value.Uint64()
+		case metrics.KindFloat64:
+			out[s.Name] = s.Value.Float64()
+		case metrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			var count uint64
+			for _, c := range h.Counts {
+				count += c
+			}
+			out[s.Name] = histogramSummary{Count: count}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// cpuCapture writes a cpu profile to a file in the background, only one runs at a time
+type cpuCapture struct {
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	running bool
+}
+
+type cpuCaptureResponse struct {
+	File     string `json:"file"`
+	Duration string `json:"duration"`
+}
+
+func (c *cpuCapture) start(w http.ResponseWriter, r *http.Request, dir string) {
+	duration := defaultCPUProfile
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil || secs < 1 || secs > 300 {
+			http.Error(w, "seconds has to be between 1 and 300", http.StatusBadRequest)
+			return
+		}
+		duration = time.Duration(secs) * time.Second
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		http.Error(w, "a cpu profile is already being captured", http.StatusConflict)
+		return
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("cpu-%s.pprof", time.Now().UTC().Format("20060102T150405Z")))
+	f, err := os.Create(path)
+	if err != nil {
+		c.logger.Error("create cpu profile", "error", err)
+		http.Error(w, "unable to create the profile file", http.StatusInternalServerError)
+		return
+	}
+	if err := rpprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		// pprof/profile is already running
+		http.Error(w, "a cpu profile is already being captured", http.StatusConflict)
+		return
+	}
+	c.running = true
+	c.logger.Info("cpu profile started", "file", path, "duration", duration.String())
+
+	time.AfterFunc(duration, func() {
+		rpprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			c.logger.Error("close cpu profile", "file", path, "error", err)
+		}
+		c.logger.Info("cpu profile written", "file", path)
+
+		c.mu.Lock()
+		c.running = false
+		c.mu.Unlock()
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(cpuCaptureResponse{
+		File:     path,
+		Duration: duration.String(),
+	})
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	h := adminService(testAdminToken).internalRouter()
+
+	for _, path := range []string{"/admin/debug/pprof/", "/admin/debug/pprof/heap", "/admin/debug/goroutines", "/admin/debug/runtime-metrics"} {
+		assert.Equal(t, http.StatusUnauthorized, adminRequest(h, http.MethodGet, path, "", "").Code, path)
+		assert.Equal(t, http.StatusOK, adminRequest(h, http.MethodGet, path, testAdminToken, "").Code, path)
+	}
+
+	w := adminRequest(h, http.MethodGet, "/admin/debug/goroutines", testAdminToken, "")
+	assert.Contains(t, w.Body.String(), "goroutine ")
+
+	snapshot := map[string]interface{}{}
+	w = adminRequest(h, http.MethodGet, "/admin/debug/runtime-metrics", testAdminToken, "")
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&snapshot))
+	assert.Contains(t, snapshot, "/sched/goroutines:goroutines")
+}
+
+func TestCPUProfileCapture(t *testing.T) {
+	s := adminService(testAdminToken)
+	s.Config.Admin.ProfileDir = t.TempDir()
+	h := s.internalRouter()
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(h, http.MethodPost, "/admin/debug/cpu-profile?seconds=0", testAdminToken, "").Code)
+
+	w := adminRequest(h, http.MethodPost, "/admin/debug/cpu-profile?seconds=1", testAdminToken, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	resp := cpuCaptureResponse{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "1s", resp.Duration)
+
+	assert.Equal(t, http.StatusConflict, adminRequest(h, http.MethodPost, "/admin/debug/cpu-profile?seconds=1", testAdminToken, "").Code)
+
+	assert.Eventually(t, func() bool {
+		info, err := os.Stat(resp.File)
+		return err == nil && info.Size() > 0 &&
+			adminRequest(h, http.MethodPost, "/admin/debug/cpu-profile?seconds=1", testAdminToken, "").Code == http.StatusAccepted
+	}, 5*time.Second, 100*time.Millisecond)
+	time.Sleep(1100 * time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+)
+
+// sseRetry is how long browsers wait before reconnecting a dropped stream, in milliseconds
+const sseRetry = 3000
+
+// listEvents streams changes to the callers list as server-sent events, a stream that reconnects
+// with Last-Event-ID is sent what it missed first, ids are only known to the pod that sent them
+func (s *Service) listEvents(w http.ResponseWriter, r *http.Request) {
+	c := caller(r)
+
+	var lastID uint64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		lastID, _ = strconv.ParseUint(id, 10, 64)
+	}
+
+	sub, missed, err := s.Events.Subscribe(c.Subject, lastID)
+	if err != nil {
+		if errors.Is(err, events.ErrTooManyStreams) {
+			w.Header().Set("Retry-After", strconv.Itoa(sseRetry/1000))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer sub.Close()
+
+	// the stream outlives the servers write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetry); err != nil {
+		return
+	}
+	for _, e := range missed {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(s.current().Events.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
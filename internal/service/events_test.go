@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+)
+
+func eventsServer(t *testing.T) (*Service, *httptest.Server) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Events = config.Events{MaxStreamsPerUser: 1, Heartbeat: 20 * time.Millisecond, Backlog: 10}
+	s := &Service{
+		Config: cfg,
+		Events: events.NewHub(cfg.Events.MaxStreamsPerUser, cfg.Events.Backlog, time.Minute),
+	}
+
+	r := chi.NewRouter()
+	r.Use(newCORSPolicy(config.CORS{
+		AllowedOrigins:   []string{"https://todo-list.app"},
+		AllowedMethods:   []string{"GET"},
+		AllowCredentials: true,
+	}).Handler)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := api.WithCaller(r.Context(), api.Caller{Subject: r.Header.Get("X-User-Subject")})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Get("/list/events", s.listEvents)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return s, srv
+}
+
+func openStream(t *testing.T, url, lastID string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url+"/list/events", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-User-Subject", "alice")
+	req.Header.Set("Origin", "https://todo-list.app")
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+// readUntil reads lines until one starts with prefix
+func readUntil(t *testing.T, r *bufio.Reader, prefix string) string {
+	t.Helper()
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream waiting for %q: %v", prefix, err)
+		}
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(line)
+		}
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	s, srv := eventsServer(t)
+
+	resp := openStream(t, srv.URL, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "https://todo-list.app", resp.Header.Get("Access-Control-Allow-Origin"))
+	body := bufio.NewReader(resp.Body)
+
+	readUntil(t, body, "retry:")
+	readUntil(t, body, ": heartbeat")
+
+	assert.Equal(t, http.StatusTooManyRequests, openStream(t, srv.URL, "").StatusCode)
+
+	e := s.Events.Publish("alice", events.ListUpdated, `"abc"`)
+	assert.Equal(t, "id: 1", readUntil(t, body, "id:"))
+	assert.Equal(t, "event: list.updated", readUntil(t, body, "event:"))
+	assert.Contains(t, readUntil(t, body, "data:"), `"etag":"\"abc\""`)
+	_ = resp.Body.Close()
+
+	assert.Eventually(t, func() bool {
+		return s.Events.Streams("alice") == 0
+	}, time.Second, 10*time.Millisecond)
+
+	s.Events.Publish("alice", events.ListDeleted, "")
+	resp = openStream(t, srv.URL, "1")
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body = bufio.NewReader(resp.Body)
+	assert.Equal(t, "id: 2", readUntil(t, body, "id:"), "missed events are sent after %d", e.ID)
+	assert.Equal(t, "event: list.deleted", readUntil(t, body, "event:"))
+}
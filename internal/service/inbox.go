@@ -0,0 +1,216 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"github.com/todo-lists-app/todo-lists-api/internal/inbox"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+)
+
+const (
+	// problemKeyChanged identifies the problem document for an item sealed to a key that isn't current
+	problemKeyChanged = "urn:todo-list:problem:key-changed"
+	// problemInboxFull identifies the problem document for an inbox that can't take more items
+	problemInboxFull = "urn:todo-list:problem:inbox-full"
+)
+
+// inboxContents is what the owner gets, the address is what they hand out to senders
+type inboxContents struct {
+	Address string       `json:"address"`
+	Items   []inbox.Item `json:"items"`
+}
+
+// dropRequest is what a sender posts, sealed is the item sealed to the recipients key as base64
+type dropRequest struct {
+	KeyID  string `json:"key_id"`
+	Sealed string `json:"sealed"`
+}
+
+// inboxRoutes lets the owner read their inbox, acknowledge items they have merged and change the address
+func (s *Service) inboxRoutes(logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			subject := caller(r).Subject
+			address, err := s.Inbox.Address(r.Context(), subject)
+			if err != nil {
+				logger.Error("inbox address", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			items, err := s.Inbox.Items(r.Context(), subject)
+			if err != nil {
+				logger.Error("inbox items", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if items == nil {
+				items = []inbox.Item{}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(inboxContents{Address: address, Items: items})
+		})
+
+		r.Post("/ack", func(w http.ResponseWriter, r *http.Request) {
+			req := struct {
+				IDs []string `json:"ids"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid ack", err)
+				return
+			}
+
+			removed, err := s.Inbox.Ack(r.Context(), caller(r).Subject, req.IDs)
+			if err != nil {
+				if errors.Is(err, inbox.ErrInvalid) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				logger.Error("ack items", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if removed > 0 {
+				s.recordChange(r.Context(), changes.ResourceInbox, changes.OperationDelete, "")
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(struct {
+				Removed int `json:"removed"`
+			}{removed})
+		})
+
+		// a new address stops items from anyone who only knows the old one
+		r.Post("/address", func(w http.ResponseWriter, r *http.Request) {
+			address, err := s.Inbox.Rotate(r.Context(), caller(r).Subject)
+			if err != nil {
+				logger.Error("rotate address", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(struct {
+				Address string `json:"address"`
+			}{address})
+		})
+	}
+}
+
+// dropRoutes lets anyone with an address fetch the key to seal items to and drop them in the inbox,
+// the key is looked up by address so senders never learn the owners subject
+func (s *Service) dropRoutes(logger *slog.Logger) func(chi.Router) {
+	// recipient finds the owner of the address and their current key, writing the response when there isn't one
+	recipient := func(w http.ResponseWriter, r *http.Request) (string, keys.Entry, bool) {
+		owner, err := s.Inbox.Owner(r.Context(), chi.URLParam(r, "address"))
+		if err != nil {
+			if errors.Is(err, inbox.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return "", keys.Entry{}, false
+			}
+			logger.Error("find inbox", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return "", keys.Entry{}, false
+		}
+
+		key, err := s.Keys.Current(r.Context(), owner)
+		if err != nil {
+			if errors.Is(err, keys.ErrNotFound) {
+				writeProblem(w, problem{
+					Type:   problemKeyRequired,
+					Title:  "Recipient has no public key",
+					Status: http.StatusConflict,
+					Detail: "items can't be sealed until the recipient publishes a key",
+				})
+				return "", keys.Entry{}, false
+			}
+			logger.Error("find key", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return "", keys.Entry{}, false
+		}
+
+		return owner, key, true
+	}
+
+	return func(r chi.Router) {
+		r.Get("/{address}/key", func(w http.ResponseWriter, r *http.Request) {
+			_, key, ok := recipient(w, r)
+			if !ok {
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(key.Key)
+		})
+
+		r.Post("/{address}", func(w http.ResponseWriter, r *http.Request) {
+			req := dropRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid item", err)
+				return
+			}
+
+			owner, key, ok := recipient(w, r)
+			if !ok {
+				return
+			}
+			if req.KeyID != key.Key.KeyID {
+				writeProblem(w, problem{
+					Type:   problemKeyChanged,
+					Title:  "Sealed to an old key",
+					Status: http.StatusConflict,
+					Detail: "fetch the key again from /inbox/{address}/key and seal the item to it",
+				})
+				return
+			}
+
+			item, err := s.Inbox.Deliver(r.Context(), owner, req.KeyID, req.Sealed)
+			if err != nil {
+				switch {
+				case errors.Is(err, inbox.ErrInvalid):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				case errors.Is(err, inbox.ErrTooLarge):
+					http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				case errors.Is(err, inbox.ErrFull):
+					writeProblem(w, problem{
+						Type:   problemInboxFull,
+						Title:  "Inbox is full",
+						Status: http.StatusConflict,
+						Detail: "the recipient has to take items out before more can be dropped in",
+					})
+				default:
+					logger.Error("deliver item", "error", err)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+
+			s.Events.Publish(owner, events.InboxItem, "")
+			s.record(r.Context(), changes.Record{
+				Subject:   owner,
+				Resource:  changes.ResourceInbox,
+				ID:        item.ID,
+				Operation: changes.OperationCreate,
+			})
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(struct {
+				ID string `json:"id"`
+			}{item.ID})
+		})
+	}
+}
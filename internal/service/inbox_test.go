@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/ratelimit"
+)
+
+func inboxRouter(t *testing.T) *routeTest {
+	t.Helper()
+
+	rt := newRouteTest(t)
+	rt.inboxSends = ratelimit.New(60, 4)
+	rt.router.Route("/inbox", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(rt.auth)
+			rt.inboxRoutes(rt.logger)(r)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(rateLimit(rt.inboxSends, byAddress(func() bool { return false })))
+			rt.dropRoutes(rt.logger)(r)
+		})
+	})
+
+	return rt
+}
+
+// senderRequest is a request from a sender without an account, from the address when it is set
+func senderRequest(method, path, address, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if address != "" {
+		req.RemoteAddr = address
+	}
+
+	return req
+}
+
+func TestInboxRoutes(t *testing.T) {
+	rt := inboxRouter(t)
+
+	contents := inboxContents{}
+	t.Run("empty inbox", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/inbox/", "alice", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&contents))
+		assert.NotEmpty(t, contents.Address)
+		assert.Empty(t, contents.Items)
+	})
+	drop := "/inbox/" + contents.Address
+
+	t.Run("no key to seal to", func(t *testing.T) {
+		w := rt.serve(senderRequest(http.MethodGet, drop+"/key", "", ""))
+		assert.Equal(t, http.StatusConflict, w.Code, "nothing can be sealed before alice publishes a key")
+		assert.Contains(t, w.Body.String(), problemKeyRequired)
+	})
+
+	k := signedKey(t, "alice", "alice-1")
+	_, _, err := rt.Keys.Publish(context.Background(), "alice", k)
+	assert.NoError(t, err)
+
+	t.Run("fetch the key", func(t *testing.T) {
+		w := rt.serve(senderRequest(http.MethodGet, drop+"/key", "", ""))
+		assert.Equal(t, http.StatusOK, w.Code, "senders don't need an account")
+		assert.Contains(t, w.Body.String(), k.PublicKey)
+		assert.NotContains(t, w.Body.String(), `"alice"`, "the subject isn't given away")
+	})
+
+	t.Run("sealed to an old key", func(t *testing.T) {
+		w := rt.serve(senderRequest(http.MethodPost, drop, "", `{"key_id":"alice-0","sealed":"c2VhbGVk"}`))
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), problemKeyChanged)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		w := rt.serve(senderRequest(http.MethodPost, drop, "", `{"key_id":"alice-1","sealed":"`+strings.Repeat("QUFB", 20)+`"}`))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("each sender is rate limited", func(t *testing.T) {
+		w := rt.serve(senderRequest(http.MethodPost, drop, "", `{"key_id":"alice-1","sealed":"c2VhbGVk"}`))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		rt.inboxSends.SetLimits(60, 10)
+		w := rt.serve(senderRequest(http.MethodPost, drop, "192.0.2.2:1234", `{"key_id":"alice-1","sealed":"c2VhbGVk"}`))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		w = rt.serve(userRequest(http.MethodGet, "/inbox/", "alice", ""))
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&contents))
+		if assert.Len(t, contents.Items, 1) {
+			assert.Equal(t, "c2VhbGVk", contents.Items[0].Sealed)
+			assert.Equal(t, "alice-1", contents.Items[0].KeyID)
+		}
+
+		page, err := rt.Changes.Since(context.Background(), "alice", "", 10)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, page.Changes) {
+			assert.Equal(t, changes.ResourceInbox, page.Changes[0].Resource)
+		}
+	})
+
+	t.Run("ack", func(t *testing.T) {
+		if !assert.NotEmpty(t, contents.Items) {
+			return
+		}
+		w := rt.serve(userRequest(http.MethodPost, "/inbox/ack", "alice", `{"ids":["`+contents.Items[0].ID+`"]}`))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"removed":1`)
+	})
+
+	t.Run("the old address stops working once rotated", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, "/inbox/address", "alice", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), contents.Address)
+
+		w = rt.serve(senderRequest(http.MethodGet, drop+"/key", "192.0.2.2:1234", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/keloran/go-healthcheck"
+	"github.com/keloran/go-probe"
+	"github.com/todo-lists-app/todo-lists-api/internal/health"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+	"github.com/todo-lists-app/todo-lists-api/internal/metrics"
+)
+
+// internalRouter serves the routes that are only for the cluster, probes, metrics and admin,
+// none of the public middleware runs in front of them
+func (s *Service) internalRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.Heartbeat("/ping"))
+	r.Get("/health", healthcheck.HTTP)
+	r.Get("/probe", probe.HTTP)
+	r.Get("/livez", health.Live)
+	r.Get("/readyz", s.Readiness.HTTP)
+	r.Get("/metrics", metrics.Handler().ServeHTTP)
+	r.Get("/version", s.Version.HTTP)
+
+	r.Route("/admin", func(r chi.Router) {
+		adminLog := s.logger(logging.ComponentAdmin)
+		r.Use(adminOnly(s.current))
+		r.Route("/log-level", logLevelRoutes(s.Levels, adminLog))
+		r.Route("/maintenance", maintenanceRoutes(s.Maintenance, adminLog))
+		r.Route("/debug", diagnosticsRoutes(func() string {
+			return s.current().Admin.ProfileDir
+		}, adminLog))
+	})
+
+	return r
+}
+
+// startInternal runs the internal listener, it keeps answering probes while the public server drains
+func (s *Service) startInternal(ctx context.Context, errChan chan error) {
+	cfg := s.Config
+	adminLog := s.logger(logging.ComponentAdmin)
+	adminLog.Info("starting internal http", "address", cfg.Server.InternalAddress)
+
+	srv := &http.Server{
+		Addr:              cfg.Server.InternalAddress,
+		Handler:           s.internalRouter(),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		// no write timeout, pprof profiles and traces stream for as long as they were asked to run
+		WriteTimeout:   0,
+		IdleTimeout:    cfg.Server.IdleTimeout,
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+	}
+
+	lis, err := listen(cfg.Server.InternalListener())
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		time.Sleep(s.current().Health.ShutdownDelay)
+
+		sctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownGrace)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			adminLog.Error("internal shutdown", "error", err)
+		}
+	}()
+
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errChan <- err
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/health"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+	"github.com/todo-lists-app/todo-lists-api/internal/version"
+)
+
+func TestInternalRouter(t *testing.T) {
+	s := &Service{
+		Readiness: &health.Readiness{},
+		Version:   version.New("api", "dev", "unknown"),
+		Levels:    logging.NewLevels(slog.LevelInfo),
+	}
+	h := s.internalRouter()
+
+	for _, path := range []string{"/ping", "/livez", "/readyz", "/metrics", "/version"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "user routes aren't on the internal listener")
+}
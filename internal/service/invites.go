@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"github.com/todo-lists-app/todo-lists-api/internal/invites"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+	"github.com/todo-lists-app/todo-lists-api/internal/mail"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+// problemKeyRequired identifies the problem document for accepting an invite before publishing a key
+const problemKeyRequired = "urn:todo-list:problem:key-required"
+
+// inviteRequest is what the owner sends to invite someone to a shared list
+type inviteRequest struct {
+	Email  string `json:"email"`
+	Access string `json:"access"`
+}
+
+// inviteRoutes lets the owner of a shared list invite people to it, list the invites and revoke them
+func (s *Service) inviteRoutes(logger *slog.Logger) func(chi.Router) {
+	failed := func(w http.ResponseWriter, op string, err error) {
+		switch {
+		case errors.Is(err, invites.ErrInvalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, invites.ErrNotFound), errors.Is(err, sharing.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, sharing.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, invites.ErrTooMany):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			logger.Error(op, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	return func(r chi.Router) {
+		r.With(rateLimit(s.inviteSends, bySubject)).Post("/{id}/invites", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+			id := chi.URLParam(r, "id")
+			req := inviteRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid invite", err)
+				return
+			}
+
+			if err := s.Sharing.RequireOwner(r.Context(), c.Subject, id); err != nil {
+				failed(w, "invite owner", err)
+				return
+			}
+			invite, token, err := s.Invites.Create(r.Context(), id, c.Subject, req.Email, req.Access)
+			if err != nil {
+				failed(w, "create invite", err)
+				return
+			}
+
+			if err := s.Mail.Send(r.Context(), inviteEmail(s.current().Invites, invite, token)); err != nil {
+				// an invite nobody was told about can't be accepted, so it shouldn't count as pending
+				logger.Error("send invite", "error", err)
+				if err := s.Invites.Revoke(r.Context(), id, invite.ID); err != nil {
+					logger.Error("revoke unsent invite", "error", err)
+				}
+				unavailable(w, nil)
+				return
+			}
+
+			w.Header().Set("Location", "/lists/"+id+"/invites/"+invite.ID)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(invite)
+		})
+
+		r.Get("/{id}/invites", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			if err := s.Sharing.RequireOwner(r.Context(), caller(r).Subject, id); err != nil {
+				failed(w, "invite owner", err)
+				return
+			}
+
+			list, err := s.Invites.ForList(r.Context(), id)
+			if err != nil {
+				failed(w, "list invites", err)
+				return
+			}
+			if list == nil {
+				list = []invites.Invite{}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(list)
+		})
+
+		r.Delete("/{id}/invites/{invite}", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			if err := s.Sharing.RequireOwner(r.Context(), caller(r).Subject, id); err != nil {
+				failed(w, "invite owner", err)
+				return
+			}
+
+			if err := s.Invites.Revoke(r.Context(), id, chi.URLParam(r, "invite")); err != nil {
+				failed(w, "revoke invite", err)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// acceptInvite binds the caller to the list with a pending grant and tells the owner, who wraps the
+// lists key for the callers published key. The caller has to publish a key first so the owner has one
+func (s *Service) acceptInvite(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := caller(r)
+
+		if _, err := s.Keys.Current(r.Context(), c.Subject); err != nil {
+			if errors.Is(err, keys.ErrNotFound) {
+				writeProblem(w, problem{
+					Type:   problemKeyRequired,
+					Title:  "Public key required",
+					Status: http.StatusConflict,
+					Detail: "publish a key with PUT /account/keys so the list can be shared with you, then accept again",
+				})
+				return
+			}
+			logger.Error("find key", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		invite, err := s.Invites.Accept(r.Context(), chi.URLParam(r, "token"), c.Subject)
+		if err != nil {
+			switch {
+			case errors.Is(err, invites.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, invites.ErrExpired):
+				http.Error(w, err.Error(), http.StatusGone)
+			case errors.Is(err, invites.ErrUsed):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case errors.Is(err, invites.ErrInvalid):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error("accept invite", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		grant, err := s.Sharing.Join(r.Context(), invite.List, c.Subject, invite.Access, invite.Owner)
+		if err != nil {
+			if err := s.Invites.Reopen(r.Context(), invite.ID); err != nil {
+				logger.Error("reopen invite", "error", err)
+			}
+			switch {
+			case errors.Is(err, sharing.ErrNotFound):
+				// the list was deleted after the invite was sent
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, sharing.ErrInvalid):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, sharing.ErrTooManyGrants):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				logger.Error("join list", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		s.Events.Publish(invite.Owner, events.InviteAccepted, "")
+		s.record(r.Context(), changes.Record{
+			Subject:   invite.Owner,
+			Resource:  changes.ResourceInvite,
+			ID:        invite.ID,
+			Operation: changes.OperationAccept,
+		})
+		s.recordSharedChange(r.Context(), []string{c.Subject}, invite.List, changes.OperationShare, "")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(grant)
+	}
+}
+
+// inviteEmail is the email with the link to accept the invite
+func inviteEmail(cfg config.Invites, invite invites.Invite, token string) mail.Message {
+	link := strings.Replace(cfg.AcceptURL, config.InviteToken, token, 1)
+
+	return mail.Message{
+		To:      invite.Email,
+		Subject: "You've been invited to a shared to-do list",
+		Body: fmt.Sprintf("Someone has shared a to-do list with you.\n\n"+
+			"Open this link to accept, it can only be used once and expires on %s:\n\n%s\n\n"+
+			"If you weren't expecting this you can ignore it.\n",
+			invite.ExpiresAt.Format("2 January 2006 at 15:04 MST"), link),
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/invites"
+	"github.com/todo-lists-app/todo-lists-api/internal/mail"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+// outbox keeps the emails it is asked to send, failing them all when err is set
+type outbox struct {
+	sent []mail.Message
+	err  error
+}
+
+func (o *outbox) Send(_ context.Context, m mail.Message) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.sent = append(o.sent, m)
+	return nil
+}
+
+func invitesRouter(t *testing.T) *routeTest {
+	t.Helper()
+
+	rt := newRouteTest(t)
+	rt.router.Route("/lists", func(r chi.Router) {
+		r.Use(rt.auth)
+		rt.sharingRoutes(rt.logger)(r)
+		rt.inviteRoutes(rt.logger)(r)
+	})
+	rt.router.Route("/invites", func(r chi.Router) {
+		r.Use(rt.auth)
+		r.Post("/{token}/accept", rt.acceptInvite(rt.logger))
+	})
+
+	return rt
+}
+
+func TestInviteRoutes(t *testing.T) {
+	rt := invitesRouter(t)
+	created, path := createShared(t, rt, "YWxpY2U=")
+
+	t.Run("only the owner can invite", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, path+"/invites", "mallory", `{"email":"bob@example.com","access":"write"}`))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	invite := invites.Invite{}
+	var accept string
+	t.Run("invite", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, path+"/invites", "alice", `{"email":"bob@example.com","access":"write"}`))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&invite))
+		if !assert.Len(t, rt.outbox.sent, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, "bob@example.com", rt.outbox.sent[0].To)
+		token := regexp.MustCompile(`/invites/([A-Z2-7]+)`).FindStringSubmatch(rt.outbox.sent[0].Body)[1]
+		assert.NotContains(t, w.Body.String(), token, "the token only goes in the email")
+		accept = "/invites/" + token + "/accept"
+	})
+	if accept == "" {
+		return
+	}
+
+	t.Run("accept without a key", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, accept, "bob", ""))
+		assert.Equal(t, http.StatusConflict, w.Code, "bob has to publish a key first")
+		assert.Contains(t, w.Body.String(), problemKeyRequired)
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		_, _, err := rt.Keys.Publish(context.Background(), "bob", signedKey(t, "bob", "bob"))
+		assert.NoError(t, err)
+
+		w := rt.serve(userRequest(http.MethodPost, accept, "bob", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		grant := sharing.Grant{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&grant))
+		assert.True(t, grant.Pending)
+		assert.Equal(t, sharing.AccessWrite, grant.Access)
+	})
+
+	t.Run("an invite is single use", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, accept, "carol", ""))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("the owner sees the acceptance", func(t *testing.T) {
+		page, err := rt.Changes.Since(context.Background(), "alice", "", 10)
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, page.Changes) {
+			last := page.Changes[len(page.Changes)-1]
+			assert.Equal(t, changes.ResourceInvite, last.Resource)
+			assert.Equal(t, invite.ID, last.ID)
+		}
+	})
+
+	t.Run("the owner wraps the key for the new member", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, path+"/grants/bob", "alice", `{"access":"write","envelope":"Ym9i"}`))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, path+"/invites", "alice", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list []invites.Invite
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, invites.StatusAccepted, list[0].Status)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, path+"/invites/"+invite.ID, "alice", ""))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("an invite that wasn't sent isn't kept", func(t *testing.T) {
+		rt.outbox.err = errors.New("smtp is down")
+		w := rt.serve(userRequest(http.MethodPost, path+"/invites", "alice", `{"email":"carol@example.com","access":"read"}`))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		list, err := rt.Invites.ForList(context.Background(), created.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
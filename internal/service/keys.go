@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+	"github.com/todo-lists-app/todo-lists-api/internal/mail"
+)
+
+// emailRequest is what a user sends to be found by an email address
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+// verifyRequest sends back the token from the verification email
+type verifyRequest struct {
+	Token string `json:"token"`
+}
+
+// publishKeyRoutes lets a user publish their identity public key
+func publishKeyRoutes(directory *keys.Directory, logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			k := keys.Key{}
+			if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+				badBody(w, "invalid key", err)
+				return
+			}
+
+			e, created, err := directory.Publish(r.Context(), caller(r).Subject, k)
+			if err != nil {
+				if errors.Is(err, keys.ErrInvalid) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				logger.Error("publish key", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			status := http.StatusOK
+			if created {
+				status = http.StatusCreated
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(e)
+		})
+	}
+}
+
+// emailRoutes lets a user verify an email address others can find their key by. Verification emails
+// count against the invite limit, both send mail to an address the user chooses
+func (s *Service) emailRoutes(logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.With(rateLimit(s.inviteSends, bySubject)).Put("/email", func(w http.ResponseWriter, r *http.Request) {
+			req := emailRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid email", err)
+				return
+			}
+
+			claimed, token, err := s.Emails.Claim(r.Context(), caller(r).Subject, req.Email)
+			if err != nil {
+				if errors.Is(err, keys.ErrInvalid) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				logger.Error("claim email", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if err := s.Mail.Send(r.Context(), verifyEmail(s.current().Keys, claimed, token)); err != nil {
+				logger.Error("send email verification", "error", err)
+				unavailable(w, nil)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(claimed)
+		})
+
+		r.Post("/email/verify", func(w http.ResponseWriter, r *http.Request) {
+			req := verifyRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid token", err)
+				return
+			}
+
+			verified, err := s.Emails.Verify(r.Context(), caller(r).Subject, req.Token)
+			if err != nil {
+				switch {
+				case errors.Is(err, keys.ErrEmailNotFound):
+					w.WriteHeader(http.StatusNotFound)
+				case errors.Is(err, keys.ErrEmailExpired):
+					http.Error(w, err.Error(), http.StatusGone)
+				default:
+					logger.Error("verify email", "error", err)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(verified)
+		})
+	}
+}
+
+// verifyEmail is the email with the link that verifies the address
+func verifyEmail(cfg config.Keys, claimed keys.Email, token string) mail.Message {
+	link := strings.Replace(cfg.EmailVerifyURL, config.EmailToken, token, 1)
+
+	return mail.Message{
+		To:      claimed.Pending,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Open this link to let people you share to-do lists with find you by this address, "+
+			"it expires on %s:\n\n%s\n\n"+
+			"If you didn't ask for this you can ignore it.\n",
+			claimed.ExpiresAt.Format("2 January 2006 at 15:04 MST"), link),
+	}
+}
+
+// lookupKeyRoutes lets a user find another users key and its history, by their subject or the email they
+// have verified. Lookups are rate limited by the router, an unknown email looks the same as a user
+// without a key
+func lookupKeyRoutes(directory *keys.Directory, emails *keys.Emails, logger *slog.Logger) func(chi.Router) {
+	lookup := func(w http.ResponseWriter, r *http.Request, op string, find func() (any, error)) {
+		found, err := find()
+		if err != nil {
+			if errors.Is(err, keys.ErrNotFound) || errors.Is(err, keys.ErrEmailNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, keys.ErrInvalid) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.Error(op, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(found)
+	}
+
+	return func(r chi.Router) {
+		r.Get("/keys", func(w http.ResponseWriter, r *http.Request) {
+			lookup(w, r, "find key by email", func() (any, error) {
+				subject, err := emails.Subject(r.Context(), r.URL.Query().Get("email"))
+				if err != nil {
+					return nil, err
+				}
+				return directory.Current(r.Context(), subject)
+			})
+		})
+
+		r.Get("/{subject}/keys", func(w http.ResponseWriter, r *http.Request) {
+			lookup(w, r, "find key", func() (any, error) {
+				return directory.Current(r.Context(), chi.URLParam(r, "subject"))
+			})
+		})
+
+		r.Get("/{subject}/keys/history", func(w http.ResponseWriter, r *http.Request) {
+			lookup(w, r, "key history", func() (any, error) {
+				return directory.History(r.Context(), chi.URLParam(r, "subject"))
+			})
+		})
+	}
+}
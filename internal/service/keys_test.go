@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+	"github.com/todo-lists-app/todo-lists-api/internal/ratelimit"
+)
+
+func keysRouter(t *testing.T, lookups *ratelimit.Limiter) *routeTest {
+	t.Helper()
+
+	rt := newRouteTest(t)
+	rt.keyLookups = lookups
+	rt.router.Route("/account/keys", func(r chi.Router) {
+		r.Use(rt.auth)
+		publishKeyRoutes(rt.Keys, rt.logger)(r)
+		rt.emailRoutes(rt.logger)(r)
+	})
+	rt.router.Route("/users", func(r chi.Router) {
+		r.Use(rt.auth)
+		r.Use(rateLimit(rt.keyLookups, bySubject))
+		lookupKeyRoutes(rt.Keys, rt.Emails, rt.logger)(r)
+	})
+	rt.router.Route("/account", func(r chi.Router) {
+		r.Delete("/", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+	})
+
+	return rt
+}
+
+// signedKey is a key for the subject signed with its own private key
+func signedKey(t *testing.T, subject, id string) keys.Key {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	k := keys.Key{KeyID: id, Algorithm: keys.AlgorithmEd25519, PublicKey: base64.StdEncoding.EncodeToString(pub)}
+	k.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, keys.SignedMessage(subject, k)))
+
+	return k
+}
+
+func TestKeyRoutes(t *testing.T) {
+	rt := keysRouter(t, ratelimit.New(60, 2))
+	k := signedKey(t, "alice", "one")
+	body, err := json.Marshal(k)
+	assert.NoError(t, err)
+
+	t.Run("publish", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, "/account/keys/", "alice", string(body)))
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("a key signed for someone else", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, "/account/keys/", "mallory", string(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("the rest of /account still routes", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, "/account/", "alice", ""))
+		assert.Equal(t, http.StatusTeapot, w.Code)
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/alice/keys", "bob", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		e := keys.Entry{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&e))
+		assert.Equal(t, k, *e.Key)
+	})
+
+	t.Run("history of a user without keys", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/carol/keys/history", "bob", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("lookups are rate limited", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/alice/keys/history", "bob", ""))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "bob has used up the burst")
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("each user has their own limit", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/alice/keys/history", "carol", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestKeyEmailRoutes(t *testing.T) {
+	rt := keysRouter(t, ratelimit.New(60, 3))
+	k := signedKey(t, "alice", "one")
+	body, err := json.Marshal(k)
+	assert.NoError(t, err)
+	w := rt.serve(userRequest(http.MethodPut, "/account/keys/", "alice", string(body)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	t.Run("not a plain address", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, "/account/keys/email", "alice", `{"email":"Alice <alice@example.com>"}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	var verify string
+	t.Run("claim", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, "/account/keys/email", "alice", `{"email":"alice@example.com"}`))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		if !assert.Len(t, rt.outbox.sent, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, "alice@example.com", rt.outbox.sent[0].To)
+		token := regexp.MustCompile(`/account/email/([A-Z2-7]+)`).FindStringSubmatch(rt.outbox.sent[0].Body)[1]
+		assert.NotContains(t, w.Body.String(), token, "the token only goes in the email")
+		verify = `{"token":"` + token + `"}`
+	})
+	if verify == "" {
+		return
+	}
+
+	t.Run("not found until verified", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/keys?email=alice@example.com", "bob", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("only the claimant can verify", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, "/account/keys/email/verify", "mallory", verify))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, "/account/keys/email/verify", "alice", verify))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"address":"alice@example.com"`)
+	})
+
+	t.Run("lookup by email", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/keys?email=Alice@example.com", "bob", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		e := keys.Entry{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&e))
+		assert.Equal(t, k, *e.Key)
+	})
+
+	t.Run("an email lookup without an email", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/keys", "bob", ""))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("email lookups share the lookup limit", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/users/keys?email=carol@example.com", "bob", ""))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "bob has used up the burst")
+	})
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	errInvalidList      = errors.New("data and iv are required")
+	errRevisionMismatch = errors.New("the list has changed since the expected revision")
+	errNoSiblings       = errors.New("resolves has to name the conflicts that were merged")
+)
+
+// listWrite is the outcome of an update, either the list was written or, when the update was made
+// from a stale revision, it was kept as a sibling and the list was left alone
+type listWrite struct {
+	list    *api.StoredList
+	sibling *conflicts.Sibling
+}
+
+// updateList is the write path shared by PUT /list and the websocket, expected is the ETag the client
+// last saw and is skipped when empty or *. An update from a stale revision is kept as a sibling for the
+// client to merge. The todo service has no revision of its own so the check and the write can't be
+// atomic, they are serialised per subject on this instance but writes handled by different instances
+// can still race
+func (s *Service) updateList(ctx context.Context, subject, data, iv, expected string) (listWrite, error) {
+	if data == "" || iv == "" {
+		return listWrite{}, errInvalidList
+	}
+	unlock := s.listWrites.Lock(subject)
+	defer unlock()
+
+	l, err := api.NewListService(ctx, *s.current(), subject).WithConnections(s.Connections).GetClient()
+	if err != nil {
+		return listWrite{}, err
+	}
+
+	if expected != "" && expected != "*" {
+		current, err := l.GetList()
+		if err != nil {
+			return listWrite{}, err
+		}
+
+		next := &api.StoredList{UserID: subject, Data: data, IV: iv}
+		switch current.ETag() {
+		case expected:
+		case next.ETag():
+			// a retry of a write that already landed
+			return listWrite{list: current}, nil
+		default:
+			sibling, err := s.addSibling(ctx, subject, expected, next)
+			if err != nil {
+				return listWrite{}, err
+			}
+			return listWrite{sibling: &sibling}, nil
+		}
+	}
+
+	updated, err := s.writeList(ctx, l, subject, data, iv, changes.OperationUpdate)
+	if err != nil {
+		return listWrite{}, err
+	}
+
+	return listWrite{list: updated}, nil
+}
+
+// createList is the write path of POST /list, it takes the same lock as updates so a create can't land
+// between the revision check and the write of an update
+func (s *Service) createList(ctx context.Context, subject, data, iv string) (*api.StoredList, error) {
+	unlock := s.listWrites.Lock(subject)
+	defer unlock()
+
+	l, err := api.NewListService(ctx, *s.current(), subject).WithConnections(s.Connections).GetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := l.CreateList(&api.StoredList{
+		UserID: subject,
+		Data:   data,
+		IV:     iv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Events.Publish(subject, events.ListUpdated, stored.ETag())
+	s.recordChange(ctx, changes.ResourceList, changes.OperationCreate, stored.ETag())
+
+	return stored, nil
+}
+
+// deleteList removes the list and the siblings kept for it, under the same lock as the other writes
+func (s *Service) deleteList(ctx context.Context, subject string, logger *slog.Logger) error {
+	unlock := s.listWrites.Lock(subject)
+	defer unlock()
+
+	l, err := api.NewListService(ctx, *s.current(), subject).WithConnections(s.Connections).GetClient()
+	if err != nil {
+		return err
+	}
+	if _, err := l.DeleteList(subject); err != nil {
+		return err
+	}
+	s.Events.Publish(subject, events.ListDeleted, "")
+	s.recordChange(ctx, changes.ResourceList, changes.OperationDelete, "")
+	if err := s.Conflicts.Forget(ctx, subject); err != nil {
+		// the list is already gone, the siblings are offered until they are resolved or the account is deleted
+		logger.Error("forget conflicts", "error", err)
+	}
+
+	return nil
+}
+
+// resolveList writes the version a client merged from the list at base and the siblings it names, then
+// drops those siblings, siblings that arrived after the client fetched the list are left for the next merge
+func (s *Service) resolveList(ctx context.Context, subject, data, iv, base string, resolves []string, logger *slog.Logger) (*api.StoredList, error) {
+	if data == "" || iv == "" {
+		return nil, errInvalidList
+	}
+	if len(resolves) == 0 {
+		return nil, errNoSiblings
+	}
+	unlock := s.listWrites.Lock(subject)
+	defer unlock()
+	if err := s.Conflicts.Check(ctx, subject, resolves); err != nil {
+		return nil, err
+	}
+
+	l, err := api.NewListService(ctx, *s.current(), subject).WithConnections(s.Connections).GetClient()
+	if err != nil {
+		return nil, err
+	}
+	current, err := l.GetList()
+	if err != nil {
+		return nil, err
+	}
+	if current.ETag() != base {
+		return nil, errRevisionMismatch
+	}
+
+	updated, err := s.writeList(ctx, l, subject, data, iv, changes.OperationResolve)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Conflicts.Resolve(ctx, subject, resolves); err != nil {
+		// the merge is already written, the siblings will be offered again and can be resolved again
+		logger.Error("resolve siblings", "error", err)
+	}
+
+	return updated, nil
+}
+
+func (s *Service) writeList(ctx context.Context, l *api.List, subject, data, iv, operation string) (*api.StoredList, error) {
+	updated, err := l.UpdateList(&api.StoredList{
+		UserID: subject,
+		Data:   data,
+		IV:     iv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Events.Publish(subject, events.ListUpdated, updated.ETag())
+	s.recordChange(ctx, changes.ResourceList, operation, updated.ETag())
+
+	return updated, nil
+}
+
+func (s *Service) addSibling(ctx context.Context, subject, base string, next *api.StoredList) (conflicts.Sibling, error) {
+	c, _ := api.CallerFromContext(ctx)
+	sibling, err := s.Conflicts.Add(ctx, conflicts.Sibling{
+		Subject:  subject,
+		Data:     next.Data,
+		IV:       next.IV,
+		Base:     base,
+		Revision: next.ETag(),
+		Device:   c.Device,
+	})
+	if err != nil {
+		return sibling, err
+	}
+	s.Events.Publish(subject, events.ListConflict, sibling.Revision)
+	s.recordChange(ctx, changes.ResourceList, changes.OperationConflict, sibling.Revision)
+
+	return sibling, nil
+}
+
+// listRoutes serves the callers list, conflicts made from stale revisions and the change events for it
+func (s *Service) listRoutes(logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/events", s.listEvents)
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+
+			l, err := api.NewListService(r.Context(), *s.current(), c.Subject).WithConnections(s.Connections).GetClient()
+			if err != nil {
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			list, err := l.GetList()
+			if err != nil {
+				if errors.Is(err, mongo.ErrNoDocuments) {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				if s.breakerOpen(w, "todo") {
+					return
+				}
+
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if list == nil {
+				if err := NoLists(w); err != nil {
+					logger.Error("list request", "method", r.Method, "error", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				return
+			}
+
+			siblings, err := s.Conflicts.List(r.Context(), c.Subject)
+			if err != nil {
+				// the siblings are still kept, they are offered again on the next read
+				logger.Error("list conflicts", "error", err)
+			}
+
+			if err := ListExists(w, list, siblings); err != nil {
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		})
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+
+			id := injectData{}
+			if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+				badBody(w, "invalid list", err)
+				return
+			}
+
+			stored, err := s.createList(r.Context(), c.Subject, id.Data, id.IV)
+			if err != nil {
+				if s.breakerOpen(w, "todo") {
+					return
+				}
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if err := ListExists(w, stored, nil); err != nil {
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		})
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+
+			id := injectData{}
+			if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+				badBody(w, "invalid list", err)
+				return
+			}
+
+			written, err := s.updateList(r.Context(), c.Subject, id.Data, id.IV, r.Header.Get("If-Match"))
+			if err != nil {
+				switch {
+				case errors.Is(err, errInvalidList):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				case errors.Is(err, conflicts.ErrTooMany):
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				case s.breakerOpen(w, "todo"):
+					return
+				}
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			if written.sibling != nil {
+				// the list was left alone, the client merges the sibling once it has fetched the list again
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusAccepted)
+				_ = json.NewEncoder(w).Encode(siblingAccepted{
+					Sibling:  written.sibling.ID,
+					Revision: written.sibling.Revision,
+				})
+				return
+			}
+
+			w.Header().Set("ETag", written.list.ETag())
+			w.WriteHeader(http.StatusOK)
+		})
+		r.Post("/resolve", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+
+			req := resolveRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid resolve request", err)
+				return
+			}
+
+			updated, err := s.resolveList(r.Context(), c.Subject, req.Data, req.IV, req.Base, req.Resolves, logger)
+			if err != nil {
+				switch {
+				case errors.Is(err, errInvalidList), errors.Is(err, errNoSiblings):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				case errors.Is(err, conflicts.ErrUnknownSibling):
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				case errors.Is(err, errRevisionMismatch):
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				case s.breakerOpen(w, "todo"):
+					return
+				}
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("ETag", updated.ETag())
+			w.WriteHeader(http.StatusOK)
+		})
+		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+
+			if err := s.deleteList(r.Context(), c.Subject, logger); err != nil {
+				if s.breakerOpen(w, "todo") {
+					return
+				}
+				logger.Error("list request", "method", r.Method, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+)
+
+func TestListConflicts(t *testing.T) {
+	s, _, _ := socketServer(t)
+	ctx := api.WithCaller(context.Background(), api.Caller{Subject: "alice", Device: "phone"})
+
+	base, err := s.updateList(ctx, "alice", "base", "iv", "")
+	assert.NoError(t, err)
+	baseRev := base.list.ETag()
+
+	// the laptop writes first, then the phone writes from the same base while offline
+	laptop, err := s.updateList(ctx, "alice", "laptop", "iv", baseRev)
+	assert.NoError(t, err)
+	assert.NotNil(t, laptop.list)
+
+	phone, err := s.updateList(ctx, "alice", "phone", "iv", baseRev)
+	assert.NoError(t, err)
+	assert.Nil(t, phone.list, "the list is left alone")
+	if assert.NotNil(t, phone.sibling) {
+		assert.Equal(t, baseRev, phone.sibling.Base)
+		assert.Equal(t, "phone", phone.sibling.Device)
+	}
+
+	retried, err := s.updateList(ctx, "alice", "laptop", "iv", baseRev)
+	assert.NoError(t, err)
+	assert.Equal(t, laptop.list.ETag(), retried.list.ETag(), "a retried write isn't a conflict")
+
+	siblings, err := s.Conflicts.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, siblings, 1)
+
+	logger := slog.New(slog.DiscardHandler)
+	t.Run("resolve", func(t *testing.T) {
+		_, err := s.resolveList(ctx, "alice", "merged", "iv", baseRev, []string{phone.sibling.ID}, logger)
+		assert.ErrorIs(t, err, errRevisionMismatch, "the merge has to start from the current list")
+
+		_, err = s.resolveList(ctx, "alice", "merged", "iv", laptop.list.ETag(), nil, logger)
+		assert.ErrorIs(t, err, errNoSiblings)
+
+		_, err = s.resolveList(ctx, "alice", "merged", "iv", laptop.list.ETag(), []string{"unknown"}, logger)
+		assert.ErrorIs(t, err, conflicts.ErrUnknownSibling)
+
+		merged, err := s.resolveList(ctx, "alice", "merged", "iv", laptop.list.ETag(), []string{phone.sibling.ID}, logger)
+		assert.NoError(t, err)
+		assert.Equal(t, (&api.StoredList{Data: "merged", IV: "iv"}).ETag(), merged.ETag())
+
+		siblings, err := s.Conflicts.List(ctx, "alice")
+		assert.NoError(t, err)
+		assert.Empty(t, siblings)
+	})
+
+	page, err := s.Changes.Since(ctx, "alice", "", 10)
+	assert.NoError(t, err)
+	var ops []string
+	for _, c := range page.Changes {
+		ops = append(ops, c.Operation)
+	}
+	assert.Equal(t, []string{changes.OperationUpdate, changes.OperationUpdate, changes.OperationConflict, changes.OperationResolve}, ops)
+}
+
+func TestListRoutesBody(t *testing.T) {
+	s, _, _ := socketServer(t)
+	s.Config.Local.Development = true
+	logger := slog.New(slog.DiscardHandler)
+
+	r := chi.NewRouter()
+	r.Use(limitBody(32))
+	r.Route("/list", func(r chi.Router) {
+		r.Use(authenticate(s.current, s.Connections, nil, logger))
+		s.listRoutes(logger)(r)
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"post too large", http.MethodPost, "/list/", `{"data":"` + strings.Repeat("x", 64) + `","iv":"iv"}`, false, http.StatusRequestEntityTooLarge},
+		{"post chunked too large", http.MethodPost, "/list/", `{"data":"` + strings.Repeat("x", 64) + `","iv":"iv"}`, true, http.StatusRequestEntityTooLarge},
+		{"put chunked too large", http.MethodPut, "/list/", `{"data":"` + strings.Repeat("x", 64) + `","iv":"iv"}`, true, http.StatusRequestEntityTooLarge},
+		{"resolve chunked too large", http.MethodPost, "/list/resolve", `{"data":"` + strings.Repeat("x", 64) + `","iv":"iv"}`, true, http.StatusRequestEntityTooLarge},
+		{"post malformed", http.MethodPost, "/list/", `{"data":`, false, http.StatusBadRequest},
+		{"put malformed", http.MethodPut, "/list/", `{"data":`, true, http.StatusBadRequest},
+		{"put within the limit", http.MethodPut, "/list/", `{"data":"list","iv":"iv"}`, true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := userRequest(tt.method, tt.path, "alice", tt.body)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestListConcurrentWrites(t *testing.T) {
+	s, _, _ := socketServer(t)
+	s.Conflicts = conflicts.NewTracker(conflicts.NewMemoryStore(), 10)
+	ctx := api.WithCaller(context.Background(), api.Caller{Subject: "alice"})
+
+	base, err := s.updateList(ctx, "alice", "base", "iv", "")
+	assert.NoError(t, err)
+
+	// every device edits from the same revision, only the first can be written
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		written int
+	)
+	for i := range 8 {
+		wg.Go(func() {
+			w, err := s.updateList(ctx, "alice", strings.Repeat("x", i+1), "iv", base.list.ETag())
+			assert.NoError(t, err)
+			if w.list != nil {
+				mu.Lock()
+				written++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, written)
+	siblings, err := s.Conflicts.List(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, siblings, 7)
+}
+
+func TestListCreateWaitsForWrites(t *testing.T) {
+	s, _, _ := socketServer(t)
+	ctx := api.WithCaller(context.Background(), api.Caller{Subject: "alice"})
+
+	// an update is between its revision check and its write
+	unlock := s.listWrites.Lock("alice")
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.createList(ctx, "alice", "new", "iv")
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the list was created while an update held the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	assert.NoError(t, <-done)
+}
+
+func TestListRoutesDelete(t *testing.T) {
+	s, _, _ := socketServer(t)
+	s.Config.Local.Development = true
+	logger := slog.New(slog.DiscardHandler)
+
+	r := chi.NewRouter()
+	r.Route("/list", func(r chi.Router) {
+		r.Use(authenticate(s.current, s.Connections, nil, logger))
+		s.listRoutes(logger)(r)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, userRequest(http.MethodPut, "/list/", "alice", `{"data":"base","iv":"iv"}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err := s.Conflicts.Add(context.Background(), conflicts.Sibling{Subject: "alice", Data: "stale", IV: "iv", Base: `"old"`, Revision: `"stale"`})
+	assert.NoError(t, err)
+
+	sub, _, err := s.Events.Subscribe("alice", 0)
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, userRequest(http.MethodDelete, "/list/", "alice", ""))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("debug"))
+
+	select {
+	case e := <-sub.C:
+		assert.Equal(t, events.ListDeleted, e.Type)
+	case <-time.After(time.Second):
+		t.Fatal("no list.deleted event")
+	}
+
+	siblings, err := s.Conflicts.List(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, siblings, "the siblings went with the list")
+
+	page, err := s.Changes.Since(context.Background(), "alice", "", 10)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, page.Changes) {
+		last := page.Changes[len(page.Changes)-1]
+		assert.Equal(t, changes.ResourceList, last.Resource)
+		assert.Equal(t, changes.OperationDelete, last.Operation)
+	}
+}
+
+// brokenConflicts fails every write, like a database that has gone away
+type brokenConflicts struct {
+	*conflicts.MemoryStore
+}
+
+func (brokenConflicts) Add(context.Context, conflicts.Sibling) error {
+	return errors.New("storage unavailable")
+}
+
+func TestListRoutesSiblingError(t *testing.T) {
+	s, _, _ := socketServer(t)
+	s.Config.Local.Development = true
+	s.Conflicts = conflicts.NewTracker(brokenConflicts{conflicts.NewMemoryStore()}, 2)
+	logger := slog.New(slog.DiscardHandler)
+
+	r := chi.NewRouter()
+	r.Route("/list", func(r chi.Router) {
+		r.Use(authenticate(s.current, s.Connections, nil, logger))
+		s.listRoutes(logger)(r)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, userRequest(http.MethodPut, "/list/", "alice", `{"data":"base","iv":"iv"}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req := userRequest(http.MethodPut, "/list/", "alice", `{"data":"stale","iv":"iv"}`)
+	req.Header.Set("If-Match", `"old"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "the sibling couldn't be kept")
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/metrics"
+)
+
+// problemMaintenance identifies the maintenance problem document
+const problemMaintenance = "urn:todo-list:problem:maintenance"
+
+// problem is an RFC 9457 problem document
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Mode   string `json:"mode,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// maintenance is the mode the api is in, it starts from the config and can be changed through the
+// admin routes, a reload only changes it when the maintenance config itself has changed
+type maintenance struct {
+	mu         sync.RWMutex
+	current    config.Maintenance
+	configured config.Maintenance
+}
+
+func newMaintenance(m config.Maintenance) *maintenance {
+	mm := &maintenance{configured: m}
+	mm.Set(m)
+
+	return mm
+}
+
+// Get returns the current mode
+func (m *maintenance) Get() config.Maintenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.current
+}
+
+// Set switches the mode
+func (m *maintenance) Set(mode config.Maintenance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.current = mode
+	metrics.SetMaintenanceMode(mode.Mode)
+}
+
+// Apply takes the mode from a reloaded config if it has changed there
+func (m *maintenance) Apply(cfg *config.Config) error {
+	m.mu.Lock()
+	changed := cfg.Maintenance != m.configured
+	m.configured = cfg.Maintenance
+	m.mu.Unlock()
+
+	if changed {
+		m.Set(cfg.Maintenance)
+	}
+
+	return nil
+}
+
+// gate turns away requests the current mode doesn't allow, read-only still lets lists be read
+func (m *maintenance) gate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mode := m.Get()
+		switch mode.Mode {
+		case config.MaintenanceFull:
+		case config.MaintenanceReadOnly:
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		detail := mode.Message
+		if detail == "" {
+			detail = "The service is down for maintenance, please try again later"
+			if mode.Mode == config.MaintenanceReadOnly {
+				detail = "Changes are paused for maintenance, lists can still be read"
+			}
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(mode.RetryAfter.Seconds()))))
+		writeProblem(w, problem{
+			Type:   problemMaintenance,
+			Title:  "Service under maintenance",
+			Status: http.StatusServiceUnavailable,
+			Detail: detail,
+			Mode:   mode.Mode,
+		})
+	})
+}
+
+type maintenanceRequest struct {
+	Mode       string `json:"mode"`
+	RetryAfter string `json:"retry_after,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// maintenanceRoutes reads and switches the mode, every switch is logged
+func maintenanceRoutes(m *maintenance, logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+			writeMaintenance(w, m.Get())
+		})
+		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
+			req := maintenanceRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+
+			mode := config.Maintenance{
+				Mode:       req.Mode,
+				RetryAfter: m.Get().RetryAfter,
+				Message:    req.Message,
+			}
+			if req.RetryAfter != "" {
+				d, err := time.ParseDuration(req.RetryAfter)
+				if err != nil {
+					http.Error(w, "invalid retry_after", http.StatusBadRequest)
+					return
+				}
+				mode.RetryAfter = d
+			}
+			if err := mode.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			m.Set(mode)
+			logger.Info("maintenance mode changed", "mode", mode.Mode, "retry_after", mode.RetryAfter.String())
+			writeMaintenance(w, mode)
+		})
+	}
+}
+
+func writeMaintenance(w http.ResponseWriter, mode config.Maintenance) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(maintenanceRequest{
+		Mode:       mode.Mode,
+		RetryAfter: mode.RetryAfter.String(),
+		Message:    mode.Message,
+	})
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+)
+
+func maintenanceRouter(m *maintenance) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/list", func(r chi.Router) {
+		r.Use(m.gate)
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+			r.MethodFunc(method, "/", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+		}
+	})
+
+	return r
+}
+
+func TestMaintenanceGate(t *testing.T) {
+	m := newMaintenance(config.Maintenance{Mode: config.MaintenanceOff, RetryAfter: 90 * time.Second})
+	h := maintenanceRouter(m)
+
+	call := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/list", nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodPost).Code)
+
+	m.Set(config.Maintenance{Mode: config.MaintenanceReadOnly, RetryAfter: 90 * time.Second})
+	assert.Equal(t, http.StatusOK, call(http.MethodGet).Code)
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		w := call(method)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, method)
+		assert.Equal(t, "90", w.Header().Get("Retry-After"))
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		p := problem{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		assert.Equal(t, problemMaintenance, p.Type)
+		assert.Equal(t, http.StatusServiceUnavailable, p.Status)
+		assert.Equal(t, config.MaintenanceReadOnly, p.Mode)
+	}
+
+	m.Set(config.Maintenance{Mode: config.MaintenanceFull, RetryAfter: time.Minute, Message: "migrating"})
+	w := call(http.MethodGet)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "migrating")
+}
+
+func TestMaintenanceApply(t *testing.T) {
+	m := newMaintenance(config.Maintenance{Mode: config.MaintenanceOff, RetryAfter: time.Minute})
+	m.Set(config.Maintenance{Mode: config.MaintenanceReadOnly, RetryAfter: time.Minute})
+
+	cfg := &config.Config{}
+	cfg.Maintenance = config.Maintenance{Mode: config.MaintenanceOff, RetryAfter: time.Minute}
+	assert.NoError(t, m.Apply(cfg))
+	assert.Equal(t, config.MaintenanceReadOnly, m.Get().Mode, "an unchanged config keeps the admin setting")
+
+	cfg.Maintenance.Mode = config.MaintenanceFull
+	assert.NoError(t, m.Apply(cfg))
+	assert.Equal(t, config.MaintenanceFull, m.Get().Mode)
+}
+
+func TestMaintenanceAdmin(t *testing.T) {
+	s := adminService(testAdminToken)
+	h := s.internalRouter()
+
+	w := adminRequest(h, http.MethodPut, "/admin/maintenance", testAdminToken, `{"mode":"read-only","retry_after":"10m"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, config.MaintenanceReadOnly, s.Maintenance.Get().Mode)
+	assert.Equal(t, 10*time.Minute, s.Maintenance.Get().RetryAfter)
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(h, http.MethodPut, "/admin/maintenance", testAdminToken, `{"mode":"sleepy"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(h, http.MethodPut, "/admin/maintenance", "", `{"mode":"off"}`).Code)
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+	"github.com/todo-lists-app/todo-lists-api/internal/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey string
+
+const accessEntryKey contextKey = "access-entry"
+
+// accessEntry is filled in by the handlers further down the chain
+type accessEntry struct {
+	subject string
+}
+
+// requestID echoes the request id back to the caller and passes it on to the downstream services
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		if id != "" {
+			w.Header().Set("X-Request-ID", id)
+		}
+
+		next.ServeHTTP(w, r.WithContext(api.WithRequestID(r.Context(), id)))
+	})
+}
+
+// accessLog writes one record per request, the subject is hashed so it can be correlated but not read
+func accessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &accessEntry{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), accessEntryKey, entry)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "access",
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("subject", logging.HashSubject(entry.subject)))
+		})
+	}
+}
+
+// authenticate validates the user against the identity service and stores them as the caller,
+// current returns the active config so a reloaded identity address is used. A device id sent in
+// X-Device-ID has to be one of the users devices that hasn't been revoked, and marks it as seen
+func authenticate(current func() *config.Config, conns *api.Connections, registry *devices.Registry, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject := r.Header.Get("X-User-Subject")
+			if subject == "" {
+				logger.Info("no subject", "request_id", middleware.GetReqID(r.Context()))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			accessToken := r.Header.Get("X-User-Access-Token")
+			if accessToken == "" {
+				logger.Info("no access token", "request_id", middleware.GetReqID(r.Context()))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			v, err := api.NewIdentityService(r.Context(), *current()).WithConnections(conns).GetClient()
+			if err != nil {
+				logger.Error("validate client", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			valid, err := v.ValidateUser(accessToken, subject)
+			if err != nil {
+				logger.Error("validate user", "error", err)
+				if status.Code(err) == codes.Unavailable {
+					unavailable(w, conns.Breaker("identity"))
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !valid {
+				logger.Info("invalid user",
+					"subject", logging.HashSubject(subject),
+					"request_id", middleware.GetReqID(r.Context()))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if entry, ok := r.Context().Value(accessEntryKey).(*accessEntry); ok {
+				entry.subject = subject
+			}
+
+			device := r.Header.Get("X-Device-ID")
+			if device != "" && registry != nil {
+				if _, err := registry.Seen(r.Context(), subject, device); err != nil {
+					if errors.Is(err, devices.ErrNotFound) || errors.Is(err, devices.ErrRevoked) {
+						logger.Info("device rejected",
+							"subject", logging.HashSubject(subject),
+							"error", err,
+							"request_id", middleware.GetReqID(r.Context()))
+						deviceRejected(w, err)
+						return
+					}
+					logger.Error("check device", "error", err)
+					unavailable(w, nil)
+					return
+				}
+			}
+
+			ctx := api.WithCaller(r.Context(), api.Caller{
+				Subject:     subject,
+				AccessToken: accessToken,
+				Device:      device,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requireDevice goes after authenticate on the sync routes, once the user has registered a device every
+// request has to say which one it comes from, otherwise a revoked device could keep syncing by leaving
+// X-Device-ID off
+func requireDevice(registry *devices.Registry, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+			// a device that was sent has already been checked by authenticate
+			if c.Device != "" || registry == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, err := registry.Check(r.Context(), c.Subject, ""); err != nil {
+				if errors.Is(err, devices.ErrRequired) {
+					logger.Info("device rejected",
+						"subject", logging.HashSubject(c.Subject),
+						"error", err,
+						"request_id", middleware.GetReqID(r.Context()))
+					deviceRejected(w, err)
+					return
+				}
+				logger.Error("check device", "error", err)
+				unavailable(w, nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// failFast rejects requests straight away while the breaker for any service the route needs is open
+func failFast(conns *api.Connections, services ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, name := range services {
+				if b := conns.Breaker(name); b != nil && b.State() == breaker.Open {
+					unavailable(w, b)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitBody rejects bodies over the limit, straight away when the length is known up front
+func limitBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// badBody answers a body that couldn't be decoded, with a 413 when limitBody cut it off part way
+func badBody(w http.ResponseWriter, message string, err error) {
+	if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	http.Error(w, message, http.StatusBadRequest)
+}
+
+// unavailable returns a 503, telling the client when the breaker will next let a request through
+func unavailable(w http.ResponseWriter, b *breaker.Breaker) {
+	retry := 1
+	if b != nil {
+		if ra := b.RetryAfter(); ra > 0 {
+			retry = int(math.Ceil(ra.Seconds()))
+		}
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+// problemRateLimited identifies the problem document for a caller making requests too quickly
+const problemRateLimited = "urn:todo-list:problem:rate-limited"
+
+// rateLimit gives each key its own bucket, such as bySubject after authenticate or byAddress
+func rateLimit(l *ratelimit.Limiter, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, wait := l.Allow(key(r)); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeProblem(w, problem{
+					Type:   problemRateLimited,
+					Title:  "Too many requests",
+					Status: http.StatusTooManyRequests,
+					Detail: "slow down and try again after Retry-After seconds",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bySubject keys a rate limit by the caller
+func bySubject(r *http.Request) string {
+	return caller(r).Subject
+}
+
+// byAddress keys a rate limit by the clients address, for routes that don't need an account
+func byAddress(trustForwardedFor func() bool) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return clientAddress(r, trustForwardedFor())
+	}
+}
+
+// clientAddress is the ip the request came from, with trustForwardedFor it is the last X-Forwarded-For
+// entry, which is the one the proxy in front of the api added
+func clientAddress(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// caller returns the user that authenticate stored on the request
+func caller(r *http.Request) api.Caller {
+	c, _ := api.CallerFromContext(r.Context())
+	return c
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+)
+
+func TestAccessLog(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Local.Development = true
+	cfg.Services.Identity = "localhost:3000"
+
+	buf := &bytes.Buffer{}
+	logger := logging.New(buf, slog.LevelDebug)
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(requestID)
+	r.Use(accessLog(logger))
+	r.Route("/list", func(r chi.Router) {
+		r.Use(authenticate(func() *config.Config { return cfg }, api.NewConnections(*cfg, logger), nil, logger))
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			logger.Info("handler", "headers", r.Header, "request", r)
+			_, _ = w.Write([]byte("ok"))
+		})
+	})
+
+	t.Run("tokens are never logged", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/list/", nil)
+		req.Header.Set("X-User-Subject", "testSubject")
+		req.Header.Set("X-User-Access-Token", "secret-access-token")
+		req.Header.Set("Authorization", "Bearer secret-bearer")
+		req.Header.Set("Cookie", "session=secret-cookie")
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get("X-Request-ID"))
+		assert.NotContains(t, buf.String(), "secret-access-token")
+		assert.NotContains(t, buf.String(), "secret-bearer")
+		assert.NotContains(t, buf.String(), "secret-cookie")
+		assert.NotContains(t, buf.String(), "testSubject")
+		assert.Contains(t, buf.String(), logging.HashSubject("testSubject"))
+		assert.Contains(t, buf.String(), `"route":"/list"`)
+		assert.Contains(t, buf.String(), `"status":200`)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/list/", nil)
+		req.Header.Set("X-User-Subject", "testSubject")
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, buf.String(), `"status":401`)
+		assert.NotContains(t, buf.String(), "testSubject")
+	})
+}
+
+func TestClientAddress(t *testing.T) {
+	for _, tt := range []struct {
+		forwarded []string
+		trust     bool
+		want      string
+	}{
+		{nil, false, "192.0.2.1"},
+		{[]string{"198.51.100.7"}, false, "192.0.2.1"},
+		{[]string{"203.0.113.9, 198.51.100.7"}, true, "198.51.100.7"},
+		{[]string{"203.0.113.9", "198.51.100.8"}, true, "198.51.100.8"},
+		{nil, true, "192.0.2.1"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/shares/x", nil)
+		req.RemoteAddr = "192.0.2.1:4000"
+		for _, f := range tt.forwarded {
+			req.Header.Add("X-Forwarded-For", f)
+		}
+		if got := clientAddress(req, tt.trust); got != tt.want {
+			t.Errorf("%v trust %v: got %s, want %s", tt.forwarded, tt.trust, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
 )
 
 // NoLists returns a 200 with no lists.
@@ -23,19 +24,23 @@ func NoLists(w http.ResponseWriter) error {
 	})
 }
 
-// ListExists returns the list data for the user.
-func ListExists(w http.ResponseWriter, l *api.StoredList) error {
+// ListExists returns the list data for the user, with any versions written from a stale revision
+// that still have to be merged.
+func ListExists(w http.ResponseWriter, l *api.StoredList, siblings []conflicts.Sibling) error {
 	type List struct {
-		Message string `json:"message,omitempty"`
-		Data    string `json:"data,omitempty"`
-		IV      string `json:"iv,omitempty"`
+		Message   string              `json:"message,omitempty"`
+		Data      string              `json:"data,omitempty"`
+		IV        string              `json:"iv,omitempty"`
+		Conflicts []conflicts.Sibling `json:"conflicts,omitempty"`
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", l.ETag())
 	w.WriteHeader(http.StatusOK)
 	return json.NewEncoder(w).Encode(List{
-		Data: l.Data,
-		IV:   l.IV,
+		Data:      l.Data,
+		IV:        l.IV,
+		Conflicts: siblings,
 	})
 }
 
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"github.com/todo-lists-app/todo-lists-api/internal/inbox"
+	"github.com/todo-lists-app/todo-lists-api/internal/invites"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+	"github.com/todo-lists-app/todo-lists-api/internal/ratelimit"
+	"github.com/todo-lists-app/todo-lists-api/internal/shares"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+// routeTest is what the route tests build their routers from, every store is in memory and the config
+// is for development so authenticate doesn't call the identity service. Tests change the limits they
+// rely on before serving anything
+type routeTest struct {
+	*Service
+	router chi.Router
+	logger *slog.Logger
+	// outbox is the services Mail
+	outbox *outbox
+}
+
+func newRouteTest(t *testing.T) *routeTest {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Local.Development = true
+	cfg.Services.Identity = "localhost:3000"
+	cfg.Invites.AcceptURL = "https://todo-list.app/invites/{token}"
+	cfg.Keys.EmailVerifyURL = "https://todo-list.app/account/email/{token}"
+
+	logger := slog.New(slog.DiscardHandler)
+	conns := api.NewConnections(*cfg, logger)
+	t.Cleanup(func() {
+		_ = conns.Close()
+	})
+	out := &outbox{}
+
+	return &routeTest{
+		Service: &Service{
+			Config:         cfg,
+			Connections:    conns,
+			Events:         events.NewHub(5, 10, time.Minute),
+			Changes:        changes.NewFeed(changes.NewMemoryStore(0)),
+			Devices:        devices.NewRegistry(devices.NewMemoryStore(), 2, time.Minute, logger),
+			Sharing:        sharing.NewLists(sharing.NewMemoryStore(), 5),
+			Keys:           keys.NewDirectory(keys.NewMemoryStore()),
+			Emails:         keys.NewEmails(keys.NewMemoryEmailStore(), time.Hour),
+			Invites:        invites.NewInvites(invites.NewMemoryStore(), time.Hour, 5),
+			Mail:           out,
+			Shares:         shares.NewShares(shares.NewMemoryStore(), time.Hour, 24*time.Hour),
+			Inbox:          inbox.NewInbox(inbox.NewMemoryStore(), 5, 64),
+			changesLog:     logger,
+			keyLookups:     ratelimit.New(60, 10),
+			inviteSends:    ratelimit.New(60, 10),
+			shareViews:     ratelimit.New(60, 20),
+			sharePasswords: ratelimit.NewWithClock(5, passwordBurst, stopped),
+			linkPasswords:  ratelimit.NewWithClock(60, linkPasswordBurst, stopped),
+			inboxSends:     ratelimit.New(60, 10),
+		},
+		router: chi.NewRouter(),
+		logger: logger,
+		outbox: out,
+	}
+}
+
+// stopped is the clock for limiters a test uses up, they never refill however slowly the test runs
+func stopped() time.Time {
+	return time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// auth is authenticate as the routers use it, checking any device that is sent against the registry
+func (rt *routeTest) auth(next http.Handler) http.Handler {
+	return authenticate(rt.current, rt.Connections, rt.Devices, rt.logger)(next)
+}
+
+// serve runs the request through the router
+func (rt *routeTest) serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	rt.router.ServeHTTP(w, req)
+
+	return w
+}
+
+// userRequest is a request authenticate accepts as the subject
+func userRequest(method, path, subject, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User-Subject", subject)
+	req.Header.Set("X-User-Access-Token", "token")
+
+	return req
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+
+	vaultHelper "github.com/keloran/vault-helper"
+	"github.com/todo-lists-app/todo-lists-api/internal/certs"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+)
+
+// serve runs the server on the listener, terminating tls itself when it is enabled,
+// HTTP/2 is always on over tls and over plaintext when h2c is enabled
+func serve(cfg *config.Config, srv *http.Server, lis net.Listener, stop <-chan struct{}, logger *slog.Logger) error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+
+	if !cfg.HTTPS.Enabled {
+		protocols.SetUnencryptedHTTP2(cfg.HTTPS.H2C)
+		srv.Protocols = protocols
+		return srv.Serve(lis)
+	}
+
+	tlsCfg, store, err := serverTLS(cfg)
+	if err != nil {
+		return fmt.Errorf("server tls: %v", err)
+	}
+	go store.Watch(cfg.HTTPS.ReloadInterval, stop, logger)
+
+	srv.Protocols = protocols
+	srv.TLSConfig = tlsCfg
+	return srv.ServeTLS(lis, "", "")
+}
+
+// listen opens the tcp or unix socket listener, a socket left behind by a previous run is removed first
+func listen(network, address string) (net.Listener, error) {
+	if network == "unix" {
+		if err := os.Remove(address); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("remove old socket: %v", err)
+		}
+	}
+
+	return net.Listen(network, address)
+}
+
+// serverTLS loads the certificate and builds the tls config from the https config
+func serverTLS(cfg *config.Config) (*tls.Config, *certs.Store, error) {
+	minVersion, err := certs.ParseVersion(cfg.HTTPS.MinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	suites, err := certs.ParseCipherSuites(cfg.HTTPS.CipherSuites)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	load := certs.FileLoader("", cfg.HTTPS.CertFile, cfg.HTTPS.KeyFile)
+	if cfg.HTTPS.VaultPath != "" {
+		var vh vaultHelper.VaultHelper
+		if cfg.Config.VaultHelper != nil {
+			vh = *cfg.Config.VaultHelper
+		}
+		load = certs.VaultLoader(vh, cfg.HTTPS.VaultPath)
+	}
+
+	store, err := certs.NewStore(load)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load certificate: %v", err)
+	}
+	if store.Certificate() == nil {
+		return nil, nil, errors.New("tls is enabled but there is no certificate")
+	}
+
+	return store.ServerConfig(minVersion, suites), store, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/certs/certstest"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+)
+
+func startServer(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+	stop := make(chan struct{})
+	go func() {
+		_ = serve(cfg, srv, lis, stop, slog.New(slog.DiscardHandler))
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		_ = srv.Close()
+	})
+
+	return lis.Addr().String()
+}
+
+func TestServe(t *testing.T) {
+	t.Run("tls with http2", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := certstest.NewCA(t)
+		certPEM, keyPEM := ca.Issue(t, "server")
+
+		cfg := &config.Config{}
+		cfg.HTTPS.Enabled = true
+		cfg.HTTPS.CertFile = certstest.Write(t, dir, "cert.pem", certPEM)
+		cfg.HTTPS.KeyFile = certstest.Write(t, dir, "key.pem", keyPEM)
+		cfg.HTTPS.MinVersion = "1.3"
+		address := startServer(t, cfg)
+
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca.PEM)
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+				ForceAttemptHTTP2: true,
+			},
+		}
+
+		resp, err := client.Get("https://" + address)
+		assert.NoError(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, resp.ProtoMajor)
+		assert.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.HTTPS.H2C = true
+		address := startServer(t, cfg)
+
+		protocols := new(http.Protocols)
+		protocols.SetUnencryptedHTTP2(true)
+		client := &http.Client{
+			Transport: &http.Transport{
+				Protocols: protocols,
+			},
+		}
+
+		resp, err := client.Get("http://" + address)
+		assert.NoError(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		assert.Equal(t, 2, resp.ProtoMajor)
+	})
+
+	t.Run("no certificate", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.HTTPS.Enabled = true
+
+		_, _, err := serverTLS(cfg)
+		assert.Error(t, err)
+	})
+
+	t.Run("cipher suites without http2 suite", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.HTTPS.CipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}
+
+		_, _, err := serverTLS(cfg)
+		assert.Error(t, err)
+	})
+}
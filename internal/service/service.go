@@ -1,80 +1,315 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"go.mongodb.org/mongo-driver/mongo"
-
-	"github.com/bugfixes/go-bugfixes/logs"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
-	"github.com/keloran/go-healthcheck"
-	"github.com/keloran/go-probe"
-	validate "github.com/todo-lists-app/go-validate-user"
 	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
 	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"github.com/todo-lists-app/todo-lists-api/internal/health"
+	"github.com/todo-lists-app/todo-lists-api/internal/inbox"
+	"github.com/todo-lists-app/todo-lists-api/internal/invites"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+	"github.com/todo-lists-app/todo-lists-api/internal/locks"
+	"github.com/todo-lists-app/todo-lists-api/internal/logging"
+	"github.com/todo-lists-app/todo-lists-api/internal/mail"
+	"github.com/todo-lists-app/todo-lists-api/internal/ratelimit"
+	"github.com/todo-lists-app/todo-lists-api/internal/shares"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+	"github.com/todo-lists-app/todo-lists-api/internal/version"
 )
 
 // Service is the service
 type Service struct {
 	Config *config.Config
+
+	Reloader    *config.Reloader
+	Connections *api.Connections
+	Readiness   *health.Readiness
+	Version     *version.Reporter
+	Levels      *logging.Levels
+	Maintenance *maintenance
+	Events      *events.Hub
+	Changes     *changes.Feed
+	Devices     *devices.Registry
+	Conflicts   *conflicts.Tracker
+	Sharing     *sharing.Lists
+	Keys        *keys.Directory
+	Emails      *keys.Emails
+	Invites     *invites.Invites
+	Mail        mail.Sender
+	Shares      *shares.Shares
+	Inbox       *inbox.Inbox
+
+	// stopping is closed when the service starts shutting down, for connections the server doesn't track
+	stopping   <-chan struct{}
+	changesLog *slog.Logger
+	// listWrites serialises the revision check and write of each users list
+	listWrites  locks.Keyed
+	keyLookups  *ratelimit.Limiter
+	inviteSends *ratelimit.Limiter
+	// shareViews limits each address, sharePasswords each address guessing at a link and linkPasswords
+	// everyone guessing at a link
+	shareViews     *ratelimit.Limiter
+	sharePasswords *ratelimit.Limiter
+	linkPasswords  *ratelimit.Limiter
+	inboxSends     *ratelimit.Limiter
 }
 
 // Start the service
 func (s *Service) Start() error {
 	errChan := make(chan error)
 
-	go startHTTP(s.Config, errChan)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s.stopping = ctx.Done()
+
+	if s.Levels == nil {
+		level, _ := logging.ParseLevel(s.Config.Logging.Level)
+		s.Levels = logging.NewLevels(level)
+	}
+	serviceLog := s.logger(logging.ComponentService)
+
+	s.Connections = api.NewConnections(*s.Config, serviceLog)
+	defer func() {
+		if err := s.Connections.Close(); err != nil {
+			serviceLog.Warn("close connections", "error", err)
+		}
+	}()
+	s.Readiness = health.NewReadiness(s.Config, s.Connections)
+	if s.Version == nil {
+		s.Version = version.New("api", "dev", "unknown")
+	}
+
+	s.Maintenance = newMaintenance(s.Config.Maintenance)
+	s.Events = events.NewHub(s.Config.Events.MaxStreamsPerUser, s.Config.Events.Backlog, s.Config.Events.BacklogTTL)
+
+	s.changesLog = s.logger(logging.ComponentChanges)
+	db, err := openStorage(ctx, s.Config.Storage)
+	if err != nil {
+		return fmt.Errorf("storage: %v", err)
+	}
+	if db != nil {
+		defer func() {
+			if err := db.Client().Disconnect(context.Background()); err != nil {
+				serviceLog.Warn("close storage", "error", err)
+			}
+		}()
+	}
+	if s.Changes == nil {
+		if s.Changes, err = newChangeFeed(ctx, db, s.Config.Changes, s.changesLog); err != nil {
+			return fmt.Errorf("change feed: %v", err)
+		}
+	}
+	if s.Devices == nil {
+		if s.Devices, err = newDeviceRegistry(ctx, db, s.Config.Devices, s.logger(logging.ComponentDevices)); err != nil {
+			return fmt.Errorf("device registry: %v", err)
+		}
+	}
+	if s.Conflicts == nil {
+		if s.Conflicts, err = newConflictTracker(ctx, db, s.Config.Conflicts, s.logger(logging.ComponentList)); err != nil {
+			return fmt.Errorf("conflict tracker: %v", err)
+		}
+	}
+	if s.Sharing == nil {
+		if s.Sharing, err = newSharedLists(ctx, db, s.Config.Sharing, s.logger(logging.ComponentSharing)); err != nil {
+			return fmt.Errorf("shared lists: %v", err)
+		}
+	}
+	if s.Keys == nil {
+		if s.Keys, err = newKeyDirectory(ctx, db, s.Config.Keys, s.logger(logging.ComponentKeys)); err != nil {
+			return fmt.Errorf("key directory: %v", err)
+		}
+	}
+	if s.Emails == nil {
+		if s.Emails, err = newKeyEmails(ctx, db, s.Config.Keys, s.logger(logging.ComponentKeys)); err != nil {
+			return fmt.Errorf("key emails: %v", err)
+		}
+	}
+	s.keyLookups = ratelimit.New(s.Config.Keys.LookupsPerMinute, s.Config.Keys.LookupBurst)
+	if s.Invites == nil {
+		if s.Invites, err = newInvites(ctx, db, s.Config.Invites, s.logger(logging.ComponentSharing)); err != nil {
+			return fmt.Errorf("invites: %v", err)
+		}
+	}
+	if s.Mail == nil {
+		if s.Mail, err = newMailer(s.Config.Mail, s.logger(logging.ComponentSharing)); err != nil {
+			return fmt.Errorf("mail: %v", err)
+		}
+	}
+	s.inviteSends = ratelimit.New(s.Config.Invites.SendsPerMinute, s.Config.Invites.SendBurst)
+	if s.Shares == nil {
+		if s.Shares, err = newShares(ctx, db, s.Config.Shares, s.logger(logging.ComponentSharing)); err != nil {
+			return fmt.Errorf("shares: %v", err)
+		}
+	}
+	s.shareViews = ratelimit.New(s.Config.Shares.ViewsPerMinute, s.Config.Shares.ViewBurst)
+	s.sharePasswords = ratelimit.New(s.Config.Shares.PasswordAttemptsPerMinute, passwordBurst)
+	s.linkPasswords = ratelimit.New(s.Config.Shares.LinkPasswordAttemptsPerMinute, linkPasswordBurst)
+	if s.Inbox == nil {
+		if s.Inbox, err = newInbox(ctx, db, s.Config.Inbox, s.logger(logging.ComponentInbox)); err != nil {
+			return fmt.Errorf("inbox: %v", err)
+		}
+	}
+	s.inboxSends = ratelimit.New(s.Config.Inbox.SendsPerMinute, s.Config.Inbox.SendBurst)
+
+	if s.Reloader == nil {
+		s.Reloader = config.NewReloader(s.Config, config.Build, serviceLog)
+	}
+	s.Reloader.Subscribe("connections", s.Connections)
+	s.Reloader.Subscribe("readiness", s.Readiness)
+	s.Reloader.Subscribe("maintenance", s.Maintenance)
+	s.Reloader.Subscribe("events", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Events.SetLimits(cfg.Events.MaxStreamsPerUser, cfg.Events.Backlog, cfg.Events.BacklogTTL)
+		return nil
+	}))
+	s.Reloader.Subscribe("devices", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Devices.SetLimits(cfg.Devices.MaxPerUser, cfg.Devices.LastSeenInterval)
+		return nil
+	}))
+	s.Reloader.Subscribe("conflicts", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Conflicts.SetLimit(cfg.Conflicts.MaxSiblings)
+		return nil
+	}))
+	s.Reloader.Subscribe("sharing", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Sharing.SetLimit(cfg.Sharing.MaxGrants)
+		return nil
+	}))
+	s.Reloader.Subscribe("keys", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.keyLookups.SetLimits(cfg.Keys.LookupsPerMinute, cfg.Keys.LookupBurst)
+		s.Emails.SetTTL(cfg.Keys.EmailTTL)
+		return nil
+	}))
+	s.Reloader.Subscribe("invites", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Invites.SetLimits(cfg.Invites.TTL, cfg.Invites.MaxPending)
+		s.inviteSends.SetLimits(cfg.Invites.SendsPerMinute, cfg.Invites.SendBurst)
+		return nil
+	}))
+	s.Reloader.Subscribe("shares", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Shares.SetLimits(cfg.Shares.DefaultTTL, cfg.Shares.MaxTTL)
+		s.shareViews.SetLimits(cfg.Shares.ViewsPerMinute, cfg.Shares.ViewBurst)
+		s.sharePasswords.SetLimits(cfg.Shares.PasswordAttemptsPerMinute, passwordBurst)
+		s.linkPasswords.SetLimits(cfg.Shares.LinkPasswordAttemptsPerMinute, linkPasswordBurst)
+		return nil
+	}))
+	s.Reloader.Subscribe("inbox", config.SubscriberFunc(func(cfg *config.Config) error {
+		s.Inbox.SetLimits(cfg.Inbox.MaxItems, cfg.Inbox.MaxItemBytes)
+		s.inboxSends.SetLimits(cfg.Inbox.SendsPerMinute, cfg.Inbox.SendBurst)
+		return nil
+	}))
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go s.Reloader.Watch(ctx, hup, s.Config.Reload.Interval)
+
+	go s.startInternal(ctx, errChan)
+	go s.startHTTP(ctx, errChan)
 
 	return <-errChan
 }
 
+// current returns the active config, it changes when the config is reloaded
+func (s *Service) current() *config.Config {
+	if s.Reloader == nil {
+		return s.Config
+	}
+
+	return s.Reloader.Current()
+}
+
+// logger is the logger for a component, its level follows the admin endpoint and every record is redacted
+func (s *Service) logger(component string) *slog.Logger {
+	return s.Levels.Logger(os.Stdout, component)
+}
+
+// breakerOpen returns a 503 when the failed call has tripped the breaker, rather than treating it as an error
+func (s *Service) breakerOpen(w http.ResponseWriter, name string) bool {
+	b := s.Connections.Breaker(name)
+	if b == nil || b.State() != breaker.Open {
+		return false
+	}
+
+	unavailable(w, b)
+	return true
+}
+
 type injectData struct {
 	Data string `json:"data"`
 	IV   string `json:"iv"`
 }
 
+// siblingAccepted is the answer to an update that was kept as a sibling
+type siblingAccepted struct {
+	Sibling  string `json:"sibling"`
+	Revision string `json:"revision"`
+}
+
+// resolveRequest is a version merged client side from the list at Base and the siblings in Resolves
+type resolveRequest struct {
+	Data     string   `json:"data"`
+	IV       string   `json:"iv"`
+	Base     string   `json:"base"`
+	Resolves []string `json:"resolves"`
+}
+
 //golint:ignore(gocyclo)
-func startHTTP(cfg *config.Config, errChan chan error) {
-	p := fmt.Sprintf(":%d", cfg.Local.HTTPPort)
-	logs.Local().Infof("starting http on %s", p)
-
-	allowedOrigins := []string{
-		"http://localhost:3000",
-		"https://todo-list.app",
-		"https://beta.todo-list.app",
-	}
-	if cfg.Local.Development {
-		allowedOrigins = append(allowedOrigins, "http://*")
-	}
+func (s *Service) startHTTP(ctx context.Context, errChan chan error) {
+	cfg := s.Config
+	httpLog := s.logger(logging.ComponentHTTP)
+	httpLog.Info("starting http", "address", cfg.Server.Address)
+
+	authLog := s.logger(logging.ComponentAuth)
+	listLog := s.logger(logging.ComponentList)
+	accountLog := s.logger(logging.ComponentAccount)
 
 	r := chi.NewRouter()
-	r.Use(middleware.Heartbeat("/ping"))
 	r.Use(middleware.RequestID)
-	r.Use(cors.New(cors.Options{
-		AllowedOrigins: allowedOrigins,
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{
-			"Accept",
-			"Authorization",
-			"Content-Type",
-			"X-CSRF-Token",
-			"X-User-Subject",
-			"X-User-Access-Token",
-		},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}).Handler)
-	r.Get("/health", healthcheck.HTTP)
-	r.Get("/probe", probe.HTTP)
+	r.Use(requestID)
+	r.Use(accessLog(httpLog))
+	policy := newCORSPolicy(cfg.CORS)
+	s.Reloader.Subscribe("cors", policy)
+	r.Use(policy.Handler)
+	r.Use(limitBody(cfg.Server.MaxBodyBytes))
+	r.Get("/version", s.Version.PublicHTTP)
+
+	// publishing a key doesn't need the user service, so it isn't under /account and its fail fast
+	r.Route("/account/keys", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		keysLog := s.logger(logging.ComponentKeys)
+		publishKeyRoutes(s.Keys, keysLog)(r)
+		s.emailRoutes(keysLog)(r)
+	})
+
+	r.Route("/users", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		r.Use(rateLimit(s.keyLookups, bySubject))
+		lookupKeyRoutes(s.Keys, s.Emails, s.logger(logging.ComponentKeys))(r)
+	})
 
 	r.Route("/account", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity", "user"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+
 		//r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		//	subject := r.Header.Get("X-User-Subject")
 		//
@@ -84,7 +319,7 @@ func startHTTP(cfg *config.Config, errChan chan error) {
 		//		return
 		//	}
 		//
-		//	a := api.NewAccountService(r.Context(), *cfg, subject)
+		//	a := api.NewAccountService(r.Context(), *s.current(), subject)
 		//	account, err := a.GetAccount()
 		//	if err != nil {
 		//		logs.Infof("Error: %s", err)
@@ -118,307 +353,172 @@ func startHTTP(cfg *config.Config, errChan chan error) {
 		//	}
 		//})
 		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-			accessToken := r.Header.Get("X-User-Access-Token")
-			if accessToken == "" {
-				logs.Info("No Access Token")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			subject := r.Header.Get("X-User-Subject")
-			if subject == "" {
-				logs.Info("No Subject")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			v, err := validate.NewValidate(r.Context(), cfg.Services.Identity, cfg.Local.Development).GetClient()
-			if err != nil {
-				logs.Infof("validate client err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			valid, err := v.ValidateUser(accessToken, subject)
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if !valid {
-				logs.Info("invalid user")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+			c := caller(r)
 
-			a, err := api.NewAccountService(r.Context(), *cfg, subject, accessToken).GetClient()
+			a, err := api.NewAccountService(r.Context(), *s.current(), c.Subject, c.AccessToken).WithConnections(s.Connections).GetClient()
 			if err != nil {
-				logs.Infof("Error Get Account Client: %s", err)
+				accountLog.Error("get account client", "error", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
 			if err := a.DeleteAccount(); err != nil {
-				logs.Infof("Error Delete Account: %s", err)
+				if s.breakerOpen(w, "user") {
+					return
+				}
+				accountLog.Error("delete account", "error", err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
-
-			w.WriteHeader(http.StatusOK)
-		})
-	})
-
-	r.Route("/list", func(r chi.Router) {
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			subject := r.Header.Get("X-User-Subject")
-			if subject == "" {
-				logs.Info("No Subject")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
+			s.recordChange(r.Context(), changes.ResourceAccount, changes.OperationDelete, "")
+			if err := s.Devices.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget devices", "error", err)
 			}
-
-			accessToken := r.Header.Get("X-User-Access-Token")
-			if accessToken == "" {
-				logs.Info("No Access Token")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
+			if err := s.Conflicts.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget conflicts", "error", err)
 			}
-
-			v, err := validate.NewValidate(r.Context(), cfg.Services.Identity, cfg.Local.Development).GetClient()
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+			if err := s.Sharing.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget shared lists", "error", err)
 			}
-			valid, err := v.ValidateUser(accessToken, subject)
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+			if err := s.Invites.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget invites", "error", err)
 			}
-			if !valid {
-				logs.Infof("invalid user: %+v", r.Header)
-				w.WriteHeader(http.StatusUnauthorized)
-				return
+			if err := s.Shares.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget shares", "error", err)
 			}
-
-			l, err := api.NewListService(r.Context(), *cfg, subject).GetClient()
-			if err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
+			if err := s.Inbox.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget inbox", "error", err)
 			}
-
-			list, err := l.GetList()
-			if err != nil {
-				if errors.Is(err, mongo.ErrNoDocuments) {
-					w.WriteHeader(http.StatusOK)
-					return
-				}
-
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
+			if err := s.Keys.Withdraw(r.Context(), c.Subject); err != nil {
+				accountLog.Error("withdraw key", "error", err)
 			}
-
-			if list == nil {
-				if err := NoLists(w); err != nil {
-					logs.Infof("Error: %s", err)
-					w.WriteHeader(http.StatusInternalServerError)
-					errChan <- err
-					return
-				}
-				return
+			if err := s.Emails.Forget(r.Context(), c.Subject); err != nil {
+				accountLog.Error("forget email", "error", err)
 			}
 
-			if err := ListExists(w, list); err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+			w.WriteHeader(http.StatusOK)
 		})
-		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-			subject := r.Header.Get("X-User-Subject")
-			if subject == "" {
-				logs.Info("No Subject")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			accessToken := r.Header.Get("X-User-Access-Token")
-			if accessToken == "" {
-				logs.Info("No Access Token")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			v, err := validate.NewValidate(r.Context(), cfg.Services.Identity, cfg.Local.Development).GetClient()
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			valid, err := v.ValidateUser(accessToken, subject)
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if !valid {
-				logs.Info("invalid user")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+	})
 
-			id := injectData{}
-			if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	r.Route("/devices", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		deviceRoutes(s.Devices, s.logger(logging.ComponentDevices))(r)
+	})
 
-			l, err := api.NewListService(r.Context(), *cfg, subject).GetClient()
-			if err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	r.Route("/lists", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		sharingLog := s.logger(logging.ComponentSharing)
+		s.sharingRoutes(sharingLog)(r)
+		s.inviteRoutes(sharingLog)(r)
+	})
 
-			stored, err := l.CreateList(&api.StoredList{
-				UserID: subject,
-				Data:   id.Data,
-				IV:     id.IV,
-			})
-			if err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	// making links doesn't need the todo service, so it isn't under /list and its fail fast
+	r.Route("/list/shares", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		shareRoutes(s.Shares, s.logger(logging.ComponentSharing))(r)
+	})
 
-			if err := ListExists(w, stored); err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	// links are opened by people without an account, so there is no authenticate
+	r.Route("/shares", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		address := byAddress(func() bool {
+			return s.current().Server.TrustForwardedFor
 		})
-		r.Put("/", func(w http.ResponseWriter, r *http.Request) {
-			subject := r.Header.Get("X-User-Subject")
-			if subject == "" {
-				logs.Info("No Subject")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-			accessToken := r.Header.Get("X-User-Access-Token")
-			if accessToken == "" {
-				logs.Info("No Access Token")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-
-			v, err := validate.NewValidate(r.Context(), cfg.Services.Identity, cfg.Local.Development).GetClient()
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			valid, err := v.ValidateUser(accessToken, subject)
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if !valid {
-				logs.Info("invalid user")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+		r.Use(rateLimit(s.shareViews, address))
+		r.Get("/{id}", s.openShare(address, s.logger(logging.ComponentSharing)))
+	})
 
-			id := injectData{}
-			if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	// items are dropped in by people without an account, so only the owners routes authenticate
+	r.Route("/inbox", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		inboxLog := s.logger(logging.ComponentInbox)
+		r.Group(func(r chi.Router) {
+			r.Use(failFast(s.Connections, "identity"))
+			r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+			s.inboxRoutes(inboxLog)(r)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(rateLimit(s.inboxSends, byAddress(func() bool {
+				return s.current().Server.TrustForwardedFor
+			})))
+			s.dropRoutes(inboxLog)(r)
+		})
+	})
 
-			l, err := api.NewListService(r.Context(), *cfg, subject).GetClient()
-			if err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	r.Route("/invites", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		r.Post("/{token}/accept", s.acceptInvite(s.logger(logging.ComponentSharing)))
+	})
 
-			if _, err := l.UpdateList(&api.StoredList{
-				UserID: subject,
-				Data:   id.Data,
-				IV:     id.IV,
-			}); err != nil {
-				logs.Infof("Error: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				errChan <- err
-				return
-			}
+	r.Route("/changes", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		r.Use(requireDevice(s.Devices, authLog))
+		r.Get("/", s.listChanges)
+	})
 
-			w.Header().Set("debug", "put list")
-			w.WriteHeader(http.StatusOK)
-		})
-		r.Delete("/", func(w http.ResponseWriter, r *http.Request) {
-			subject := r.Header.Get("X-User-Subject")
-			if subject == "" {
-				logs.Info("No Subject")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
-			accessToken := r.Header.Get("X-User-Access-Token")
-			if accessToken == "" {
-				logs.Info("No Access Token")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+	r.Route("/ws", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		r.Use(requireDevice(s.Devices, authLog))
+		r.Get("/", s.listSocket(listLog))
+	})
 
-			v, err := validate.NewValidate(r.Context(), cfg.Services.Identity, cfg.Local.Development).GetClient()
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			valid, err := v.ValidateUser(accessToken, subject)
-			if err != nil {
-				logs.Infof("validate user err: %s", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			if !valid {
-				logs.Info("invalid user")
-				w.WriteHeader(http.StatusUnauthorized)
-				return
-			}
+	r.Route("/list", func(r chi.Router) {
+		r.Use(s.Maintenance.gate)
+		r.Use(failFast(s.Connections, "identity", "todo"))
+		r.Use(authenticate(s.current, s.Connections, s.Devices, authLog))
+		r.Use(requireDevice(s.Devices, authLog))
 
-			logs.Infof("Subject: %s", subject)
-			w.Header().Set("debug", "delete list")
-			w.WriteHeader(http.StatusNotImplemented)
-			logs.Local().Info("Delete List")
-		})
+		s.listRoutes(listLog)(r)
 	})
 
 	srv := &http.Server{
-		Addr:              p,
+		Addr:              cfg.Server.Address,
 		Handler:           r,
-		ReadTimeout:       5 * time.Second,
-		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      15 * time.Second,
-		IdleTimeout:       15 * time.Second,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	lis, err := listen(cfg.Server.Listener())
+	if err != nil {
+		errChan <- err
+		return
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
+	stop := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		httpLog.Info("shutting down")
+		s.Readiness.ShuttingDown()
+		time.Sleep(s.current().Health.ShutdownDelay)
+
+		sctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownGrace)
+		defer cancel()
+		if err := srv.Shutdown(sctx); err != nil {
+			httpLog.Error("shutdown", "error", err)
+		}
+		close(stop)
+	}()
+
+	if err := serve(cfg, srv, lis, stop, httpLog); err != nil {
+		if errors.Is(err, http.ErrServerClosed) {
+			errChan <- nil
+			return
+		}
 		errChan <- err
 		return
 	}
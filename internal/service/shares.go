@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/shares"
+)
+
+// problemSharePassword identifies the problem document for a link that needs a password
+const problemSharePassword = "urn:todo-list:problem:share-password"
+
+const (
+	// passwordBurst is how many passwords one address can try on a link at once, enough for a typo or two
+	passwordBurst = 3
+	// linkPasswordBurst is how many passwords every address together can try on a link at once
+	linkPasswordBurst = 20
+)
+
+// createShare is what the owner sends to make a link, the snapshot is encrypted with a key that only
+// goes in the links fragment, expires_at and max_views are optional
+type createShare struct {
+	Data      string    `json:"data"`
+	IV        string    `json:"iv"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxViews  int       `json:"max_views"`
+	Password  string    `json:"password"`
+}
+
+// snapshot is what someone opening a link gets
+type snapshot struct {
+	Data      string    `json:"data"`
+	IV        string    `json:"iv"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ViewsLeft *int      `json:"views_left,omitempty"`
+}
+
+// shareRoutes lets a user make, list and revoke links to their list
+func shareRoutes(links *shares.Shares, logger *slog.Logger) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			req := createShare{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid share", err)
+				return
+			}
+
+			share, err := links.Create(r.Context(), caller(r).Subject, req.Data, req.IV, req.ExpiresAt, req.MaxViews, req.Password)
+			if err != nil {
+				if errors.Is(err, shares.ErrInvalid) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				logger.Error("create share", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Location", "/shares/"+share.ID)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(share)
+		})
+
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			list, err := links.ForOwner(r.Context(), caller(r).Subject)
+			if err != nil {
+				logger.Error("list shares", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []shares.Share{}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(list)
+		})
+
+		r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if err := links.Revoke(r.Context(), caller(r).Subject, chi.URLParam(r, "id")); err != nil {
+				if errors.Is(err, shares.ErrNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				logger.Error("revoke share", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// openShare serves a links snapshot to anyone, the password comes in X-Share-Password. Guesses at it
+// are limited for each address, and for every address together so spreading them over many addresses
+// doesn't help
+func (s *Service) openShare(address func(*http.Request) string, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		password := r.Header.Get("X-Share-Password")
+		if password != "" {
+			ok, wait := s.sharePasswords.Allow(id + " " + address(r))
+			if ok {
+				ok, wait = s.linkPasswords.Allow(id)
+			}
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeProblem(w, problem{
+					Type:   problemRateLimited,
+					Title:  "Too many password attempts",
+					Status: http.StatusTooManyRequests,
+					Detail: "wait and try the password again after Retry-After seconds",
+				})
+				return
+			}
+		}
+
+		share, err := s.Shares.Open(r.Context(), id, password)
+		if err != nil {
+			switch {
+			case errors.Is(err, shares.ErrNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, shares.ErrGone):
+				http.Error(w, err.Error(), http.StatusGone)
+			case errors.Is(err, shares.ErrPasswordRequired), errors.Is(err, shares.ErrWrongPassword):
+				title := "Password required"
+				if errors.Is(err, shares.ErrWrongPassword) {
+					title = "Wrong password"
+				}
+				writeProblem(w, problem{
+					Type:   problemSharePassword,
+					Title:  title,
+					Status: http.StatusUnauthorized,
+					Detail: "send the links password in the X-Share-Password header",
+				})
+			default:
+				logger.Error("open share", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		out := snapshot{
+			Data:      share.Data,
+			IV:        share.IV,
+			ExpiresAt: share.ExpiresAt,
+		}
+		if share.MaxViews > 0 {
+			left := share.MaxViews - share.Views
+			out.ViewsLeft = &left
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/shares"
+)
+
+func sharesRouter(t *testing.T) *routeTest {
+	t.Helper()
+
+	rt := newRouteTest(t)
+	rt.router.Route("/list/shares", func(r chi.Router) {
+		r.Use(rt.auth)
+		shareRoutes(rt.Shares, rt.logger)(r)
+	})
+	rt.router.Route("/shares", func(r chi.Router) {
+		r.Use(rateLimit(rt.shareViews, byAddress(func() bool { return false })))
+		r.Get("/{id}", rt.openShare(byAddress(func() bool { return false }), rt.logger))
+	})
+
+	return rt
+}
+
+// newShareLink creates a link to a snapshot of alices list
+func newShareLink(t *testing.T, rt *routeTest, body string) shares.Share {
+	t.Helper()
+
+	w := rt.serve(userRequest(http.MethodPost, "/list/shares/", "alice", body))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	share := shares.Share{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&share))
+	assert.Equal(t, "/shares/"+share.ID, w.Header().Get("Location"))
+
+	return share
+}
+
+// openRequest opens a link without an account, with the password and from the address when they are set
+func openRequest(link, password, address string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	if password != "" {
+		req.Header.Set("X-Share-Password", password)
+	}
+	if address != "" {
+		req.RemoteAddr = address
+	}
+
+	return req
+}
+
+func TestShareRoutes(t *testing.T) {
+	rt := sharesRouter(t)
+
+	t.Run("protected link", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPost, "/list/shares/", "alice", `{"data":"snapshot","iv":"iv","max_views":1,"password":"hunter2"}`))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NotContains(t, w.Body.String(), "hunter2")
+		assert.NotContains(t, w.Body.String(), "snapshot")
+		share := shares.Share{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&share))
+		assert.True(t, share.Protected)
+		link := "/shares/" + share.ID
+
+		t.Run("without the password", func(t *testing.T) {
+			w := rt.serve(openRequest(link, "", ""))
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "no account is needed, but the password is")
+			assert.Contains(t, w.Body.String(), problemSharePassword)
+		})
+
+		t.Run("wrong password", func(t *testing.T) {
+			w := rt.serve(openRequest(link, "wrong", ""))
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run("open", func(t *testing.T) {
+			w := rt.serve(openRequest(link, "hunter2", ""))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			got := snapshot{}
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Equal(t, "snapshot", got.Data)
+			if assert.NotNil(t, got.ViewsLeft) {
+				assert.Equal(t, 0, *got.ViewsLeft)
+			}
+		})
+
+		t.Run("password attempts are limited per address", func(t *testing.T) {
+			for range passwordBurst - 2 {
+				w := rt.serve(openRequest(link, "guess", ""))
+				assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+			}
+			w := rt.serve(openRequest(link, "guess", ""))
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+			w = rt.serve(openRequest(link, "guess", "192.0.2.2:1234"))
+			assert.NotEqual(t, http.StatusTooManyRequests, w.Code, "another address has its own limit")
+		})
+
+		t.Run("every address together has a ceiling", func(t *testing.T) {
+			limited := false
+			for i := range linkPasswordBurst {
+				w := rt.serve(openRequest(link, "guess", fmt.Sprintf("192.0.2.%d:1234", i+10)))
+				if w.Code == http.StatusTooManyRequests {
+					limited = true
+					break
+				}
+			}
+			assert.True(t, limited)
+		})
+	})
+
+	open := newShareLink(t, rt, `{"data":"open","iv":"iv"}`)
+
+	t.Run("open link", func(t *testing.T) {
+		w := rt.serve(openRequest("/shares/"+open.ID, "", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/list/shares/", "alice", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list []shares.Share
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		assert.Len(t, list, 2)
+	})
+
+	t.Run("only the owner can revoke", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, "/list/shares/"+open.ID, "bob", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, "/list/shares/"+open.ID, "alice", ""))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = rt.serve(openRequest("/shares/"+open.ID, "", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
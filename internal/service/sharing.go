@@ -0,0 +1,195 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+// sharedListRequest is what a client sends to create or update a shared list, the envelope is the
+// content key wrapped for the owners own public key and is only read on create
+type sharedListRequest struct {
+	Data     string `json:"data"`
+	IV       string `json:"iv"`
+	Envelope string `json:"envelope"`
+}
+
+// sharedList is a shared list as one member sees it, with the content key wrapped for them
+type sharedList struct {
+	sharing.List
+	Access   string `json:"access"`
+	Envelope string `json:"envelope"`
+}
+
+// grantRequest is what the owner sends to share a list, the envelope is the content key wrapped
+// for the recipients public key. After revoking someone the owner re-encrypts the list with PUT
+// /lists/{id} and sends their own grant again, with no access, to replace their envelope
+type grantRequest struct {
+	Access   string `json:"access"`
+	Envelope string `json:"envelope"`
+}
+
+// sharingRoutes serves the lists a user shares or has been given, the access in the callers grant is
+// checked on every request and a list they have no grant to is reported as not found
+func (s *Service) sharingRoutes(logger *slog.Logger) func(chi.Router) {
+	failed := func(w http.ResponseWriter, op string, err error) {
+		switch {
+		case errors.Is(err, sharing.ErrInvalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, sharing.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, sharing.ErrForbidden):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, sharing.ErrStale):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case errors.Is(err, sharing.ErrTooManyGrants):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			logger.Error(op, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	return func(r chi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			lists, err := s.Sharing.Accessible(r.Context(), caller(r).Subject)
+			if err != nil {
+				failed(w, "list shared lists", err)
+				return
+			}
+			if lists == nil {
+				lists = []sharing.Summary{}
+			}
+
+			writeJSON(w, http.StatusOK, lists)
+		})
+
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			c := caller(r)
+			req := sharedListRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid list", err)
+				return
+			}
+
+			l, err := s.Sharing.Create(r.Context(), c.Subject, req.Data, req.IV, req.Envelope)
+			if err != nil {
+				failed(w, "create shared list", err)
+				return
+			}
+			s.recordSharedChange(r.Context(), []string{c.Subject}, l.ID, changes.OperationCreate, l.Revision)
+
+			w.Header().Set("Location", "/lists/"+l.ID)
+			w.Header().Set("ETag", l.Revision)
+			writeJSON(w, http.StatusCreated, sharedList{List: l, Access: sharing.AccessOwner, Envelope: req.Envelope})
+		})
+
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			l, g, err := s.Sharing.Get(r.Context(), caller(r).Subject, chi.URLParam(r, "id"))
+			if err != nil {
+				failed(w, "get shared list", err)
+				return
+			}
+
+			w.Header().Set("ETag", l.Revision)
+			writeJSON(w, http.StatusOK, sharedList{List: l, Access: g.Access, Envelope: g.Envelope})
+		})
+
+		r.Put("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			req := sharedListRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid list", err)
+				return
+			}
+
+			l, err := s.Sharing.Update(r.Context(), caller(r).Subject, id, req.Data, req.IV, r.Header.Get("If-Match"))
+			if err != nil {
+				failed(w, "update shared list", err)
+				return
+			}
+			s.recordMembers(r, logger, id, changes.OperationUpdate, l.Revision)
+
+			w.Header().Set("ETag", l.Revision)
+			w.WriteHeader(http.StatusOK)
+		})
+
+		r.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			id := chi.URLParam(r, "id")
+			members, err := s.Sharing.Delete(r.Context(), caller(r).Subject, id)
+			if err != nil {
+				failed(w, "delete shared list", err)
+				return
+			}
+			s.recordSharedChange(r.Context(), members, id, changes.OperationDelete, "")
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		r.Get("/{id}/grants", func(w http.ResponseWriter, r *http.Request) {
+			grants, err := s.Sharing.Grants(r.Context(), caller(r).Subject, chi.URLParam(r, "id"))
+			if err != nil {
+				failed(w, "list grants", err)
+				return
+			}
+
+			writeJSON(w, http.StatusOK, grants)
+		})
+
+		r.Put("/{id}/grants/{recipient}", func(w http.ResponseWriter, r *http.Request) {
+			id, recipient := chi.URLParam(r, "id"), chi.URLParam(r, "recipient")
+			req := grantRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				badBody(w, "invalid grant", err)
+				return
+			}
+
+			g, created, err := s.Sharing.Share(r.Context(), caller(r).Subject, id, recipient, req.Access, req.Envelope)
+			if err != nil {
+				failed(w, "share list", err)
+				return
+			}
+			s.recordSharedChange(r.Context(), []string{recipient}, id, changes.OperationShare, "")
+
+			status := http.StatusOK
+			if created {
+				status = http.StatusCreated
+			}
+			writeJSON(w, status, g)
+		})
+
+		r.Delete("/{id}/grants/{recipient}", func(w http.ResponseWriter, r *http.Request) {
+			id, recipient := chi.URLParam(r, "id"), chi.URLParam(r, "recipient")
+			if err := s.Sharing.Revoke(r.Context(), caller(r).Subject, id, recipient); err != nil {
+				failed(w, "revoke grant", err)
+				return
+			}
+			s.recordSharedChange(r.Context(), []string{recipient}, id, changes.OperationUnshare, "")
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// recordMembers records a change to the shared list for everyone who has access to it
+func (s *Service) recordMembers(r *http.Request, logger *slog.Logger, id, operation, revision string) {
+	members, err := s.Sharing.Members(r.Context(), id)
+	if err != nil {
+		logger.Error("shared list members", "error", err)
+		return
+	}
+
+	s.recordSharedChange(r.Context(), members, id, operation, revision)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+)
+
+func sharingRouter(t *testing.T) *routeTest {
+	t.Helper()
+
+	rt := newRouteTest(t)
+	rt.router.Route("/lists", func(r chi.Router) {
+		r.Use(rt.auth)
+		rt.sharingRoutes(rt.logger)(r)
+	})
+
+	return rt
+}
+
+// createShared creates a list owned by alice with the envelope, returning its path
+func createShared(t *testing.T, rt *routeTest, envelope string) (sharedList, string) {
+	t.Helper()
+
+	w := rt.serve(userRequest(http.MethodPost, "/lists/", "alice", `{"data":"secret","iv":"iv","envelope":"`+envelope+`"}`))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	created := sharedList{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	path := "/lists/" + created.ID
+	assert.Equal(t, path, w.Header().Get("Location"))
+
+	return created, path
+}
+
+func TestSharingRoutes(t *testing.T) {
+	rt := sharingRouter(t)
+	created, path := createShared(t, rt, "YWxpY2U=")
+
+	t.Run("not visible before it is shared", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, path, "bob", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("share", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, path+"/grants/bob", "alice", `{"access":"read","envelope":"Ym9i"}`))
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = rt.serve(userRequest(http.MethodGet, path, "bob", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		got := sharedList{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal(t, "Ym9i", got.Envelope)
+		assert.Equal(t, sharing.AccessRead, got.Access)
+		assert.Equal(t, created.Revision, w.Header().Get("ETag"))
+	})
+
+	t.Run("read access can't write", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, path, "bob", `{"data":"changed","iv":"iv2"}`))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("only the owner can share", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, path+"/grants/carol", "bob", `{"access":"read","envelope":"Y2Fyb2w="}`))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("update from a stale revision", func(t *testing.T) {
+		req := userRequest(http.MethodPut, path, "alice", `{"data":"changed","iv":"iv2"}`)
+		req.Header.Set("If-Match", `"stale"`)
+		w := rt.serve(req)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		req := userRequest(http.MethodPut, path, "alice", `{"data":"changed","iv":"iv2"}`)
+		req.Header.Set("If-Match", created.Revision)
+		w := rt.serve(req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("members see the changes", func(t *testing.T) {
+		page, err := rt.Changes.Since(t.Context(), "bob", "", 10)
+		assert.NoError(t, err)
+		if assert.Len(t, page.Changes, 2) {
+			assert.Equal(t, changes.OperationShare, page.Changes[0].Operation)
+			assert.Equal(t, changes.OperationUpdate, page.Changes[1].Operation)
+			assert.Equal(t, created.ID, page.Changes[1].ID)
+		}
+	})
+
+	t.Run("accessible lists", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, "/lists/", "bob", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var summaries []sharing.Summary
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&summaries))
+		assert.Len(t, summaries, 1)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, path+"/grants/bob", "alice", ""))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = rt.serve(userRequest(http.MethodGet, path, "bob", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, path, "alice", ""))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
+
+func TestSharingRotate(t *testing.T) {
+	rt := sharingRouter(t)
+	created, path := createShared(t, rt, "b2xkLWFsaWNl")
+
+	w := rt.serve(userRequest(http.MethodPut, path+"/grants/bob", "alice", `{"access":"write","envelope":"b2xkLWJvYg=="}`))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// bob is removed, so the list is re-encrypted under a new key that is wrapped for alice again
+	t.Run("revoke", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodDelete, path+"/grants/bob", "alice", ""))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("re-encrypt", func(t *testing.T) {
+		req := userRequest(http.MethodPut, path, "alice", `{"data":"rotated","iv":"iv2"}`)
+		req.Header.Set("If-Match", created.Revision)
+		w := rt.serve(req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("replace the owners envelope", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, path+"/grants/alice", "alice", `{"envelope":"bmV3LWFsaWNl"}`))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("the owner can't change their access", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodPut, path+"/grants/alice", "alice", `{"access":"read","envelope":"bmV3LWFsaWNl"}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("the owner reads with the new key", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, path, "alice", ""))
+		assert.Equal(t, http.StatusOK, w.Code)
+		got := sharedList{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal(t, "rotated", got.Data)
+		assert.Equal(t, "bmV3LWFsaWNl", got.Envelope)
+		assert.Equal(t, sharing.AccessOwner, got.Access)
+	})
+
+	t.Run("the revoked member can't read", func(t *testing.T) {
+		w := rt.serve(userRequest(http.MethodGet, path, "bob", ""))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
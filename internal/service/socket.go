@@ -0,0 +1,255 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/breaker"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"golang.org/x/net/websocket"
+)
+
+// Socket message types
+const (
+	socketUpdate = "update"
+	socketAck    = "ack"
+	socketError  = "error"
+	socketClose  = "close"
+)
+
+// Socket error codes
+const (
+	socketInvalid     = "invalid"
+	socketConflict    = "conflict"
+	socketMaintenance = "maintenance"
+	socketUnavailable = "unavailable"
+	socketInternal    = "internal"
+	socketUnsupported = "unsupported"
+	socketTooSlow     = "too-slow"
+	socketRevoked     = "revoked"
+)
+
+// socketMessage is every message on /ws, which fields are set depends on the type,
+// clients send update and get back ack or error, and list events from every device including their own
+type socketMessage struct {
+	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"`
+	Data     string `json:"data,omitempty"`
+	IV       string `json:"iv,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	EventID  uint64 `json:"event_id,omitempty"`
+	// Sibling is set on an ack for an update made from a stale revision, which was kept as a sibling
+	// of the list instead of being written
+	Sibling string `json:"sibling,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// listSocket upgrades to the /ws sync channel, the caller is authenticated on the upgrade request.
+// Browsers don't apply cors to websockets so the origin is checked against the cors policy here.
+// A socket subscribes to the same hub as /list/events, so it counts towards SSE_MAX_STREAMS_PER_USER
+func (s *Service) listSocket(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.current()
+		if origin := r.Header.Get("Origin"); origin != "" && !originAllowed(cfg.CORS, origin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		c := caller(r)
+		sub, _, err := s.Events.Subscribe(c.Subject, 0)
+		if err != nil {
+			if errors.Is(err, events.ErrTooManyStreams) {
+				w.Header().Set("Retry-After", strconv.Itoa(sseRetry/1000))
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer sub.Close()
+
+		websocket.Server{
+			// the origin has already been checked, clients that aren't browsers don't send one
+			Handshake: func(*websocket.Config, *http.Request) error {
+				return nil
+			},
+			Handler: func(ws *websocket.Conn) {
+				s.serveSocket(r.Context(), ws, cfg, c, sub, logger)
+			},
+		}.ServeHTTP(liveWriter{ResponseWriter: w, timeout: 2 * cfg.WebSocket.PingInterval}, r)
+	}
+}
+
+// liveWriter hands the websocket server a connection whose read deadline is pushed back whenever
+// anything arrives, pongs included, which x/net/websocket reads without ever returning them. A client
+// that stops answering pings, such as one whose network went away, is closed once the deadline passes
+type liveWriter struct {
+	http.ResponseWriter
+	timeout time.Duration
+}
+
+// Hijack implements http.Hijacker
+func (w liveWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	live := &liveConn{Conn: conn, timeout: w.timeout}
+	// anything the server had already buffered is read before the connection itself
+	reader := io.MultiReader(io.LimitReader(buf.Reader, int64(buf.Reader.Buffered())), live)
+
+	return live, bufio.NewReadWriter(bufio.NewReader(reader), buf.Writer), nil
+}
+
+type liveConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *liveConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+
+	return n, err
+}
+
+// serveSocket reads updates one at a time, so a client sending faster than they can be applied is
+// slowed down by tcp, while a single writer sends replies, events and pings
+func (s *Service) serveSocket(ctx context.Context, ws *websocket.Conn, cfg *config.Config, c api.Caller, sub *events.Subscription, logger *slog.Logger) {
+	ws.MaxPayloadBytes = int(cfg.Server.MaxBodyBytes)
+	// the http servers deadlines carry over to the hijacked connection, the read deadline is replaced by
+	// one that each frame from the client pushes back
+	_ = ws.SetWriteDeadline(time.Time{})
+	_ = ws.SetReadDeadline(time.Now().Add(2 * cfg.WebSocket.PingInterval))
+
+	var once sync.Once
+	closeSocket := func() {
+		once.Do(func() {
+			_ = ws.Close()
+		})
+	}
+	defer closeSocket()
+
+	out := make(chan socketMessage, cfg.WebSocket.SendBuffer)
+	done := make(chan struct{})
+	go func() {
+		defer closeSocket()
+		s.writeSocket(ws, cfg.WebSocket, out, sub, done)
+	}()
+	defer close(done)
+
+	for {
+		in := socketMessage{}
+		if err := websocket.JSON.Receive(ws, &in); err != nil {
+			return
+		}
+
+		select {
+		case out <- s.handleSocketMessage(ctx, c, in, logger):
+		default:
+			// the client isn't reading its acks
+			return
+		}
+	}
+}
+
+func (s *Service) writeSocket(ws *websocket.Conn, cfg config.WebSocket, out <-chan socketMessage, sub *events.Subscription, done <-chan struct{}) {
+	ping := time.NewTicker(cfg.PingInterval)
+	defer ping.Stop()
+
+	send := func(m socketMessage) bool {
+		_ = ws.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+		return websocket.JSON.Send(ws, m) == nil
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.stopping:
+			send(socketMessage{Type: socketClose, Message: "server is shutting down"})
+			return
+		case m := <-out:
+			if !send(m) || m.Code == socketRevoked {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				send(socketMessage{Type: socketError, Code: socketTooSlow, Message: "fell too far behind, reconnect to catch up"})
+				return
+			}
+			if !send(socketMessage{Type: e.Type, Revision: e.ETag, EventID: e.ID}) {
+				return
+			}
+		case <-ping.C:
+			// only this goroutine uses Write, so switching the payload type is safe
+			_ = ws.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+			ws.PayloadType = websocket.PingFrame
+			_, err := ws.Write(nil)
+			ws.PayloadType = websocket.TextFrame
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleSocketMessage applies an update the same way PUT /list does and builds the reply
+func (s *Service) handleSocketMessage(ctx context.Context, c api.Caller, in socketMessage, logger *slog.Logger) socketMessage {
+	reply := func(code, message string) socketMessage {
+		return socketMessage{Type: socketError, ID: in.ID, Code: code, Message: message}
+	}
+
+	if in.Type != socketUpdate {
+		return reply(socketUnsupported, "unsupported message type")
+	}
+	if s.Maintenance != nil && s.Maintenance.Get().Mode != config.MaintenanceOff {
+		return reply(socketMaintenance, "changes are paused for maintenance")
+	}
+	// the device was checked when the socket opened, but it may have been revoked since
+	if s.Devices != nil {
+		if _, err := s.Devices.Check(ctx, c.Subject, c.Device); err != nil {
+			if errors.Is(err, devices.ErrNotFound) || errors.Is(err, devices.ErrRevoked) || errors.Is(err, devices.ErrRequired) {
+				return reply(socketRevoked, err.Error())
+			}
+			return reply(socketUnavailable, "unable to check the device")
+		}
+	}
+
+	written, err := s.updateList(ctx, c.Subject, in.Data, in.IV, in.Revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidList):
+			return reply(socketInvalid, err.Error())
+		case errors.Is(err, conflicts.ErrTooMany):
+			return reply(socketConflict, err.Error())
+		}
+		if b := s.Connections.Breaker("todo"); b != nil && b.State() == breaker.Open {
+			return reply(socketUnavailable, "the list service is unavailable")
+		}
+		logger.Error("socket update", "error", err)
+		return reply(socketInternal, "unable to update the list")
+	}
+
+	if written.sibling != nil {
+		return socketMessage{Type: socketAck, ID: in.ID, Revision: written.sibling.Revision, Sibling: written.sibling.ID}
+	}
+
+	return socketMessage{Type: socketAck, ID: in.ID, Revision: written.list.ETag()}
+}
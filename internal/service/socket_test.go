@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/todo-lists-app/protobufs/generated/todo/v1"
+	"github.com/todo-lists-app/todo-lists-api/internal/api"
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+	"github.com/todo-lists-app/todo-lists-api/internal/events"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+)
+
+// fakeTodo keeps one list per user in memory
+type fakeTodo struct {
+	pb.UnimplementedTodoServiceServer
+
+	mu    sync.Mutex
+	lists map[string]*pb.TodoRetrieveResponse
+}
+
+func (f *fakeTodo) Get(_ context.Context, in *pb.TodoGetRequest) (*pb.TodoRetrieveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if l, ok := f.lists[in.GetUserId()]; ok {
+		return l, nil
+	}
+	return &pb.TodoRetrieveResponse{UserId: in.GetUserId()}, nil
+}
+
+func (f *fakeTodo) Update(_ context.Context, in *pb.TodoInjectRequest) (*pb.TodoRetrieveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l := &pb.TodoRetrieveResponse{UserId: in.GetUserId(), Data: in.GetData(), Iv: in.GetIv()}
+	f.lists[in.GetUserId()] = l
+	return l, nil
+}
+
+func (f *fakeTodo) Insert(ctx context.Context, in *pb.TodoInjectRequest) (*pb.TodoRetrieveResponse, error) {
+	return f.Update(ctx, in)
+}
+
+func (f *fakeTodo) Delete(_ context.Context, in *pb.TodoDeleteRequest) (*pb.TodoRetrieveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.lists, in.GetUserId())
+	return &pb.TodoRetrieveResponse{UserId: in.GetUserId()}, nil
+}
+
+func socketServer(t *testing.T) (*Service, *httptest.Server, chan struct{}) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	g := grpc.NewServer()
+	pb.RegisterTodoServiceServer(g, &fakeTodo{lists: map[string]*pb.TodoRetrieveResponse{}})
+	go func() {
+		_ = g.Serve(lis)
+	}()
+	t.Cleanup(g.Stop)
+
+	cfg := &config.Config{}
+	cfg.Services.Todo = lis.Addr().String()
+	cfg.CORS.AllowedOrigins = []string{"https://todo-list.app"}
+	cfg.Server.MaxBodyBytes = 1 << 20
+	cfg.Events = config.Events{MaxStreamsPerUser: 2, Heartbeat: time.Second, Backlog: 10}
+	cfg.WebSocket = config.WebSocket{PingInterval: time.Second, WriteTimeout: time.Second, SendBuffer: 4}
+
+	conns := api.NewConnections(*cfg, slog.New(slog.DiscardHandler))
+	t.Cleanup(func() {
+		_ = conns.Close()
+	})
+
+	stopping := make(chan struct{})
+	s := &Service{
+		Config:      cfg,
+		Connections: conns,
+		Maintenance: newMaintenance(config.Maintenance{Mode: config.MaintenanceOff, RetryAfter: time.Minute}),
+		Events:      events.NewHub(cfg.Events.MaxStreamsPerUser, cfg.Events.Backlog, time.Minute),
+		Changes:     changes.NewFeed(changes.NewMemoryStore(time.Hour)),
+		Devices:     devices.NewRegistry(devices.NewMemoryStore(), 5, time.Minute, slog.New(slog.DiscardHandler)),
+		Conflicts:   conflicts.NewTracker(conflicts.NewMemoryStore(), 2),
+		stopping:    stopping,
+		changesLog:  slog.New(slog.DiscardHandler),
+	}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			ctx := api.WithCaller(r.Context(), api.Caller{Subject: q.Get("subject"), Device: q.Get("device")})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Get("/ws", s.listSocket(slog.New(slog.DiscardHandler)))
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	return s, srv, stopping
+}
+
+func dialSocket(t *testing.T, srv *httptest.Server, subject string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?subject=" + subject
+	ws, err := websocket.Dial(url, "", "https://todo-list.app")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = ws.Close()
+	})
+	_ = ws.SetDeadline(time.Now().Add(5 * time.Second))
+
+	return ws
+}
+
+// receive skips list events until a message of the given type arrives
+func receive(t *testing.T, ws *websocket.Conn, kind string) socketMessage {
+	t.Helper()
+
+	for {
+		m := socketMessage{}
+		if err := websocket.JSON.Receive(ws, &m); err != nil {
+			t.Fatalf("waiting for %s: %v", kind, err)
+		}
+		if m.Type == kind {
+			return m
+		}
+	}
+}
+
+func TestListSocket(t *testing.T) {
+	s, srv, _ := socketServer(t)
+
+	phone := dialSocket(t, srv, "alice")
+	laptop := dialSocket(t, srv, "alice")
+
+	assert.NoError(t, websocket.JSON.Send(phone, socketMessage{Type: socketUpdate, ID: "1", Data: "cipher", IV: "iv"}))
+	ack := receive(t, phone, socketAck)
+	assert.Equal(t, "1", ack.ID)
+	assert.Equal(t, (&api.StoredList{Data: "cipher", IV: "iv"}).ETag(), ack.Revision)
+
+	update := receive(t, laptop, events.ListUpdated)
+	assert.Equal(t, ack.Revision, update.Revision)
+	assert.NotZero(t, update.EventID)
+
+	page, err := s.Changes.Since(context.Background(), "alice", "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, page.Changes, 1) {
+		assert.Equal(t, changes.OperationUpdate, page.Changes[0].Operation)
+		assert.Equal(t, ack.Revision, page.Changes[0].Revision)
+	}
+
+	t.Run("stale revision", func(t *testing.T) {
+		assert.NoError(t, websocket.JSON.Send(laptop, socketMessage{Type: socketUpdate, ID: "2", Data: "offline", IV: "iv", Revision: `"stale"`}))
+		m := receive(t, laptop, socketAck)
+		assert.Equal(t, "2", m.ID)
+		assert.NotEmpty(t, m.Sibling)
+
+		assert.Equal(t, m.Revision, receive(t, phone, events.ListConflict).Revision)
+	})
+
+	t.Run("current revision", func(t *testing.T) {
+		assert.NoError(t, websocket.JSON.Send(laptop, socketMessage{Type: socketUpdate, ID: "3", Data: "other", IV: "iv", Revision: ack.Revision}))
+		m := receive(t, laptop, socketAck)
+		assert.Equal(t, "3", m.ID)
+		assert.NotEqual(t, ack.Revision, m.Revision)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.NoError(t, websocket.JSON.Send(phone, socketMessage{Type: socketUpdate, ID: "4", Data: "cipher"}))
+		assert.Equal(t, socketInvalid, receive(t, phone, socketError).Code)
+
+		assert.NoError(t, websocket.JSON.Send(phone, socketMessage{Type: "delete", ID: "5"}))
+		assert.Equal(t, socketUnsupported, receive(t, phone, socketError).Code)
+	})
+
+	t.Run("maintenance", func(t *testing.T) {
+		s.Maintenance.Set(config.Maintenance{Mode: config.MaintenanceReadOnly, RetryAfter: time.Minute})
+		defer s.Maintenance.Set(config.Maintenance{Mode: config.MaintenanceOff, RetryAfter: time.Minute})
+
+		assert.NoError(t, websocket.JSON.Send(phone, socketMessage{Type: socketUpdate, ID: "6", Data: "cipher", IV: "iv"}))
+		assert.Equal(t, socketMaintenance, receive(t, phone, socketError).Code)
+	})
+}
+
+func TestListSocketOrigin(t *testing.T) {
+	_, srv, _ := socketServer(t)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?subject=alice"
+	_, err := websocket.Dial(url, "", "https://evil.example")
+	assert.Error(t, err)
+}
+
+func TestListSocketLimit(t *testing.T) {
+	_, srv, _ := socketServer(t)
+
+	dialSocket(t, srv, "alice")
+	dialSocket(t, srv, "alice")
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/ws?subject=alice", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func TestListSocketShutdown(t *testing.T) {
+	_, srv, stopping := socketServer(t)
+
+	ws := dialSocket(t, srv, "alice")
+	close(stopping)
+
+	assert.Equal(t, socketClose, receive(t, ws, socketClose).Type)
+	m := socketMessage{}
+	assert.Error(t, websocket.JSON.Receive(ws, &m))
+}
+
+func TestListSocketRevoked(t *testing.T) {
+	s, srv, _ := socketServer(t)
+
+	d, err := s.Devices.Register(context.Background(), "alice", devices.Device{Name: "Phone", Platform: "ios"})
+	assert.NoError(t, err)
+	ws := dialSocket(t, srv, "alice&device="+d.ID)
+
+	assert.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: socketUpdate, ID: "1", Data: "cipher", IV: "iv"}))
+	receive(t, ws, socketAck)
+
+	assert.NoError(t, s.Devices.Revoke(context.Background(), "alice", d.ID))
+	assert.NoError(t, websocket.JSON.Send(ws, socketMessage{Type: socketUpdate, ID: "2", Data: "other", IV: "iv"}))
+	assert.Equal(t, socketRevoked, receive(t, ws, socketError).Code)
+
+	m := socketMessage{}
+	assert.Error(t, websocket.JSON.Receive(ws, &m), "the socket is closed once the device is revoked")
+}
+
+func TestListSocketReadDeadline(t *testing.T) {
+	s, srv, _ := socketServer(t)
+	s.Config.WebSocket.PingInterval = 20 * time.Millisecond
+
+	t.Run("a client answering pings stays open", func(t *testing.T) {
+		ws := dialSocket(t, srv, "alice")
+		go func() {
+			// reading is what answers the pings
+			m := socketMessage{}
+			for websocket.JSON.Receive(ws, &m) == nil {
+			}
+		}()
+
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(t, 1, s.Events.Streams("alice"))
+	})
+
+	t.Run("a client that stops answering is closed", func(t *testing.T) {
+		dialSocket(t, srv, "bob")
+		assert.Equal(t, 1, s.Events.Streams("bob"))
+
+		assert.Eventually(t, func() bool {
+			return s.Events.Streams("bob") == 0
+		}, time.Second, 10*time.Millisecond, "the stream is given back once the deadline passes")
+	})
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/changes"
+	"github.com/todo-lists-app/todo-lists-api/internal/config"
+	"github.com/todo-lists-app/todo-lists-api/internal/conflicts"
+	"github.com/todo-lists-app/todo-lists-api/internal/devices"
+	"github.com/todo-lists-app/todo-lists-api/internal/inbox"
+	"github.com/todo-lists-app/todo-lists-api/internal/invites"
+	"github.com/todo-lists-app/todo-lists-api/internal/keys"
+	"github.com/todo-lists-app/todo-lists-api/internal/mail"
+	"github.com/todo-lists-app/todo-lists-api/internal/shares"
+	"github.com/todo-lists-app/todo-lists-api/internal/sharing"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// storageTimeout bounds connecting and creating indexes at startup
+const storageTimeout = 10 * time.Second
+
+// openStorage connects to the api's own database, there is none when no url is configured
+func openStorage(ctx context.Context, cfg config.Storage) (*mongo.Database, error) {
+	if cfg.MongoURL == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURL))
+	if err != nil {
+		return nil, fmt.Errorf("connect to storage: %v", err)
+	}
+
+	return client.Database(cfg.Database), nil
+}
+
+// newChangeFeed opens the change feed, changes are only kept in memory when there is no database
+func newChangeFeed(ctx context.Context, db *mongo.Database, cfg config.Changes, logger *slog.Logger) (*changes.Feed, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, changes are kept in memory and cursors expire on a restart")
+		return changes.NewFeed(changes.NewMemoryStore(cfg.Retention)), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := changes.NewMongoStore(ctx, db, cfg.Collection, int32(cfg.Retention/time.Second), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes.NewFeed(store), nil
+}
+
+// newDeviceRegistry opens the device registry, devices are only kept in memory when there is no database
+func newDeviceRegistry(ctx context.Context, db *mongo.Database, cfg config.Devices, logger *slog.Logger) (*devices.Registry, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, devices are kept in memory and have to register again after a restart")
+		return devices.NewRegistry(devices.NewMemoryStore(), cfg.MaxPerUser, cfg.LastSeenInterval, logger), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := devices.NewMongoStore(ctx, db, cfg.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return devices.NewRegistry(store, cfg.MaxPerUser, cfg.LastSeenInterval, logger), nil
+}
+
+// newConflictTracker opens the sibling store, siblings are only kept in memory when there is no database
+func newConflictTracker(ctx context.Context, db *mongo.Database, cfg config.Conflicts, logger *slog.Logger) (*conflicts.Tracker, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, conflicting versions are kept in memory and are lost on a restart")
+		return conflicts.NewTracker(conflicts.NewMemoryStore(), cfg.MaxSiblings), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := conflicts.NewMongoStore(ctx, db, cfg.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts.NewTracker(store, cfg.MaxSiblings), nil
+}
+
+// newSharedLists opens the shared lists, they are only kept in memory when there is no database
+func newSharedLists(ctx context.Context, db *mongo.Database, cfg config.Sharing, logger *slog.Logger) (*sharing.Lists, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, shared lists are kept in memory and are lost on a restart")
+		return sharing.NewLists(sharing.NewMemoryStore(), cfg.MaxGrants), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := sharing.NewMongoStore(ctx, db, cfg.ListsCollection, cfg.GrantsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharing.NewLists(store, cfg.MaxGrants), nil
+}
+
+// newKeyDirectory opens the key directory, keys are only kept in memory when there is no database
+func newKeyDirectory(ctx context.Context, db *mongo.Database, cfg config.Keys, logger *slog.Logger) (*keys.Directory, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, published keys are kept in memory and their history is lost on a restart")
+		return keys.NewDirectory(keys.NewMemoryStore()), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := keys.NewMongoStore(ctx, db, cfg.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.NewDirectory(store), nil
+}
+
+// newKeyEmails opens the verified emails, they are only kept in memory when there is no database
+func newKeyEmails(ctx context.Context, db *mongo.Database, cfg config.Keys, logger *slog.Logger) (*keys.Emails, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, verified emails are kept in memory and have to be verified again after a restart")
+		return keys.NewEmails(keys.NewMemoryEmailStore(), cfg.EmailTTL), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := keys.NewMongoEmailStore(ctx, db, cfg.EmailsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.NewEmails(store, cfg.EmailTTL), nil
+}
+
+// newInvites opens the invites, they are only kept in memory when there is no database
+func newInvites(ctx context.Context, db *mongo.Database, cfg config.Invites, logger *slog.Logger) (*invites.Invites, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, invites are kept in memory and can't be accepted after a restart")
+		return invites.NewInvites(invites.NewMemoryStore(), cfg.TTL, cfg.MaxPending), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := invites.NewMongoStore(ctx, db, cfg.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return invites.NewInvites(store, cfg.TTL, cfg.MaxPending), nil
+}
+
+// newShares opens the public links, they are only kept in memory when there is no database
+func newShares(ctx context.Context, db *mongo.Database, cfg config.Shares, logger *slog.Logger) (*shares.Shares, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, public links are kept in memory and stop working after a restart")
+		return shares.NewShares(shares.NewMemoryStore(), cfg.DefaultTTL, cfg.MaxTTL), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := shares.NewMongoStore(ctx, db, cfg.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return shares.NewShares(store, cfg.DefaultTTL, cfg.MaxTTL), nil
+}
+
+// newInbox opens the inboxes, they are only kept in memory when there is no database
+func newInbox(ctx context.Context, db *mongo.Database, cfg config.Inbox, logger *slog.Logger) (*inbox.Inbox, error) {
+	if db == nil {
+		logger.Warn("no STORAGE_MONGO_URL, inboxes are kept in memory and lost on restart")
+		return inbox.NewInbox(inbox.NewMemoryStore(), cfg.MaxItems, cfg.MaxItemBytes), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+	store, err := inbox.NewMongoStore(ctx, db, cfg.AddressesCollection, cfg.ItemsCollection, int32(cfg.Retention/time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	return inbox.NewInbox(store, cfg.MaxItems, cfg.MaxItemBytes), nil
+}
+
+// newMailer sends through the smtp server, emails are only logged when there is none
+func newMailer(cfg config.Mail, logger *slog.Logger) (mail.Sender, error) {
+	if cfg.Host == "" {
+		logger.Warn("no MAIL_SMTP_HOST, emails such as invites are logged instead of sent")
+		return mail.Log{Logger: logger}, nil
+	}
+
+	return mail.NewSMTP(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From, cfg.Timeout)
+}
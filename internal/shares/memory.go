@@ -0,0 +1,106 @@
+package shares
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps shares in memory, for development and tests
+type MemoryStore struct {
+	mu     sync.Mutex
+	shares map[string]Share
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		shares: make(map[string]Share),
+	}
+}
+
+// Insert implements Store
+func (m *MemoryStore) Insert(_ context.Context, s Share) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.shares[s.ID] = s
+	return nil
+}
+
+// Get implements Store
+func (m *MemoryStore) Get(_ context.Context, id string) (Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.shares[id]
+	if !ok {
+		return Share{}, ErrNotFound
+	}
+
+	return s, nil
+}
+
+// ForOwner implements Store
+func (m *MemoryStore) ForOwner(_ context.Context, owner string) ([]Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Share
+	for _, s := range m.shares {
+		if s.Owner == owner {
+			out = append(out, s)
+		}
+	}
+	slices.SortFunc(out, func(a, b Share) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return out, nil
+}
+
+// View implements Store
+func (m *MemoryStore) View(_ context.Context, id string, now time.Time) (Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.shares[id]
+	if !ok {
+		return Share{}, ErrNotFound
+	}
+	if !s.available(now) {
+		return Share{}, ErrGone
+	}
+
+	s.Views++
+	m.shares[id] = s
+	return s, nil
+}
+
+// Delete implements Store
+func (m *MemoryStore) Delete(_ context.Context, owner, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.shares[id]; !ok || s.Owner != owner {
+		return ErrNotFound
+	}
+
+	delete(m.shares, id)
+	return nil
+}
+
+// DeleteForOwner implements Store
+func (m *MemoryStore) DeleteForOwner(_ context.Context, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, s := range m.shares {
+		if s.Owner == owner {
+			delete(m.shares, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package shares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps shares in a collection, mongo drops them once they have expired
+type MongoStore struct {
+	shares *mongo.Collection
+}
+
+// NewMongoStore makes sure the indexes exist
+func NewMongoStore(ctx context.Context, db *mongo.Database, collection string) (*MongoStore, error) {
+	m := &MongoStore{
+		shares: db.Collection(collection),
+	}
+
+	if _, err := m.shares.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "owner", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("create share indexes: %v", err)
+	}
+
+	return m, nil
+}
+
+// Insert implements Store
+func (m *MongoStore) Insert(ctx context.Context, s Share) error {
+	if _, err := m.shares.InsertOne(ctx, s); err != nil {
+		return fmt.Errorf("insert share: %v", err)
+	}
+
+	return nil
+}
+
+// Get implements Store
+func (m *MongoStore) Get(ctx context.Context, id string) (Share, error) {
+	s := Share{}
+	if err := m.shares.FindOne(ctx, bson.M{"_id": id}).Decode(&s); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Share{}, ErrNotFound
+		}
+		return Share{}, fmt.Errorf("find share: %v", err)
+	}
+
+	return s, nil
+}
+
+// ForOwner implements Store
+func (m *MongoStore) ForOwner(ctx context.Context, owner string) ([]Share, error) {
+	cur, err := m.shares.Find(ctx, bson.M{"owner": owner}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find shares: %v", err)
+	}
+
+	var shares []Share
+	if err := cur.All(ctx, &shares); err != nil {
+		return nil, fmt.Errorf("read shares: %v", err)
+	}
+
+	return shares, nil
+}
+
+// View implements Store, the view is only counted while the share is available so the last view
+// can't be handed out twice
+func (m *MongoStore) View(ctx context.Context, id string, now time.Time) (Share, error) {
+	s := Share{}
+	err := m.shares.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":        id,
+			"expires_at": bson.M{"$gt": now},
+			"$or": bson.A{
+				bson.M{"max_views": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$views", "$max_views"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"views": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&s)
+	if err == nil {
+		return s, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return Share{}, fmt.Errorf("view share: %v", err)
+	}
+
+	if _, err := m.Get(ctx, id); err != nil {
+		return Share{}, err
+	}
+	return Share{}, ErrGone
+}
+
+// Delete implements Store
+func (m *MongoStore) Delete(ctx context.Context, owner, id string) error {
+	res, err := m.shares.DeleteOne(ctx, bson.M{"_id": id, "owner": owner})
+	if err != nil {
+		return fmt.Errorf("delete share: %v", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteForOwner implements Store
+func (m *MongoStore) DeleteForOwner(ctx context.Context, owner string) error {
+	if _, err := m.shares.DeleteMany(ctx, bson.M{"owner": owner}); err != nil {
+		return fmt.Errorf("delete shares: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package shares keeps read-only public links to a snapshot of a list. The client encrypts the snapshot
+// with a fresh key that only ever goes in the links fragment, so the server can hand the ciphertext to
+// anyone with the link without being able to read it
+package shares
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Password hashing, iterations follow the owasp advice for pbkdf2 with sha-256
+const (
+	passwordIterations = 600_000
+	passwordKeyLength  = 32
+	maxPassword        = 1024
+)
+
+var (
+	// ErrNotFound is returned for a link that doesn't exist or was revoked
+	ErrNotFound = errors.New("share not found")
+	// ErrGone is returned for a link that has expired or been viewed as many times as it allows
+	ErrGone = errors.New("share is no longer available")
+	// ErrPasswordRequired is returned when opening a link that has a password without one
+	ErrPasswordRequired = errors.New("share needs a password")
+	// ErrWrongPassword is returned when the password doesn't match
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrInvalid is wrapped by every problem with a new share
+	ErrInvalid = errors.New("invalid share")
+)
+
+// Share is a snapshot of a list behind a public link, MaxViews is 0 when it can be viewed any number of times
+type Share struct {
+	ID        string    `json:"id" bson:"_id"`
+	Owner     string    `json:"-" bson:"owner"`
+	Data      string    `json:"-" bson:"data"`
+	IV        string    `json:"-" bson:"iv"`
+	Password  string    `json:"-" bson:"password,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	MaxViews  int       `json:"max_views,omitempty" bson:"max_views"`
+	Views     int       `json:"views" bson:"views"`
+	Protected bool      `json:"password" bson:"-"`
+}
+
+// available reports whether the share can still be viewed
+func (s Share) available(now time.Time) bool {
+	return now.Before(s.ExpiresAt) && (s.MaxViews == 0 || s.Views < s.MaxViews)
+}
+
+// Store keeps the shares
+type Store interface {
+	Insert(ctx context.Context, s Share) error
+	// Get returns ErrNotFound when there is no such share
+	Get(ctx context.Context, id string) (Share, error)
+	ForOwner(ctx context.Context, owner string) ([]Share, error)
+	// View counts a view while the share is still available, returning ErrGone when it isn't
+	View(ctx context.Context, id string, now time.Time) (Share, error)
+	// Delete returns ErrNotFound when the owner has no such share
+	Delete(ctx context.Context, owner, id string) error
+	DeleteForOwner(ctx context.Context, owner string) error
+}
+
+// Shares creates and opens links
+type Shares struct {
+	store Store
+
+	mu         sync.RWMutex
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+// NewShares creates the shares over the store
+func NewShares(store Store, defaultTTL, maxTTL time.Duration) *Shares {
+	return &Shares{
+		store:      store,
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+	}
+}
+
+// SetLimits changes how long new links last, for a config reload
+func (s *Shares) SetLimits(defaultTTL, maxTTL time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaultTTL = defaultTTL
+	s.maxTTL = maxTTL
+}
+
+// Create stores the snapshot, a zero expiresAt uses the default lifetime and an empty password
+// leaves the link open to anyone who has it
+func (s *Shares) Create(ctx context.Context, owner, data, iv string, expiresAt time.Time, maxViews int, password string) (Share, error) {
+	s.mu.RLock()
+	defaultTTL, maxTTL := s.defaultTTL, s.maxTTL
+	s.mu.RUnlock()
+
+	now := time.Now().UTC()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(defaultTTL)
+	}
+
+	var errs []error
+	if data == "" || iv == "" {
+		errs = append(errs, fmt.Errorf("%w: data and iv are required", ErrInvalid))
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxTTL)) {
+		errs = append(errs, fmt.Errorf("%w: expires_at has to be in the next %s", ErrInvalid, maxTTL))
+	}
+	if maxViews < 0 {
+		errs = append(errs, fmt.Errorf("%w: max_views can't be negative", ErrInvalid))
+	}
+	if len(password) > maxPassword {
+		errs = append(errs, fmt.Errorf("%w: password can be at most %d characters", ErrInvalid, maxPassword))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Share{}, err
+	}
+
+	share := Share{
+		ID:        rand.Text(),
+		Owner:     owner,
+		Data:      data,
+		IV:        iv,
+		CreatedAt: now,
+		ExpiresAt: expiresAt.UTC(),
+		MaxViews:  maxViews,
+	}
+	if password != "" {
+		hashed, err := hashPassword(password)
+		if err != nil {
+			return Share{}, err
+		}
+		share.Password = hashed
+	}
+	if err := s.store.Insert(ctx, share); err != nil {
+		return Share{}, err
+	}
+	share.Protected = share.Password != ""
+
+	return share, nil
+}
+
+// Open checks the password and counts a view, a view is only used up once the password matches
+func (s *Shares) Open(ctx context.Context, id, password string) (Share, error) {
+	share, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Share{}, err
+	}
+	if !share.available(time.Now()) {
+		return Share{}, ErrGone
+	}
+	if share.Password != "" {
+		if password == "" {
+			return Share{}, ErrPasswordRequired
+		}
+		ok, err := checkPassword(share.Password, password)
+		if err != nil {
+			return Share{}, err
+		}
+		if !ok {
+			return Share{}, ErrWrongPassword
+		}
+	}
+
+	viewed, err := s.store.View(ctx, id, time.Now())
+	if err != nil {
+		return Share{}, err
+	}
+	viewed.Protected = viewed.Password != ""
+
+	return viewed, nil
+}
+
+// ForOwner returns the owners links, including ones that can no longer be viewed but are still kept
+func (s *Shares) ForOwner(ctx context.Context, owner string) ([]Share, error) {
+	list, err := s.store.ForOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Protected = list[i].Password != ""
+	}
+
+	return list, nil
+}
+
+// Revoke deletes the link so it can't be opened again
+func (s *Shares) Revoke(ctx context.Context, owner, id string) error {
+	return s.store.Delete(ctx, owner, id)
+}
+
+// Forget deletes every link the owner made, for when their account is deleted
+func (s *Shares) Forget(ctx context.Context, owner string) error {
+	return s.store.DeleteForOwner(ctx, owner)
+}
+
+// hashPassword returns pbkdf2-sha256$iterations$salt$key with the salt and key in base64
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		"pbkdf2-sha256",
+		strconv.Itoa(passwordIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+func checkPassword(hashed, password string) (bool, error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false, errors.New("unknown password hash")
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false, errors.New("bad password hash iterations")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, errors.New("bad password hash salt")
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, errors.New("bad password hash")
+	}
+
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
@@ -0,0 +1,58 @@
+package shares
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShares(t *testing.T) {
+	s := NewShares(NewMemoryStore(), time.Hour, 24*time.Hour)
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, "alice", "data", "iv", time.Now().Add(48*time.Hour), 0, "")
+	assert.ErrorIs(t, err, ErrInvalid, "longer than the max lifetime")
+	_, err = s.Create(ctx, "alice", "", "iv", time.Time{}, -1, "")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	open, err := s.Create(ctx, "alice", "data", "iv", time.Time{}, 2, "")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), open.ExpiresAt, time.Minute)
+
+	for range 2 {
+		viewed, err := s.Open(ctx, open.ID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "data", viewed.Data)
+	}
+	_, err = s.Open(ctx, open.ID, "")
+	assert.ErrorIs(t, err, ErrGone, "only max_views views are allowed")
+
+	locked, err := s.Create(ctx, "alice", "secret", "iv", time.Time{}, 1, "hunter2")
+	assert.NoError(t, err)
+	assert.True(t, locked.Protected)
+	assert.NotContains(t, locked.Password, "hunter2")
+
+	_, err = s.Open(ctx, locked.ID, "")
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+	_, err = s.Open(ctx, locked.ID, "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassword)
+	viewed, err := s.Open(ctx, locked.ID, "hunter2")
+	assert.NoError(t, err, "wrong passwords don't use up views")
+	assert.Equal(t, 1, viewed.Views)
+
+	list, err := s.ForOwner(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	assert.ErrorIs(t, s.Revoke(ctx, "bob", open.ID), ErrNotFound)
+	assert.NoError(t, s.Revoke(ctx, "alice", open.ID))
+	_, err = s.Open(ctx, open.ID, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, s.Forget(ctx, "alice"))
+	list, err = s.ForOwner(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
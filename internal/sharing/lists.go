@@ -0,0 +1,462 @@
+// Package sharing keeps lists that are shared between users. The content of a shared list is encrypted
+// with its own key, which every member is given wrapped for their own public key, so the server only ever
+// sees ciphertext and wrapped keys
+package sharing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Access a member can have to a shared list
+const (
+	AccessOwner = "owner"
+	AccessWrite = "write"
+	AccessRead  = "read"
+)
+
+// Limits on what a grant can hold
+const (
+	maxEnvelope  = 4096
+	maxRecipient = 256
+)
+
+var (
+	// ErrNotFound is returned for a list that doesn't exist or the caller isn't a member of,
+	// so lists can't be discovered by guessing ids
+	ErrNotFound = errors.New("list not found")
+	// ErrForbidden is returned when a member doesn't have the access the operation needs
+	ErrForbidden = errors.New("not allowed")
+	// ErrStale is returned when the list has changed since the revision the client expected
+	ErrStale = errors.New("the list has changed since the expected revision")
+	// ErrTooManyGrants is returned when the list is already shared with as many users as it can be
+	ErrTooManyGrants = errors.New("the list is shared with too many users")
+	// ErrInvalid is wrapped by every problem with what the client sent
+	ErrInvalid = errors.New("invalid request")
+)
+
+// List is a shared list, Data and IV are the ciphertext under the lists content key
+type List struct {
+	ID        string    `json:"id" bson:"_id"`
+	Owner     string    `json:"owner" bson:"owner"`
+	Data      string    `json:"data" bson:"data"`
+	IV        string    `json:"iv" bson:"iv"`
+	Revision  string    `json:"revision" bson:"revision"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Grant gives a user access to a list, Envelope is the content key wrapped for the users public key,
+// it is empty while the grant is pending, such as after an invite is accepted and before the owner
+// has wrapped the key for the new member
+type Grant struct {
+	List      string    `json:"list" bson:"list"`
+	Recipient string    `json:"recipient" bson:"recipient"`
+	Access    string    `json:"access" bson:"access"`
+	Envelope  string    `json:"envelope,omitempty" bson:"envelope"`
+	GrantedBy string    `json:"granted_by" bson:"granted_by"`
+	At        time.Time `json:"at" bson:"at"`
+	Pending   bool      `json:"pending,omitempty" bson:"-"`
+}
+
+// Summary is a list a user can see, without its content
+type Summary struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Access    string    `json:"access"`
+	Revision  string    `json:"revision"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Pending   bool      `json:"pending,omitempty"`
+}
+
+// Store keeps the lists and their grants
+type Store interface {
+	// CreateList stores a new list and the owners grant
+	CreateList(ctx context.Context, l List, owner Grant) error
+	// GetList returns ErrNotFound when there is no such list
+	GetList(ctx context.Context, id string) (List, error)
+	// UpdateList replaces the content when the revision is still expected, which is skipped when empty,
+	// returning ErrStale when it isn't and ErrNotFound when there is no such list
+	UpdateList(ctx context.Context, l List, expected string) error
+	// DeleteList drops the list and every grant to it
+	DeleteList(ctx context.Context, id string) error
+	// Grant returns ErrNotFound when the recipient has no grant to the list
+	Grant(ctx context.Context, list, recipient string) (Grant, error)
+	Grants(ctx context.Context, list string) ([]Grant, error)
+	GrantsFor(ctx context.Context, recipient string) ([]Grant, error)
+	// PutGrant adds the grant or replaces the recipients existing one
+	PutGrant(ctx context.Context, g Grant) error
+	// DeleteGrant returns ErrNotFound when the recipient has no grant to the list
+	DeleteGrant(ctx context.Context, list, recipient string) error
+}
+
+// Lists enforces who can do what to a shared list, every method takes the subject making the request
+type Lists struct {
+	store Store
+
+	mu        sync.RWMutex
+	maxGrants int
+}
+
+// NewLists creates the shared lists over the store
+func NewLists(store Store, maxGrants int) *Lists {
+	return &Lists{
+		store:     store,
+		maxGrants: maxGrants,
+	}
+}
+
+// SetLimit changes how many users a list can be shared with, for a config reload
+func (s *Lists) SetLimit(maxGrants int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxGrants = maxGrants
+}
+
+// Revision identifies a version of a lists content, the same way a personal lists ETag does
+func Revision(data, iv string) string {
+	sum := sha256.Sum256([]byte(iv + ":" + data))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Create stores a new list owned by the subject, envelope is the content key wrapped for the owner
+func (s *Lists) Create(ctx context.Context, owner, data, iv, envelope string) (List, error) {
+	if err := validateContent(data, iv); err != nil {
+		return List{}, err
+	}
+	if err := validateEnvelope(envelope); err != nil {
+		return List{}, err
+	}
+
+	now := time.Now().UTC()
+	l := List{
+		ID:        rand.Text(),
+		Owner:     owner,
+		Data:      data,
+		IV:        iv,
+		Revision:  Revision(data, iv),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.store.CreateList(ctx, l, Grant{
+		List:      l.ID,
+		Recipient: owner,
+		Access:    AccessOwner,
+		Envelope:  envelope,
+		GrantedBy: owner,
+		At:        now,
+	}); err != nil {
+		return List{}, err
+	}
+
+	return l, nil
+}
+
+// Accessible returns every list the subject owns or has been given
+func (s *Lists) Accessible(ctx context.Context, subject string) ([]Summary, error) {
+	grants, err := s.store.GrantsFor(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Summary, 0, len(grants))
+	for _, g := range grants {
+		l, err := s.store.GetList(ctx, g.List)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// deleted between reading the grant and the list
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, Summary{
+			ID:        l.ID,
+			Owner:     l.Owner,
+			Access:    g.Access,
+			Revision:  l.Revision,
+			UpdatedAt: l.UpdatedAt,
+			Pending:   g.Envelope == "",
+		})
+	}
+
+	return out, nil
+}
+
+// Get returns the list and the subjects grant to it, which holds their wrapped key
+func (s *Lists) Get(ctx context.Context, subject, id string) (List, Grant, error) {
+	g, err := s.member(ctx, subject, id)
+	if err != nil {
+		return List{}, g, err
+	}
+
+	l, err := s.store.GetList(ctx, id)
+	return l, g, err
+}
+
+// Update replaces the content, the subject needs write access, expected is skipped when empty or *
+func (s *Lists) Update(ctx context.Context, subject, id, data, iv, expected string) (List, error) {
+	g, err := s.member(ctx, subject, id)
+	if err != nil {
+		return List{}, err
+	}
+	if g.Access == AccessRead {
+		return List{}, ErrForbidden
+	}
+	if err := validateContent(data, iv); err != nil {
+		return List{}, err
+	}
+	if expected == "*" {
+		expected = ""
+	}
+
+	l, err := s.store.GetList(ctx, id)
+	if err != nil {
+		return l, err
+	}
+	l.Data = data
+	l.IV = iv
+	l.Revision = Revision(data, iv)
+	l.UpdatedAt = time.Now().UTC()
+	if err := s.store.UpdateList(ctx, l, expected); err != nil {
+		return List{}, err
+	}
+
+	return l, nil
+}
+
+// Delete drops the list for every member, only the owner can delete it. It returns the members the
+// list was dropped for, who are only looked up once the subject is known to be the owner
+func (s *Lists) Delete(ctx context.Context, subject, id string) ([]string, error) {
+	if err := s.RequireOwner(ctx, subject, id); err != nil {
+		return nil, err
+	}
+	members, err := s.Members(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, s.store.DeleteList(ctx, id)
+}
+
+// Grants returns who the list is shared with, any member can see this but only their own wrapped key
+func (s *Lists) Grants(ctx context.Context, subject, id string) ([]Grant, error) {
+	if _, err := s.member(ctx, subject, id); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.store.Grants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range grants {
+		grants[i].Pending = grants[i].Envelope == ""
+		if grants[i].Recipient != subject {
+			grants[i].Envelope = ""
+		}
+	}
+
+	return grants, nil
+}
+
+// Share gives the recipient access, replacing any access they already have, only the owner can share.
+// It reports whether the recipient is new to the list. The owner sharing with themselves only replaces
+// their envelope, which is how they keep access after re-encrypting the list under a new key
+func (s *Lists) Share(ctx context.Context, subject, id, recipient, access, envelope string) (Grant, bool, error) {
+	if err := s.RequireOwner(ctx, subject, id); err != nil {
+		return Grant{}, false, err
+	}
+
+	var errs []error
+	if recipient == "" || len(recipient) > maxRecipient {
+		errs = append(errs, fmt.Errorf("%w: recipient has to be between 1 and %d characters", ErrInvalid, maxRecipient))
+	}
+	switch {
+	case recipient == subject:
+		if access != "" && access != AccessOwner {
+			errs = append(errs, fmt.Errorf("%w: the owners access can't be changed", ErrInvalid))
+		}
+		access = AccessOwner
+	case access != AccessRead && access != AccessWrite:
+		errs = append(errs, fmt.Errorf("%w: access has to be %s or %s", ErrInvalid, AccessRead, AccessWrite))
+	}
+	errs = append(errs, validateEnvelope(envelope))
+	if err := errors.Join(errs...); err != nil {
+		return Grant{}, false, err
+	}
+
+	grants, err := s.store.Grants(ctx, id)
+	if err != nil {
+		return Grant{}, false, err
+	}
+	_, found := find(grants, recipient)
+	if !found && s.full(grants) {
+		return Grant{}, false, ErrTooManyGrants
+	}
+
+	grant := Grant{
+		List:      id,
+		Recipient: recipient,
+		Access:    access,
+		Envelope:  envelope,
+		GrantedBy: subject,
+		At:        time.Now().UTC(),
+	}
+	if err := s.store.PutGrant(ctx, grant); err != nil {
+		return Grant{}, false, err
+	}
+
+	return grant, !found, nil
+}
+
+// Join adds the recipient with a pending grant, for an invite the owner sent, the owner then wraps
+// the key for them with Share. A recipient who already has access keeps it as it is
+func (s *Lists) Join(ctx context.Context, id, recipient, access, invitedBy string) (Grant, error) {
+	l, err := s.store.GetList(ctx, id)
+	if err != nil {
+		return Grant{}, err
+	}
+	if recipient == l.Owner {
+		return Grant{}, fmt.Errorf("%w: the owner already has access", ErrInvalid)
+	}
+
+	grants, err := s.store.Grants(ctx, id)
+	if err != nil {
+		return Grant{}, err
+	}
+	if existing, found := find(grants, recipient); found {
+		existing.Pending = existing.Envelope == ""
+		return existing, nil
+	}
+	if s.full(grants) {
+		return Grant{}, ErrTooManyGrants
+	}
+
+	grant := Grant{
+		List:      id,
+		Recipient: recipient,
+		Access:    access,
+		GrantedBy: invitedBy,
+		At:        time.Now().UTC(),
+	}
+	if err := s.store.PutGrant(ctx, grant); err != nil {
+		return Grant{}, err
+	}
+	grant.Pending = true
+
+	return grant, nil
+}
+
+// RequireOwner returns ErrForbidden when the subject is a member but not the owner of the list
+func (s *Lists) RequireOwner(ctx context.Context, subject, id string) error {
+	g, err := s.member(ctx, subject, id)
+	if err != nil {
+		return err
+	}
+	if g.Access != AccessOwner {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// full reports whether the list can't be shared with anyone else, the owners grant doesn't count
+func (s *Lists) full(grants []Grant) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(grants)-1 >= s.maxGrants
+}
+
+func find(grants []Grant, recipient string) (Grant, bool) {
+	for _, g := range grants {
+		if g.Recipient == recipient {
+			return g, true
+		}
+	}
+
+	return Grant{}, false
+}
+
+// Revoke takes the recipients access away, the owner can revoke anyone and a member can leave,
+// the owner can't lose their own access. The recipient may still have the content key, so the
+// owner should re-encrypt the list under a new key once they have revoked someone
+func (s *Lists) Revoke(ctx context.Context, subject, id, recipient string) error {
+	g, err := s.member(ctx, subject, id)
+	if err != nil {
+		return err
+	}
+	if g.Access != AccessOwner && recipient != subject {
+		return ErrForbidden
+	}
+	if g.Access == AccessOwner && recipient == subject {
+		return fmt.Errorf("%w: the owner can't revoke their own access, delete the list instead", ErrInvalid)
+	}
+
+	return s.store.DeleteGrant(ctx, id, recipient)
+}
+
+// Members returns everyone with access to the list, for telling them it has changed
+func (s *Lists) Members(ctx context.Context, id string) ([]string, error) {
+	grants, err := s.store.Grants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0, len(grants))
+	for _, g := range grants {
+		members = append(members, g.Recipient)
+	}
+
+	return members, nil
+}
+
+// Forget deletes the lists the subject owns and drops them from the lists they were given,
+// for when their account is deleted
+func (s *Lists) Forget(ctx context.Context, subject string) error {
+	grants, err := s.store.GrantsFor(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, g := range grants {
+		if g.Access == AccessOwner {
+			errs = append(errs, s.store.DeleteList(ctx, g.List))
+			continue
+		}
+		errs = append(errs, s.store.DeleteGrant(ctx, g.List, subject))
+	}
+
+	return errors.Join(errs...)
+}
+
+// member returns the subjects grant, a list they have no grant to is reported as not found
+func (s *Lists) member(ctx context.Context, subject, id string) (Grant, error) {
+	return s.store.Grant(ctx, id, subject)
+}
+
+func validateContent(data, iv string) error {
+	if data == "" || iv == "" {
+		return fmt.Errorf("%w: data and iv are required", ErrInvalid)
+	}
+
+	return nil
+}
+
+func validateEnvelope(envelope string) error {
+	_, std := base64.StdEncoding.DecodeString(envelope)
+	_, url := base64.RawURLEncoding.DecodeString(envelope)
+	if envelope == "" || len(envelope) > maxEnvelope || (std != nil && url != nil) {
+		return fmt.Errorf("%w: envelope has to be base64 and at most %d characters", ErrInvalid, maxEnvelope)
+	}
+
+	return nil
+}
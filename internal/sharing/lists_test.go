@@ -0,0 +1,134 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLists(t *testing.T) {
+	s := NewLists(NewMemoryStore(), 1)
+	ctx := context.Background()
+
+	l, err := s.Create(ctx, "alice", "data", "iv", "YWxpY2Uta2V5")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, l.ID)
+	assert.Equal(t, Revision("data", "iv"), l.Revision)
+
+	_, _, err = s.Get(ctx, "bob", l.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "a list isn't visible before it is shared")
+
+	_, created, err := s.Share(ctx, "alice", l.ID, "bob", AccessRead, "Ym9iLWtleQ==")
+	assert.NoError(t, err)
+	assert.True(t, created)
+	_, _, err = s.Share(ctx, "alice", l.ID, "carol", AccessRead, "Y2Fyb2wta2V5")
+	assert.ErrorIs(t, err, ErrTooManyGrants)
+	_, _, err = s.Share(ctx, "bob", l.ID, "carol", AccessRead, "Y2Fyb2wta2V5")
+	assert.ErrorIs(t, err, ErrForbidden, "only the owner can share")
+	_, _, err = s.Share(ctx, "alice", l.ID, "bob", "admin", "not base64!")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	got, grant, err := s.Get(ctx, "bob", l.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", got.Data)
+	assert.Equal(t, "Ym9iLWtleQ==", grant.Envelope)
+
+	_, err = s.Update(ctx, "bob", l.ID, "new", "iv2", "")
+	assert.ErrorIs(t, err, ErrForbidden, "read access can't write")
+
+	_, created, err = s.Share(ctx, "alice", l.ID, "bob", AccessWrite, "Ym9iLWtleQ==")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	updated, err := s.Update(ctx, "bob", l.ID, "new", "iv2", l.Revision)
+	assert.NoError(t, err)
+	_, err = s.Update(ctx, "alice", l.ID, "other", "iv3", l.Revision)
+	assert.ErrorIs(t, err, ErrStale)
+
+	grants, err := s.Grants(ctx, "bob", l.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, grants, 2) {
+		for _, g := range grants {
+			if g.Recipient == "alice" {
+				assert.Empty(t, g.Envelope, "members only see their own wrapped key")
+			}
+		}
+	}
+
+	summaries, err := s.Accessible(ctx, "bob")
+	assert.NoError(t, err)
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, AccessWrite, summaries[0].Access)
+		assert.Equal(t, updated.Revision, summaries[0].Revision)
+	}
+
+	_, err = s.Delete(ctx, "bob", l.ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, s.Revoke(ctx, "alice", l.ID, "alice"), ErrInvalid)
+	assert.NoError(t, s.Revoke(ctx, "bob", l.ID, "bob"), "a member can leave")
+	_, _, err = s.Get(ctx, "bob", l.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, s.Forget(ctx, "alice"))
+	_, _, err = s.Get(ctx, "alice", l.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListsOwnerEnvelope(t *testing.T) {
+	s := NewLists(NewMemoryStore(), 5)
+	ctx := context.Background()
+
+	l, err := s.Create(ctx, "alice", "data", "iv", "YWxpY2Uta2V5")
+	assert.NoError(t, err)
+
+	_, _, err = s.Share(ctx, "alice", l.ID, "alice", AccessRead, "bmV3LWtleQ==")
+	assert.ErrorIs(t, err, ErrInvalid, "the owner can't give up ownership")
+
+	g, created, err := s.Share(ctx, "alice", l.ID, "alice", "", "bmV3LWtleQ==")
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, AccessOwner, g.Access)
+
+	_, grant, err := s.Get(ctx, "alice", l.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "bmV3LWtleQ==", grant.Envelope)
+	assert.Equal(t, AccessOwner, grant.Access)
+}
+
+// ungrouped can find a single grant but fails to list them
+type ungrouped struct {
+	*MemoryStore
+}
+
+func (ungrouped) Grants(context.Context, string) ([]Grant, error) {
+	return nil, errors.New("storage unavailable")
+}
+
+func TestListsDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	s := NewLists(store, 5)
+
+	l, err := s.Create(ctx, "alice", "data", "iv", "YWxpY2U=")
+	assert.NoError(t, err)
+	_, _, err = s.Share(ctx, "alice", l.ID, "bob", AccessRead, "Ym9i")
+	assert.NoError(t, err)
+
+	t.Run("members aren't looked up for someone who isn't the owner", func(t *testing.T) {
+		broken := NewLists(ungrouped{store}, 5)
+		_, err := broken.Delete(ctx, "mallory", l.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = broken.Delete(ctx, "bob", l.ID)
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		members, err := s.Delete(ctx, "alice", l.ID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"alice", "bob"}, members)
+
+		_, _, err = s.Get(ctx, "bob", l.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
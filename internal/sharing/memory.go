@@ -0,0 +1,150 @@
+package sharing
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps shared lists in memory, for development and tests
+type MemoryStore struct {
+	mu     sync.Mutex
+	lists  map[string]List
+	grants map[string][]Grant
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		lists:  make(map[string]List),
+		grants: make(map[string][]Grant),
+	}
+}
+
+// CreateList implements Store
+func (m *MemoryStore) CreateList(_ context.Context, l List, owner Grant) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lists[l.ID] = l
+	m.grants[l.ID] = []Grant{owner}
+	return nil
+}
+
+// GetList implements Store
+func (m *MemoryStore) GetList(_ context.Context, id string) (List, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.lists[id]
+	if !ok {
+		return List{}, ErrNotFound
+	}
+
+	return l, nil
+}
+
+// UpdateList implements Store
+func (m *MemoryStore) UpdateList(_ context.Context, l List, expected string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.lists[l.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if expected != "" && current.Revision != expected {
+		return ErrStale
+	}
+
+	m.lists[l.ID] = l
+	return nil
+}
+
+// DeleteList implements Store
+func (m *MemoryStore) DeleteList(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.lists, id)
+	delete(m.grants, id)
+	return nil
+}
+
+// Grant implements Store
+func (m *MemoryStore) Grant(_ context.Context, list, recipient string) (Grant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, g := range m.grants[list] {
+		if g.Recipient == recipient {
+			return g, nil
+		}
+	}
+
+	return Grant{}, ErrNotFound
+}
+
+// Grants implements Store
+func (m *MemoryStore) Grants(_ context.Context, list string) ([]Grant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Grant(nil), m.grants[list]...), nil
+}
+
+// GrantsFor implements Store
+func (m *MemoryStore) GrantsFor(_ context.Context, recipient string) ([]Grant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Grant
+	for _, grants := range m.grants {
+		for _, g := range grants {
+			if g.Recipient == recipient {
+				out = append(out, g)
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b Grant) int {
+		return a.At.Compare(b.At)
+	})
+
+	return out, nil
+}
+
+// PutGrant implements Store
+func (m *MemoryStore) PutGrant(_ context.Context, g Grant) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lists[g.List]; !ok {
+		return ErrNotFound
+	}
+	i := slices.IndexFunc(m.grants[g.List], func(existing Grant) bool {
+		return existing.Recipient == g.Recipient
+	})
+	if i < 0 {
+		m.grants[g.List] = append(m.grants[g.List], g)
+		return nil
+	}
+
+	m.grants[g.List][i] = g
+	return nil
+}
+
+// DeleteGrant implements Store
+func (m *MemoryStore) DeleteGrant(_ context.Context, list, recipient string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.grants[list])
+	m.grants[list] = slices.DeleteFunc(m.grants[list], func(g Grant) bool {
+		return g.Recipient == recipient
+	})
+	if len(m.grants[list]) == before {
+		return ErrNotFound
+	}
+
+	return nil
+}
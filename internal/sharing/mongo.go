@@ -0,0 +1,166 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps shared lists and their grants in two collections
+type MongoStore struct {
+	lists  *mongo.Collection
+	grants *mongo.Collection
+}
+
+// NewMongoStore makes sure the indexes exist, a recipient has at most one grant to each list
+func NewMongoStore(ctx context.Context, db *mongo.Database, lists, grants string) (*MongoStore, error) {
+	m := &MongoStore{
+		lists:  db.Collection(lists),
+		grants: db.Collection(grants),
+	}
+
+	if _, err := m.grants.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "list", Value: 1}, {Key: "recipient", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "recipient", Value: 1}, {Key: "at", Value: 1}},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("create grant indexes: %v", err)
+	}
+
+	return m, nil
+}
+
+// CreateList implements Store
+func (m *MongoStore) CreateList(ctx context.Context, l List, owner Grant) error {
+	if _, err := m.lists.InsertOne(ctx, l); err != nil {
+		return fmt.Errorf("insert list: %v", err)
+	}
+	if _, err := m.grants.InsertOne(ctx, owner); err != nil {
+		_, _ = m.lists.DeleteOne(ctx, bson.M{"_id": l.ID})
+		return fmt.Errorf("insert owner grant: %v", err)
+	}
+
+	return nil
+}
+
+// GetList implements Store
+func (m *MongoStore) GetList(ctx context.Context, id string) (List, error) {
+	l := List{}
+	if err := m.lists.FindOne(ctx, bson.M{"_id": id}).Decode(&l); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return List{}, ErrNotFound
+		}
+		return List{}, fmt.Errorf("find list: %v", err)
+	}
+
+	return l, nil
+}
+
+// UpdateList implements Store, the revision is compared in the update so concurrent writers can't both win
+func (m *MongoStore) UpdateList(ctx context.Context, l List, expected string) error {
+	filter := bson.M{"_id": l.ID}
+	if expected != "" {
+		filter["revision"] = expected
+	}
+
+	res, err := m.lists.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"data":       l.Data,
+		"iv":         l.IV,
+		"revision":   l.Revision,
+		"updated_at": l.UpdatedAt,
+	}})
+	if err != nil {
+		return fmt.Errorf("update list: %v", err)
+	}
+	if res.MatchedCount == 1 {
+		return nil
+	}
+
+	if _, err := m.GetList(ctx, l.ID); err != nil {
+		return err
+	}
+	return ErrStale
+}
+
+// DeleteList implements Store
+func (m *MongoStore) DeleteList(ctx context.Context, id string) error {
+	if _, err := m.grants.DeleteMany(ctx, bson.M{"list": id}); err != nil {
+		return fmt.Errorf("delete grants: %v", err)
+	}
+	if _, err := m.lists.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("delete list: %v", err)
+	}
+
+	return nil
+}
+
+// Grant implements Store
+func (m *MongoStore) Grant(ctx context.Context, list, recipient string) (Grant, error) {
+	g := Grant{}
+	if err := m.grants.FindOne(ctx, bson.M{"list": list, "recipient": recipient}).Decode(&g); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Grant{}, ErrNotFound
+		}
+		return Grant{}, fmt.Errorf("find grant: %v", err)
+	}
+
+	return g, nil
+}
+
+// Grants implements Store
+func (m *MongoStore) Grants(ctx context.Context, list string) ([]Grant, error) {
+	return m.find(ctx, bson.M{"list": list})
+}
+
+// GrantsFor implements Store
+func (m *MongoStore) GrantsFor(ctx context.Context, recipient string) ([]Grant, error) {
+	return m.find(ctx, bson.M{"recipient": recipient})
+}
+
+// PutGrant implements Store
+func (m *MongoStore) PutGrant(ctx context.Context, g Grant) error {
+	if _, err := m.GetList(ctx, g.List); err != nil {
+		return err
+	}
+
+	if _, err := m.grants.ReplaceOne(ctx, bson.M{"list": g.List, "recipient": g.Recipient}, g, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("put grant: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteGrant implements Store
+func (m *MongoStore) DeleteGrant(ctx context.Context, list, recipient string) error {
+	res, err := m.grants.DeleteOne(ctx, bson.M{"list": list, "recipient": recipient})
+	if err != nil {
+		return fmt.Errorf("delete grant: %v", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (m *MongoStore) find(ctx context.Context, filter bson.M) ([]Grant, error) {
+	cur, err := m.grants.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("find grants: %v", err)
+	}
+
+	var grants []Grant
+	if err := cur.All(ctx, &grants); err != nil {
+		return nil, fmt.Errorf("read grants: %v", err)
+	}
+
+	return grants, nil
+}
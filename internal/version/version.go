@@ -0,0 +1,121 @@
+// Package version reports which build of the service is running
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/todo-lists-app/todo-lists-api/internal/metrics"
+)
+
+// Dependency is a module the binary was built with
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// Info is the body returned by /version
+type Info struct {
+	Service       string       `json:"service"`
+	Version       string       `json:"version"`
+	Hash          string       `json:"hash"`
+	GoVersion     string       `json:"go_version,omitempty"`
+	Dependencies  []Dependency `json:"dependencies,omitempty"`
+	StartTime     time.Time    `json:"start_time"`
+	Uptime        string       `json:"uptime"`
+	UptimeSeconds int64        `json:"uptime_seconds"`
+}
+
+// Reporter knows the build that is running and when it started
+type Reporter struct {
+	Service string
+	Version string
+	Hash    string
+
+	started time.Time
+	deps    []Dependency
+	now     func() time.Time
+}
+
+// New creates the reporter for the values injected at build time and publishes the build_info metric
+func New(service, version, hash string) *Reporter {
+	r := &Reporter{
+		Service: service,
+		Version: version,
+		Hash:    hash,
+		started: time.Now(),
+		deps:    dependencies(),
+		now:     time.Now,
+	}
+	metrics.SetBuildInfo(map[string]string{
+		"service":    service,
+		"version":    version,
+		"hash":       hash,
+		"go_version": runtime.Version(),
+	})
+
+	return r
+}
+
+func dependencies() []Dependency {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	deps := make([]Dependency, 0, len(bi.Deps))
+	for _, d := range bi.Deps {
+		dep := Dependency{
+			Path:    d.Path,
+			Version: d.Version,
+		}
+		if d.Replace != nil {
+			dep.Replace = d.Replace.Path + "@" + d.Replace.Version
+		}
+		deps = append(deps, dep)
+	}
+
+	return deps
+}
+
+// Info returns everything about the build, including the go version and dependencies
+func (r *Reporter) Info() Info {
+	uptime := r.now().Sub(r.started)
+
+	return Info{
+		Service:       r.Service,
+		Version:       r.Version,
+		Hash:          r.Hash,
+		GoVersion:     runtime.Version(),
+		Dependencies:  r.deps,
+		StartTime:     r.started,
+		Uptime:        uptime.Truncate(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+	}
+}
+
+// HTTP is the internal /version handler
+func (r *Reporter) HTTP(w http.ResponseWriter, _ *http.Request) {
+	write(w, r.Info())
+}
+
+// PublicHTTP is the public /version handler, the go version and dependencies are left out
+// so they can't be matched against known vulnerabilities
+func (r *Reporter) PublicHTTP(w http.ResponseWriter, _ *http.Request) {
+	info := r.Info()
+	info.GoVersion = ""
+	info.Dependencies = nil
+
+	write(w, info)
+}
+
+func write(w http.ResponseWriter, info Info) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(info)
+}
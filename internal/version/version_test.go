@@ -0,0 +1,41 @@
+package version
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporter(t *testing.T) {
+	r := New("api", "1.2.3", "abc123")
+	r.started = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time {
+		return r.started.Add(90*time.Minute + 500*time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	r.HTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	info := Info{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+	assert.Equal(t, "api", info.Service)
+	assert.Equal(t, "1.2.3", info.Version)
+	assert.Equal(t, "abc123", info.Hash)
+	assert.NotEmpty(t, info.GoVersion)
+	assert.Equal(t, "1h30m0s", info.Uptime)
+	assert.Equal(t, int64(5400), info.UptimeSeconds)
+	assert.True(t, r.started.Equal(info.StartTime))
+
+	w = httptest.NewRecorder()
+	r.PublicHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.NotContains(t, w.Body.String(), "go_version")
+	assert.NotContains(t, w.Body.String(), "dependencies")
+
+	assert.Equal(t, `"1.2.3"`, expvar.Get("build_info").(*expvar.Map).Get("version").String())
+}